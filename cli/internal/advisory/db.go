@@ -0,0 +1,413 @@
+package advisory
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultSource serves the OSV database as one all.zip per ecosystem
+const DefaultSource = "https://osv-vulnerabilities.storage.googleapis.com"
+
+// EnvSource overrides DefaultSource, for example with an internal mirror
+const EnvSource = "RAINCHECK_ADVISORY_URL"
+
+// MaxAge is how old downloaded advisories may get before a scan refreshes
+// them
+const MaxAge = 24 * time.Hour
+
+// Advisories are kept apart by origin so a refresh never drops imported ones
+const (
+	sourceDownloaded = "osv"
+	sourceImported   = "imported"
+)
+
+// index is the on-disk form of the advisories of one ecosystem from one
+// source, keyed by normalized package name
+type index struct {
+	Ecosystem string                `json:"ecosystem"`
+	Updated   time.Time             `json:"updated"`
+	Packages  map[string][]Advisory `json:"packages"`
+}
+
+func newIndex(ecosystem string) *index {
+	return &index{Ecosystem: ecosystem, Packages: make(map[string][]Advisory)}
+}
+
+// add files an advisory under its package, replacing an older copy
+func (idx *index) add(e entry) {
+	list := idx.Packages[e.name]
+	for i, a := range list {
+		if a.ID == e.advisory.ID {
+			if !e.advisory.Modified.Before(a.Modified) {
+				list[i] = e.advisory
+			}
+			return
+		}
+	}
+	idx.Packages[e.name] = append(list, e.advisory)
+}
+
+func (idx *index) count() int {
+	n := 0
+	for _, list := range idx.Packages {
+		n += len(list)
+	}
+	return n
+}
+
+// DB is the local advisory database
+type DB struct {
+	dir    string
+	loaded map[string]map[string][]Advisory
+}
+
+// DefaultDir returns the default database directory,
+// ~/.cache/raincheck/advisories on Linux
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate cache directory: %w", err)
+	}
+	return filepath.Join(dir, "raincheck", "advisories"), nil
+}
+
+// Open returns the database stored in dir, creating the directory if needed
+func Open(dir string) (*DB, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create advisory directory: %w", err)
+	}
+	return &DB{dir: dir, loaded: make(map[string]map[string][]Advisory)}, nil
+}
+
+// Dir returns the directory holding the database
+func (db *DB) Dir() string {
+	return db.dir
+}
+
+func (db *DB) path(source, ecosystem string) string {
+	return filepath.Join(db.dir, source, ecosystem+".json.gz")
+}
+
+// readIndex loads an index, returning nil when it does not exist
+func readIndex(path string) (*index, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read advisories: %w", err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read advisories from %s: %w", path, err)
+	}
+	var idx index
+	if err := json.NewDecoder(gz).Decode(&idx); err != nil {
+		return nil, fmt.Errorf("failed to read advisories from %s: %w", path, err)
+	}
+	if idx.Packages == nil {
+		idx.Packages = make(map[string][]Advisory)
+	}
+	return &idx, nil
+}
+
+// writeIndex saves an index through a temporary file so readers never see
+// a partial database
+func writeIndex(path string, idx *index) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to write advisories: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to write advisories: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	gz := gzip.NewWriter(tmp)
+	if err := json.NewEncoder(gz).Encode(idx); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write advisories: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write advisories: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write advisories: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write advisories: %w", err)
+	}
+	return nil
+}
+
+// Import adds the OSV records in path to the database. path may be a JSON
+// file holding one record or an array of records, a zip archive such as an
+// OSV all.zip export, or a directory of those. It returns the number of
+// advisories imported per ecosystem.
+func (db *DB) Import(path string) (map[string]int, error) {
+	indexes := make(map[string]*index)
+	add := func(data []byte, name string) error {
+		entries, err := parseRecords(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+		for _, e := range entries {
+			if indexes[e.ecosystem] == nil {
+				indexes[e.ecosystem] = newIndex(e.ecosystem)
+			}
+			indexes[e.ecosystem].add(e)
+		}
+		return nil
+	}
+
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return nil
+		case strings.EqualFold(filepath.Ext(p), ".zip"):
+			return readZip(p, add)
+		case strings.EqualFold(filepath.Ext(p), ".json"):
+			data, err := os.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			return add(data, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to import advisories: %w", err)
+	}
+
+	counts := make(map[string]int)
+	for ecosystem, imported := range indexes {
+		path := db.path(sourceImported, ecosystem)
+		idx, err := readIndex(path)
+		if err != nil {
+			return nil, err
+		}
+		if idx == nil {
+			idx = newIndex(ecosystem)
+		}
+		for name, list := range imported.Packages {
+			for _, a := range list {
+				idx.add(entry{ecosystem: ecosystem, name: name, advisory: a})
+			}
+		}
+		idx.Updated = time.Now().UTC()
+		if err := writeIndex(path, idx); err != nil {
+			return nil, err
+		}
+		counts[ecosystem] = imported.count()
+		delete(db.loaded, ecosystem)
+	}
+	return counts, nil
+}
+
+// parseRecords parses a single OSV record or an array of records
+func parseRecords(data []byte) ([]entry, error) {
+	data = bytes.TrimSpace(data)
+	if !bytes.HasPrefix(data, []byte("[")) {
+		return parseRecord(data)
+	}
+
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	var entries []entry
+	for _, r := range raw {
+		e, err := parseRecord(r)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e...)
+	}
+	return entries, nil
+}
+
+// readZip passes every JSON file in the zip archive at path to add
+func readZip(path string, add func(data []byte, name string) error) error {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() || !strings.EqualFold(filepath.Ext(f.Name), ".json") {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		if err := add(data, path+":"+f.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Update downloads the advisories of ecosystem from source, replacing the
+// previously downloaded ones. Imported advisories are kept.
+func (db *DB) Update(ctx context.Context, client *http.Client, source, ecosystem string) error {
+	url := strings.TrimSuffix(source, "/") + "/" + ecosystem + "/all.zip"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to download %s advisories: %w", ecosystem, err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download %s advisories: %w", ecosystem, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download %s advisories: %s returned %s", ecosystem, url, resp.Status)
+	}
+
+	// zip needs random access, so the archive is spooled to disk
+	tmp, err := os.CreateTemp(db.dir, ".download*")
+	if err != nil {
+		return fmt.Errorf("failed to download %s advisories: %w", ecosystem, err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, resp.Body)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to download %s advisories: %w", ecosystem, err)
+	}
+
+	idx := newIndex(ecosystem)
+	err = readZip(tmp.Name(), func(data []byte, name string) error {
+		entries, err := parseRecords(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+		for _, e := range entries {
+			if e.ecosystem == ecosystem {
+				idx.add(e)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read %s advisories: %w", ecosystem, err)
+	}
+
+	idx.Updated = time.Now().UTC()
+	if err := writeIndex(db.path(sourceDownloaded, ecosystem), idx); err != nil {
+		return err
+	}
+	delete(db.loaded, ecosystem)
+	return nil
+}
+
+// Status describes the advisories stored for one ecosystem and source
+type Status struct {
+	Ecosystem  string
+	Source     string
+	Updated    time.Time
+	Packages   int
+	Advisories int
+}
+
+// Status returns the state of every index in the database
+func (db *DB) Status() ([]Status, error) {
+	var statuses []Status
+	for _, ecosystem := range Ecosystems {
+		for _, source := range []string{sourceDownloaded, sourceImported} {
+			idx, err := readIndex(db.path(source, ecosystem))
+			if err != nil {
+				return nil, err
+			}
+			if idx == nil {
+				continue
+			}
+			statuses = append(statuses, Status{
+				Ecosystem:  ecosystem,
+				Source:     source,
+				Updated:    idx.Updated,
+				Packages:   len(idx.Packages),
+				Advisories: idx.count(),
+			})
+		}
+	}
+	return statuses, nil
+}
+
+// Downloaded returns when the advisories of ecosystem were last downloaded,
+// or the zero time if never
+func (db *DB) Downloaded(ecosystem string) time.Time {
+	info, err := os.Stat(db.path(sourceDownloaded, ecosystem))
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// Has reports whether any advisories are stored for ecosystem
+func (db *DB) Has(ecosystem string) bool {
+	for _, source := range []string{sourceDownloaded, sourceImported} {
+		if _, err := os.Stat(db.path(source, ecosystem)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// advisories returns the advisories of ecosystem from every source, keyed
+// by normalized package name. Where both sources hold an advisory the
+// newer copy wins.
+func (db *DB) advisories(ecosystem string) (map[string][]Advisory, error) {
+	if m, ok := db.loaded[ecosystem]; ok {
+		return m, nil
+	}
+
+	merged := newIndex(ecosystem)
+	for _, source := range []string{sourceDownloaded, sourceImported} {
+		idx, err := readIndex(db.path(source, ecosystem))
+		if err != nil {
+			return nil, err
+		}
+		if idx == nil {
+			continue
+		}
+		if len(merged.Packages) == 0 {
+			merged = idx
+			continue
+		}
+		for name, list := range idx.Packages {
+			for _, a := range list {
+				merged.add(entry{ecosystem: ecosystem, name: name, advisory: a})
+			}
+		}
+	}
+	db.loaded[ecosystem] = merged.Packages
+	return merged.Packages, nil
+}
@@ -0,0 +1,152 @@
+package advisory
+
+import (
+	"sort"
+	"strings"
+
+	"raincheck/internal/deps"
+)
+
+// Match is an advisory affecting a package
+type Match struct {
+	Package  deps.Package
+	Advisory Advisory
+	// Affected describes the affected range the version lies in
+	Affected string
+	// Fixed is the lowest version fixing the advisory above the package
+	// version, empty when no fix is known
+	Fixed string
+}
+
+// Match returns the advisories affecting pkgs. A package installed at several
+// paths is reported once, as is an advisory published under several IDs.
+func (db *DB) Match(pkgs []deps.Package) ([]Match, error) {
+	var matches []Match
+	seen := make(map[string]bool)
+	for _, p := range pkgs {
+		if seen[p.Ecosystem+"\x00"+p.ID()] {
+			continue
+		}
+		seen[p.Ecosystem+"\x00"+p.ID()] = true
+
+		index, err := db.advisories(p.Ecosystem)
+		if err != nil {
+			return nil, err
+		}
+		candidates := append([]Advisory(nil), index[deps.NormalizeName(p.Ecosystem, p.Name)]...)
+		// GitHub advisories carry severities, so they win over their aliases
+		sort.SliceStable(candidates, func(a, b int) bool {
+			return strings.HasPrefix(candidates[a].ID, "GHSA-") && !strings.HasPrefix(candidates[b].ID, "GHSA-")
+		})
+
+		reported := make(map[string]bool)
+		for _, adv := range candidates {
+			if reported[adv.ID] {
+				continue
+			}
+			affected, fixed, ok := affects(p.Ecosystem, p.Version, adv)
+			if !ok {
+				continue
+			}
+			reported[adv.ID] = true
+			for _, alias := range adv.Aliases {
+				reported[alias] = true
+			}
+			matches = append(matches, Match{Package: p, Advisory: adv, Affected: affected, Fixed: fixed})
+		}
+	}
+	return matches, nil
+}
+
+// affects reports whether version is affected by adv, together with the
+// affected range it lies in and the version fixing it
+func affects(ecosystem, version string, adv Advisory) (string, string, bool) {
+	cmp := func(a, b string) int {
+		return deps.CompareVersions(ecosystem, a, b)
+	}
+
+	for _, events := range adv.Ranges {
+		events = sortEvents(ecosystem, events)
+		introduced, open := "", false
+		for _, ev := range events {
+			switch {
+			case ev.Introduced != "":
+				if !open {
+					introduced, open = ev.Introduced, true
+				}
+			case ev.Fixed != "" || ev.Limit != "":
+				end := ev.Fixed + ev.Limit
+				if open && atLeast(cmp, version, introduced) && cmp(version, end) < 0 {
+					return describeRange(introduced, "<"+end), ev.Fixed, true
+				}
+				open = false
+			case ev.LastAffected != "":
+				if open && atLeast(cmp, version, introduced) && cmp(version, ev.LastAffected) <= 0 {
+					return describeRange(introduced, "<="+ev.LastAffected), "", true
+				}
+				open = false
+			}
+		}
+		if open && atLeast(cmp, version, introduced) {
+			return describeRange(introduced, ""), "", true
+		}
+	}
+
+	for _, v := range adv.Versions {
+		if cmp(v, version) == 0 {
+			return "listed as affected", lowestFix(cmp, version, adv), true
+		}
+	}
+	return "", "", false
+}
+
+// atLeast reports whether version is at or above introduced, where "0"
+// stands for every version
+func atLeast(cmp func(a, b string) int, version, introduced string) bool {
+	return introduced == "0" || cmp(version, introduced) >= 0
+}
+
+// sortEvents orders the events of a range by version as OSV requires for
+// evaluation, with introduced "0" first
+func sortEvents(ecosystem string, events []Event) []Event {
+	sorted := append([]Event(nil), events...)
+	version := func(e Event) string {
+		return e.Introduced + e.Fixed + e.LastAffected + e.Limit
+	}
+	sort.SliceStable(sorted, func(a, b int) bool {
+		va, vb := version(sorted[a]), version(sorted[b])
+		if va == "0" || vb == "0" {
+			return va == "0" && vb != "0"
+		}
+		return deps.CompareVersions(ecosystem, va, vb) < 0
+	})
+	return sorted
+}
+
+// lowestFix returns the lowest fixed version above version in any range
+func lowestFix(cmp func(a, b string) int, version string, adv Advisory) string {
+	fix := ""
+	for _, events := range adv.Ranges {
+		for _, ev := range events {
+			if ev.Fixed != "" && cmp(ev.Fixed, version) > 0 && (fix == "" || cmp(ev.Fixed, fix) < 0) {
+				fix = ev.Fixed
+			}
+		}
+	}
+	return fix
+}
+
+// describeRange renders a range such as ">=1.0.0, <1.2.3"
+func describeRange(introduced, upper string) string {
+	var parts []string
+	if introduced != "0" {
+		parts = append(parts, ">="+introduced)
+	}
+	if upper != "" {
+		parts = append(parts, upper)
+	}
+	if len(parts) == 0 {
+		return "all versions"
+	}
+	return strings.Join(parts, ", ")
+}
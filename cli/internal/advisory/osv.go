@@ -0,0 +1,186 @@
+package advisory
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"raincheck/internal/api"
+	"raincheck/internal/deps"
+)
+
+// osvRecord is the subset of the OSV schema raincheck uses, see
+// https://ossf.github.io/osv-schema/
+type osvRecord struct {
+	ID        string        `json:"id"`
+	Aliases   []string      `json:"aliases"`
+	Summary   string        `json:"summary"`
+	Details   string        `json:"details"`
+	Modified  time.Time     `json:"modified"`
+	Withdrawn *time.Time    `json:"withdrawn"`
+	Severity  []osvSeverity `json:"severity"`
+	Affected  []struct {
+		Package struct {
+			Ecosystem string `json:"ecosystem"`
+			Name      string `json:"name"`
+		} `json:"package"`
+		Severity []osvSeverity `json:"severity"`
+		Ranges   []struct {
+			Type   string  `json:"type"`
+			Events []Event `json:"events"`
+		} `json:"ranges"`
+		Versions []string `json:"versions"`
+	} `json:"affected"`
+	DatabaseSpecific struct {
+		Severity string `json:"severity"`
+	} `json:"database_specific"`
+}
+
+type osvSeverity struct {
+	Type  string `json:"type"`
+	Score string `json:"score"`
+}
+
+// Advisory is a vulnerability as it affects one package
+type Advisory struct {
+	ID       string    `json:"id"`
+	Aliases  []string  `json:"aliases,omitempty"`
+	Summary  string    `json:"summary,omitempty"`
+	Modified time.Time `json:"modified"`
+	// Severity is ERROR, WARNING or INFO
+	Severity string `json:"severity"`
+	// Ranges and Versions describe the affected versions; a version is
+	// affected when it lies in any range or is listed
+	Ranges   [][]Event `json:"ranges,omitempty"`
+	Versions []string  `json:"versions,omitempty"`
+}
+
+// Event is an OSV range event; exactly one field is set
+type Event struct {
+	Introduced   string `json:"introduced,omitempty"`
+	Fixed        string `json:"fixed,omitempty"`
+	LastAffected string `json:"last_affected,omitempty"`
+	Limit        string `json:"limit,omitempty"`
+}
+
+// entry is an advisory filed under the package it affects
+type entry struct {
+	ecosystem, name string
+	advisory        Advisory
+}
+
+// parseRecord converts an OSV record into one advisory per affected package
+// of a supported ecosystem. Withdrawn records yield nothing.
+func parseRecord(data []byte) ([]entry, error) {
+	var rec osvRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, err
+	}
+	if rec.ID == "" || rec.Withdrawn != nil {
+		return nil, nil
+	}
+
+	// A record may list the same package in several affected entries, which
+	// are combined
+	var entries []*entry
+	byPackage := make(map[string]*entry)
+	for _, a := range rec.Affected {
+		ecosystem := supportedEcosystem(a.Package.Ecosystem)
+		if ecosystem == "" || a.Package.Name == "" {
+			continue
+		}
+
+		name := deps.NormalizeName(ecosystem, a.Package.Name)
+		e, ok := byPackage[ecosystem+"\x00"+name]
+		if !ok {
+			e = &entry{ecosystem: ecosystem, name: name, advisory: Advisory{
+				ID:       rec.ID,
+				Aliases:  rec.Aliases,
+				Summary:  summary(rec),
+				Modified: rec.Modified,
+				Severity: severity(rec, a.Severity),
+			}}
+			byPackage[ecosystem+"\x00"+name] = e
+			entries = append(entries, e)
+		}
+
+		e.advisory.Versions = append(e.advisory.Versions, a.Versions...)
+		for _, r := range a.Ranges {
+			// Git ranges name commits, which lockfiles do not record
+			if (r.Type == "SEMVER" || r.Type == "ECOSYSTEM") && len(r.Events) > 0 {
+				e.advisory.Ranges = append(e.advisory.Ranges, r.Events)
+			}
+		}
+	}
+
+	var result []entry
+	for _, e := range entries {
+		if len(e.advisory.Ranges) > 0 || len(e.advisory.Versions) > 0 {
+			result = append(result, *e)
+		}
+	}
+	return result, nil
+}
+
+// Ecosystems lists the OSV ecosystems raincheck can match packages against
+var Ecosystems = []string{deps.EcosystemGo, deps.EcosystemNPM, deps.EcosystemPyPI, deps.EcosystemCargo, deps.EcosystemMaven}
+
+// supportedEcosystem returns the ecosystem of an OSV package, dropping
+// release suffixes such as "Debian:12", or "" when it is not supported
+func supportedEcosystem(ecosystem string) string {
+	ecosystem, _, _ = strings.Cut(ecosystem, ":")
+	for _, e := range Ecosystems {
+		if e == ecosystem {
+			return e
+		}
+	}
+	return ""
+}
+
+// summary returns the summary of a record, or the first line of its
+// details when it has none
+func summary(rec osvRecord) string {
+	if s := strings.TrimSpace(rec.Summary); s != "" {
+		return s
+	}
+	s, _, _ := strings.Cut(strings.TrimSpace(rec.Details), "\n")
+	if r := []rune(s); len(r) > 200 {
+		s = string(r[:200]) + "…"
+	}
+	return s
+}
+
+// severity maps the severity of an advisory onto ERROR, WARNING or INFO:
+// from the database's rating such as GitHub's CRITICAL to LOW, else from
+// the highest CVSS v3 base score. Malicious packages are always ERROR and
+// unrated advisories WARNING.
+func severity(rec osvRecord, affected []osvSeverity) string {
+	if strings.HasPrefix(rec.ID, "MAL-") {
+		return api.SeverityError
+	}
+	switch strings.ToUpper(rec.DatabaseSpecific.Severity) {
+	case "CRITICAL", "HIGH":
+		return api.SeverityError
+	case "MODERATE", "MEDIUM":
+		return api.SeverityWarning
+	case "LOW":
+		return api.SeverityInfo
+	}
+
+	best := -1.0
+	for _, s := range append(append([]osvSeverity{}, rec.Severity...), affected...) {
+		if s.Type != "CVSS_V3" {
+			continue
+		}
+		if score, ok := cvss3BaseScore(s.Score); ok && score > best {
+			best = score
+		}
+	}
+	switch {
+	case best >= 7:
+		return api.SeverityError
+	case best >= 4 || best < 0:
+		return api.SeverityWarning
+	}
+	return api.SeverityInfo
+}
@@ -0,0 +1,65 @@
+package advisory
+
+import (
+	"fmt"
+	"strings"
+
+	"raincheck/internal/api"
+)
+
+// Category turns matches into the Dependencies report category
+func Category(matches []Match) *api.Category {
+	issues := make([]api.Issue, 0, len(matches))
+	for _, m := range matches {
+		issues = append(issues, api.Issue{
+			Severity:    m.Advisory.Severity,
+			Type:        m.Advisory.ID,
+			Description: description(m),
+			Line:        m.Package.Line,
+			Suggestion:  suggestion(m),
+		})
+	}
+	return api.NewCategory(issues)
+}
+
+// Analysis returns the report entry of a manifest checked for advisories
+func Analysis(matches []Match) *api.AnalysisResponse {
+	return api.ScanAnalysis(api.CategoryDependencies, Category(matches))
+}
+
+// description names the package, the advisory with its CVE and GHSA
+// aliases, and the affected range
+func description(m Match) string {
+	ids := m.Advisory.ID
+	var aliases []string
+	for _, a := range m.Advisory.Aliases {
+		if strings.HasPrefix(a, "CVE-") || strings.HasPrefix(a, "GHSA-") {
+			aliases = append(aliases, a)
+		}
+	}
+	if len(aliases) > 0 {
+		ids += " (" + strings.Join(aliases, ", ") + ")"
+	}
+
+	kind := "dependency"
+	if !m.Package.Direct {
+		kind = "transitive dependency"
+	}
+	desc := fmt.Sprintf("%s %s is affected by %s", kind, m.Package.ID(), ids)
+	if m.Advisory.Summary != "" {
+		desc += ": " + m.Advisory.Summary
+	}
+	return desc + " [affected: " + m.Affected + "]"
+}
+
+func suggestion(m Match) string {
+	switch {
+	case strings.HasPrefix(m.Advisory.ID, "MAL-"):
+		return fmt.Sprintf("Remove %s, which is a malicious package, and rotate any credentials it could reach", m.Package.Name)
+	case m.Fixed == "":
+		return fmt.Sprintf("No fixed version of %s is known; replace it or mitigate the vulnerability", m.Package.Name)
+	case !m.Package.Direct:
+		return fmt.Sprintf("Upgrade %s to %s or later by upgrading the package that requires it or overriding its version", m.Package.Name, m.Fixed)
+	}
+	return fmt.Sprintf("Upgrade %s to %s or later", m.Package.Name, m.Fixed)
+}
@@ -2,17 +2,21 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
-const (
-	baseURL = "http://143.244.141.172:1000"
-	// baseURL = "http://localhost:1000"
-)
+// DefaultBaseURL is the backend used when no server is configured
+const DefaultBaseURL = "http://143.244.141.172:1000"
 
 // Issue represents a single code issue
 type Issue struct {
@@ -31,74 +35,480 @@ type Category struct {
 
 // AnalysisResponse represents the complete analysis response
 type AnalysisResponse struct {
-	OverallScore    float64  `json:"overall_score"`
-	Security        Category `json:"security"`
-	Performance     Category `json:"performance"`
-	CodeQuality     Category `json:"code_quality"`
-	Maintainability Category `json:"maintainability"`
-	BestPractices   Category `json:"best_practices"`
-	Suggestions     []string `json:"suggestions"`
+	OverallScore float64 `json:"overall_score"`
+	// The categories the backend reports. Scans for a single concern, such
+	// as secrets, leave them out.
+	Security        *Category `json:"security,omitempty"`
+	Performance     *Category `json:"performance,omitempty"`
+	CodeQuality     *Category `json:"code_quality,omitempty"`
+	Maintainability *Category `json:"maintainability,omitempty"`
+	BestPractices   *Category `json:"best_practices,omitempty"`
+	// Secrets holds committed credentials found by raincheck secrets; the
+	// backend never reports it
+	Secrets *Category `json:"secrets,omitempty"`
+	// Dependencies holds vulnerable dependencies found by raincheck deps;
+	// the backend never reports it
+	Dependencies *Category `json:"dependencies,omitempty"`
+	// Licenses holds license policy violations found by raincheck licenses;
+	// the backend never reports it
+	Licenses    *Category `json:"licenses,omitempty"`
+	Suggestions []string  `json:"suggestions"`
+	// Partial is set by the backend when parts of a file could not be
+	// analyzed; the issues and scores cover only the rest
+	Partial bool `json:"partial,omitempty"`
+	// Unanalyzed lists the lines of a partial analysis that were not
+	// analyzed
+	Unanalyzed []LineRange `json:"unanalyzed,omitempty"`
+}
+
+// LineRange is a range of lines of a file, inclusive and numbered from 1
+type LineRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+func (r LineRange) String() string {
+	if r.Start == r.End {
+		return strconv.Itoa(r.Start)
+	}
+	return fmt.Sprintf("%d-%d", r.Start, r.End)
+}
+
+// UnanalyzedLines describes the lines of a partial analysis that were not
+// analyzed, such as "40-120, 300-310"
+func (r *AnalysisResponse) UnanalyzedLines() string {
+	var ranges []string
+	for _, lr := range r.Unanalyzed {
+		ranges = append(ranges, lr.String())
+	}
+	return strings.Join(ranges, ", ")
+}
+
+// Severities in increasing order of importance
+const (
+	SeverityInfo    = "INFO"
+	SeverityWarning = "WARNING"
+	SeverityError   = "ERROR"
+)
+
+// NormalizeSeverity maps a backend severity onto ERROR, WARNING or INFO
+func NormalizeSeverity(severity string) string {
+	switch strings.ToUpper(strings.TrimSpace(severity)) {
+	case "ERROR", "CRITICAL", "HIGH":
+		return SeverityError
+	case "WARNING", "WARN", "MEDIUM":
+		return SeverityWarning
+	}
+	return SeverityInfo
+}
+
+// Score range of a category, as the backend enforces it
+const (
+	MinScore = 1
+	MaxScore = 10
+)
+
+// penalties are the points each issue takes off a category by severity
+var penalties = map[string]float64{
+	SeverityError:   3,
+	SeverityWarning: 1.5,
+	SeverityInfo:    0.5,
+}
+
+// Score rates a category from MaxScore down by the severity of its issues,
+// never going below MinScore
+func Score(issues []Issue) float64 {
+	score := float64(MaxScore)
+	for _, issue := range issues {
+		score -= penalties[NormalizeSeverity(issue.Severity)]
+	}
+	return max(score, MinScore)
+}
+
+// NewCategory returns a category holding issues, scored with Score
+func NewCategory(issues []Issue) *Category {
+	if issues == nil {
+		issues = []Issue{}
+	}
+	return &Category{Score: Score(issues), Issues: issues}
+}
+
+// Keys of the categories filled by raincheck's own scans
+const (
+	CategorySecrets      = "secrets"
+	CategoryDependencies = "dependencies"
+	CategoryLicenses     = "licenses"
+)
+
+// ScanAnalysis returns an analysis holding only c as the category with the
+// given key, for scans that report on a single concern. Its overall score
+// is the score of c.
+func ScanAnalysis(key string, c *Category) *AnalysisResponse {
+	resp := &AnalysisResponse{OverallScore: c.Score, Suggestions: []string{}}
+	switch key {
+	case CategorySecrets:
+		resp.Secrets = c
+	case CategoryDependencies:
+		resp.Dependencies = c
+	case CategoryLicenses:
+		resp.Licenses = c
+	default:
+		panic("unknown scan category " + key)
+	}
+	return resp
+}
+
+// SeverityRank orders severities so that higher ranks are more severe
+func SeverityRank(severity string) int {
+	switch NormalizeSeverity(severity) {
+	case SeverityError:
+		return 2
+	case SeverityWarning:
+		return 1
+	}
+	return 0
+}
+
+// ValidSeverity reports whether severity names one of INFO, WARNING or ERROR
+func ValidSeverity(severity string) bool {
+	switch strings.ToUpper(strings.TrimSpace(severity)) {
+	case SeverityInfo, SeverityWarning, SeverityError:
+		return true
+	}
+	return false
+}
+
+// NamedCategory pairs a category with its display name and JSON key
+type NamedCategory struct {
+	Key      string
+	Name     string
+	Category *Category
+}
+
+// CategoryKeys returns the keys of the categories the backend reports
+func CategoryKeys() []string {
+	all := &AnalysisResponse{}
+	all.AddStandardCategories()
+	var keys []string
+	for _, c := range all.Categories() {
+		keys = append(keys, c.Key)
+	}
+	return keys
+}
+
+// AddStandardCategories adds the categories the backend reports that are
+// missing, with no issues and the top score
+func (r *AnalysisResponse) AddStandardCategories() {
+	for _, c := range []**Category{&r.Security, &r.Performance, &r.CodeQuality, &r.Maintainability, &r.BestPractices} {
+		if *c == nil {
+			*c = &Category{Score: MaxScore, Issues: []Issue{}}
+		}
+	}
+}
+
+// Categories returns the categories present in the analysis in report
+// order
+func (r *AnalysisResponse) Categories() []NamedCategory {
+	var categories []NamedCategory
+	for _, c := range []NamedCategory{
+		{Key: "security", Name: "Security", Category: r.Security},
+		{Key: "performance", Name: "Performance", Category: r.Performance},
+		{Key: "code_quality", Name: "Code Quality", Category: r.CodeQuality},
+		{Key: "maintainability", Name: "Maintainability", Category: r.Maintainability},
+		{Key: "best_practices", Name: "Best Practices", Category: r.BestPractices},
+	} {
+		if c.Category != nil {
+			categories = append(categories, c)
+		}
+	}
+	if r.Secrets != nil {
+		categories = append(categories, NamedCategory{Key: CategorySecrets, Name: "Secrets", Category: r.Secrets})
+	}
+	if r.Dependencies != nil {
+		categories = append(categories, NamedCategory{Key: CategoryDependencies, Name: "Dependencies", Category: r.Dependencies})
+	}
+	if r.Licenses != nil {
+		categories = append(categories, NamedCategory{Key: CategoryLicenses, Name: "Licenses", Category: r.Licenses})
+	}
+	return categories
+}
+
+// StatusError is returned when the backend responds with a non-200 status
+type StatusError struct {
+	StatusCode int
+	Body       string
+	// RetryAfter is the delay requested by a Retry-After header, if any
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("API request failed with status %d: %s", e.StatusCode, e.Body)
+}
+
+// IsAuthError reports whether err was caused by the backend rejecting the
+// API key
+func IsAuthError(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusUnauthorized || statusErr.StatusCode == http.StatusForbidden
+	}
+	return false
+}
+
+// IssueCount returns the number of issues across all categories
+func (r *AnalysisResponse) IssueCount() int {
+	count := 0
+	for _, c := range r.Categories() {
+		count += len(c.Category.Issues)
+	}
+	return count
 }
 
+// FilterIssues removes every issue for which keep returns false and reports
+// how many issues were removed
+func (r *AnalysisResponse) FilterIssues(keep func(category string, issue Issue) bool) int {
+	removed := 0
+	for _, c := range r.Categories() {
+		kept := c.Category.Issues[:0]
+		for _, issue := range c.Category.Issues {
+			if keep(c.Name, issue) {
+				kept = append(kept, issue)
+			} else {
+				removed++
+			}
+		}
+		c.Category.Issues = kept
+	}
+	return removed
+}
+
+// Client talks to the raincheck backend. Requests that fail with a network
+// error or a transient status are retried according to Retry, and each call
+// gives up once Timeout has elapsed across all attempts.
 type Client struct {
-	apiKey string
-	client *http.Client
+	baseURL string
+	apiKey  string
+	client  *http.Client
+
+	// Retry controls how failed requests are retried
+	Retry RetryPolicy
+	// Timeout bounds a call including all retries; zero means no limit
+	Timeout time.Duration
+	// JobThreshold is the size of code above which AnalyzeCode queues the
+	// analysis as a job on the backend; zero disables jobs
+	JobThreshold int
+	// Jobs queues every analysis as a job, whatever its size
+	Jobs bool
+
+	// noJobs is set once the backend turns out not to support jobs
+	noJobs atomic.Bool
+
+	// version caches the backend's analysis version
+	versionOnce sync.Once
+	version     string
+	versionErr  error
 }
 
-func NewClient(apiKey string) *Client {
+// DefaultTimeout is the default overall timeout of a single API call
+const DefaultTimeout = 3 * time.Minute
+
+// attemptTimeout bounds a single HTTP attempt. The backend itself gives up
+// on the AI service after 90 seconds.
+const attemptTimeout = 90 * time.Second
+
+// NewClient creates a client for the backend at baseURL, falling back to
+// DefaultBaseURL when baseURL is empty
+func NewClient(baseURL, apiKey string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
 	return &Client{
-		apiKey: apiKey,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		apiKey:  apiKey,
 		client: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout: attemptTimeout,
 		},
+		Retry:        DefaultRetryPolicy,
+		Timeout:      DefaultTimeout,
+		JobThreshold: DefaultJobThreshold,
+	}
+}
+
+// BaseURL returns the URL of the backend the client talks to
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// CodeRequest is a file sent for analysis. Everything but Code is optional
+// and lets the backend tailor its review to the language and project.
+type CodeRequest struct {
+	Code     string `json:"code"`
+	Filename string `json:"filename,omitempty"`
+	Language string `json:"language,omitempty"`
+	// Path is the file's path relative to the repository root
+	Path    string          `json:"path,omitempty"`
+	Context *ProjectContext `json:"context,omitempty"`
+}
+
+// ProjectContext describes the project a file belongs to
+type ProjectContext struct {
+	// Frameworks are the web and application frameworks the project
+	// depends on, such as django or express
+	Frameworks []string `json:"frameworks,omitempty"`
+	// GoModule is the module path from the nearest go.mod
+	GoModule string `json:"go_module,omitempty"`
+}
+
+// AnalyzeCode sends a file to the backend for analysis. Files larger than
+// JobThreshold, and every file when Jobs is set, are queued as jobs and
+// polled for, so slow analyses do not run into the backend's response
+// timeout. The request is aborted, and a queued job cancelled, when ctx is
+// cancelled.
+func (c *Client) AnalyzeCode(ctx context.Context, req CodeRequest) (*AnalysisResponse, error) {
+	if c.JobThreshold > 0 && !c.noJobs.Load() && (c.Jobs || len(req.Code) > c.JobThreshold) {
+		resp, err := c.analyzeAsJob(ctx, req)
+		if !errors.Is(err, errJobsUnsupported) {
+			return resp, err
+		}
+		// Older backends only analyze synchronously
+		c.noJobs.Store(true)
+	}
+
+	var analysisResp AnalysisResponse
+	if err := c.post(ctx, "/api/analyze-code", req, &analysisResp); err != nil {
+		return nil, err
 	}
+	return &analysisResp, nil
 }
 
-func (c *Client) AnalyzeCode(code string) (*AnalysisResponse, error) {
-	url := fmt.Sprintf("%s/api/analyze-code", baseURL)
+// FixResponse is the backend's proposed fix for an issue
+type FixResponse struct {
+	Success     bool   `json:"success"`
+	Diff        string `json:"diff"`
+	Explanation string `json:"explanation"`
+	Confidence  int    `json:"confidence"`
+	Changelog   string `json:"changelog"`
+}
 
-	// Create request body
+// FixIssue asks the backend for a fix to problem in code, guided by the
+// issue's suggestion
+func (c *Client) FixIssue(ctx context.Context, code, suggestion, problem string) (*FixResponse, error) {
 	reqBody := map[string]string{
-		"code": code,
+		"code":       code,
+		"suggestion": suggestion,
+		"problem":    problem,
+	}
+
+	var fixResp FixResponse
+	if err := c.post(ctx, "/api/issues/fix", reqBody, &fixResp); err != nil {
+		return nil, err
 	}
-	jsonBody, err := json.Marshal(reqBody)
+	return &fixResp, nil
+}
+
+// AnalysisVersion returns the version the backend reports for its analyses,
+// which changes with its prompt, model and settings. It is fetched once per
+// client; backends that do not report a version return "".
+func (c *Client) AnalysisVersion(ctx context.Context) (string, error) {
+	c.versionOnce.Do(func() {
+		var out struct {
+			AnalysisVersion string `json:"analysis_version"`
+		}
+		err := c.send(ctx, http.MethodGet, "/api/version", nil, &out)
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && (statusErr.StatusCode == http.StatusNotFound || statusErr.StatusCode == http.StatusMethodNotAllowed) {
+			err = nil
+		}
+		c.version, c.versionErr = out.AnalysisVersion, err
+	})
+	return c.version, c.versionErr
+}
+
+// VerifyKey checks that the backend accepts the client's API key by
+// requesting an authenticated, read-only endpoint
+func (c *Client) VerifyKey(ctx context.Context) error {
+	var stats map[string]any
+	return c.send(ctx, http.MethodGet, "/api/stats", nil, &stats)
+}
+
+// post sends body as JSON to path and decodes the response into out
+func (c *Client) post(ctx context.Context, path string, body, out any) error {
+	jsonBody, err := json.Marshal(body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+		return fmt.Errorf("failed to marshal request body: %w", err)
+	}
+	return c.send(ctx, http.MethodPost, path, jsonBody, out)
+}
+
+// send performs a request and decodes the response into out, retrying
+// transient failures until the attempts or the timeout run out
+func (c *Client) send(ctx context.Context, method, path string, jsonBody []byte, out any) error {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	attempts := c.Retry.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
 	}
 
-	// Create request
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
+	for attempt := 1; ; attempt++ {
+		respBody, err := c.do(ctx, method, path, jsonBody)
+		if err == nil {
+			if err := json.Unmarshal(respBody, out); err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
+			return nil
+		}
+
+		if attempt >= attempts || !retryable(ctx, err) {
+			if attempt > 1 {
+				return fmt.Errorf("giving up after %d attempt(s): %w", attempt, err)
+			}
+			return err
+		}
+
+		if err := c.Retry.wait(ctx, attempt, err); err != nil {
+			return fmt.Errorf("giving up after %d attempt(s): %w", attempt, err)
+		}
+	}
+}
+
+// do performs a single request and returns the body of a successful
+// response
+func (c *Client) do(ctx context.Context, method, path string, jsonBody []byte) ([]byte, error) {
+	var reqBody io.Reader
+	if jsonBody != nil {
+		reqBody = bytes.NewReader(jsonBody)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
-
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
+	if jsonBody != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
 	req.Header.Set("X-API-Key", c.apiKey)
 
-	// Send request
 	resp, err := c.client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	// Read response body
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	// Check status code
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &StatusError{
+			StatusCode: resp.StatusCode,
+			Body:       string(body),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
 	}
-
-	// Parse response
-	var analysisResp AnalysisResponse
-	if err := json.Unmarshal(body, &analysisResp); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	return &analysisResp, nil
+	return body, nil
 }
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// DefaultJobThreshold is the default size of code above which analyses run
+// as jobs
+const DefaultJobThreshold = 32 * 1024
+
+// Job statuses reported by the backend
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobDone      = "done"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// Job is an analysis queued on the backend
+type Job struct {
+	ID     string            `json:"id"`
+	Status string            `json:"status"`
+	Result *AnalysisResponse `json:"result,omitempty"`
+	Error  string            `json:"error,omitempty"`
+}
+
+// Delays between polls of a job, growing from the first to the last
+const (
+	firstPollDelay = time.Second
+	maxPollDelay   = 5 * time.Second
+)
+
+// errJobsUnsupported is returned by analyzeAsJob when the backend has no
+// job API
+var errJobsUnsupported = errors.New("backend does not support analysis jobs")
+
+// analyzeAsJob queues req as a job and polls it until it finishes, giving
+// up after Timeout. The job is cancelled when ctx is cancelled or the
+// timeout expires.
+func (c *Client) analyzeAsJob(ctx context.Context, req CodeRequest) (*AnalysisResponse, error) {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	// Queuing a job is not idempotent, so it is only sent again when the
+	// backend turned it away: a retry after a lost response would queue a
+	// duplicate analysis nobody polls
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+	attempts := max(c.Retry.MaxAttempts, 1)
+	var respBody []byte
+	for attempt := 1; ; attempt++ {
+		respBody, err = c.do(ctx, http.MethodPost, "/api/jobs", body)
+		if err == nil {
+			break
+		}
+		var statusErr *StatusError
+		if !errors.As(err, &statusErr) {
+			return nil, err
+		}
+		switch statusErr.StatusCode {
+		case http.StatusNotFound, http.StatusMethodNotAllowed:
+			return nil, errJobsUnsupported
+		case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+			// Nothing was queued, for example because the queue is full
+		default:
+			return nil, err
+		}
+		if attempt >= attempts {
+			return nil, fmt.Errorf("giving up after %d attempt(s): %w", attempt, err)
+		}
+		if err := c.Retry.wait(ctx, attempt, err); err != nil {
+			return nil, fmt.Errorf("giving up after %d attempt(s): %w", attempt, err)
+		}
+	}
+	var job Job
+	if err := json.Unmarshal(respBody, &job); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	delay := firstPollDelay
+	for {
+		switch job.Status {
+		case JobDone:
+			if job.Result == nil {
+				return nil, fmt.Errorf("analysis job %s finished without a result", job.ID)
+			}
+			return job.Result, nil
+		case JobFailed:
+			return nil, fmt.Errorf("analysis failed: %s", job.Error)
+		case JobCancelled:
+			return nil, fmt.Errorf("analysis job %s was cancelled", job.ID)
+		}
+
+		select {
+		case <-ctx.Done():
+			c.cancelJob(job.ID)
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxPollDelay)
+
+		if err := c.send(ctx, http.MethodGet, "/api/jobs/"+job.ID, nil, &job); err != nil {
+			if ctx.Err() != nil {
+				c.cancelJob(job.ID)
+			}
+			return nil, err
+		}
+	}
+}
+
+// cancelJob asks the backend to cancel a job that is no longer waited for.
+// It is best effort: a job that cannot be cancelled runs to completion and
+// its result expires on the backend.
+func (c *Client) cancelJob(id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c.do(ctx, http.MethodDelete, "/api/jobs/"+id, nil)
+}
@@ -0,0 +1,170 @@
+package baseline
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"raincheck/internal/api"
+	"raincheck/internal/report"
+)
+
+// FileName is the default name of the baseline file at the project root
+const FileName = ".raincheck-baseline.json"
+
+// version is the baseline file format version
+const version = 1
+
+// contextLines is the number of lines on each side of an issue hashed into
+// its fingerprint
+const contextLines = 2
+
+// Entry is a single known finding
+type Entry struct {
+	Fingerprint string `json:"fingerprint"`
+	Path        string `json:"path"`
+	Category    string `json:"category"`
+	Type        string `json:"type"`
+	Severity    string `json:"severity"`
+	Description string `json:"description"`
+	Line        int    `json:"line,omitempty"`
+}
+
+// Baseline is a set of known findings that are excluded from new results
+type Baseline struct {
+	Version   int       `json:"version"`
+	Generated time.Time `json:"generated_at"`
+	Entries   []Entry   `json:"entries"`
+}
+
+// New creates an empty baseline
+func New() *Baseline {
+	return &Baseline{Version: version, Generated: time.Now().UTC()}
+}
+
+// Load reads a baseline file
+func Load(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline: %w", err)
+	}
+
+	var b Baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline %s: %w", path, err)
+	}
+	if b.Version != version {
+		return nil, fmt.Errorf("unsupported baseline version %d in %s", b.Version, path)
+	}
+	return &b, nil
+}
+
+// Save writes the baseline to path with entries in a stable order
+func (b *Baseline) Save(path string) error {
+	sort.SliceStable(b.Entries, func(i, j int) bool {
+		if b.Entries[i].Path != b.Entries[j].Path {
+			return b.Entries[i].Path < b.Entries[j].Path
+		}
+		if b.Entries[i].Line != b.Entries[j].Line {
+			return b.Entries[i].Line < b.Entries[j].Line
+		}
+		return b.Entries[i].Fingerprint < b.Entries[j].Fingerprint
+	})
+
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal baseline: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write baseline: %w", err)
+	}
+	return nil
+}
+
+// Add records every issue of a file's analysis. path should be relative to
+// the project root so the baseline does not depend on the working directory.
+func (b *Baseline) Add(path string, analysis *api.AnalysisResponse, content []byte) {
+	lines := splitLines(content)
+	for _, c := range analysis.Categories() {
+		for _, issue := range c.Category.Issues {
+			b.Entries = append(b.Entries, Entry{
+				Fingerprint: Fingerprint(path, issue, lines),
+				Path:        path,
+				Category:    c.Name,
+				Type:        issue.Type,
+				Severity:    api.NormalizeSeverity(issue.Severity),
+				Description: issue.Description,
+				Line:        issue.Line,
+			})
+		}
+	}
+}
+
+// Apply moves the issues of f that match baseline entries from its analysis
+// into f.Baselined. Each entry matches at most one issue, so a finding that
+// appears more often than recorded is still reported as new.
+func (b *Baseline) Apply(f *report.File, path string, content []byte) {
+	remaining := make(map[string]int)
+	for _, e := range b.Entries {
+		if e.Path == path {
+			remaining[e.Fingerprint]++
+		}
+	}
+	if len(remaining) == 0 {
+		return
+	}
+
+	lines := splitLines(content)
+	f.Analysis.FilterIssues(func(category string, issue api.Issue) bool {
+		fp := Fingerprint(path, issue, lines)
+		if remaining[fp] == 0 {
+			return true
+		}
+		remaining[fp]--
+		f.Baselined = append(f.Baselined, report.Finding{Category: category, Issue: issue})
+		return false
+	})
+}
+
+var digits = regexp.MustCompile(`[0-9]+`)
+
+// Fingerprint identifies an issue independently of its exact line number by
+// hashing the file path, issue type, normalized description and the
+// whitespace-normalized source lines around the issue
+func Fingerprint(path string, issue api.Issue, lines []string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00", path, normalize(issue.Type), normalize(issue.Description))
+
+	if issue.Line > 0 && issue.Line <= len(lines) {
+		start := issue.Line - 1 - contextLines
+		if start < 0 {
+			start = 0
+		}
+		end := issue.Line + contextLines
+		if end > len(lines) {
+			end = len(lines)
+		}
+		for _, line := range lines[start:end] {
+			fmt.Fprintf(h, "%s\n", strings.Join(strings.Fields(line), " "))
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// normalize lowercases text, collapses whitespace and masks numbers, which
+// often carry line numbers that shift between runs
+func normalize(s string) string {
+	s = digits.ReplaceAllString(strings.ToLower(s), "#")
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func splitLines(content []byte) []string {
+	return strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+}
@@ -0,0 +1,186 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"raincheck/internal/api"
+)
+
+// Cache stores analysis results on disk keyed by file content
+type Cache struct {
+	dir string
+}
+
+// entry is the on-disk form of a cached result
+type entry struct {
+	Server   string                `json:"server"`
+	Version  string                `json:"version"`
+	Created  time.Time             `json:"created_at"`
+	Analysis *api.AnalysisResponse `json:"analysis"`
+}
+
+// Stats describes the contents of the cache
+type Stats struct {
+	Dir     string
+	Entries int
+	Size    int64
+	Oldest  time.Time
+	Newest  time.Time
+}
+
+// DefaultDir returns the default cache directory, ~/.cache/raincheck/results
+// on Linux
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate cache directory: %w", err)
+	}
+	return filepath.Join(dir, "raincheck", "results"), nil
+}
+
+// Open returns the cache stored in dir, creating the directory if needed
+func Open(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &Cache{dir: dir}, nil
+}
+
+// Key identifies the analysis of req by the backend at server, whose
+// analyses have the given version. The file's name, language and project are
+// part of the key since the backend tailors its review to them.
+func Key(server, version string, req api.CodeRequest) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00", version, server)
+	json.NewEncoder(h).Encode(req)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Get returns the cached analysis for key. Each hit refreshes the entry's
+// modification time so Prune removes the least recently used results.
+func (c *Cache) Get(key string) (*api.AnalysisResponse, bool) {
+	path := c.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil || e.Analysis == nil {
+		return nil, false
+	}
+
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return e.Analysis, true
+}
+
+// Put stores the analysis for key, made by server at the given version
+func (c *Cache) Put(key, server, version string, analysis *api.AnalysisResponse) error {
+	data, err := json.Marshal(entry{Server: server, Version: version, Created: time.Now().UTC(), Analysis: analysis})
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache entry: %w", err)
+	}
+
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+
+	// Write to a temporary file first so concurrent readers never see a
+	// partial entry
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return nil
+}
+
+// Stats walks the cache and reports its size
+func (c *Cache) Stats() (Stats, error) {
+	stats := Stats{Dir: c.dir}
+	err := c.walk(func(path string, info fs.FileInfo) error {
+		stats.Entries++
+		stats.Size += info.Size()
+		if stats.Oldest.IsZero() || info.ModTime().Before(stats.Oldest) {
+			stats.Oldest = info.ModTime()
+		}
+		if info.ModTime().After(stats.Newest) {
+			stats.Newest = info.ModTime()
+		}
+		return nil
+	})
+	return stats, err
+}
+
+// Prune removes entries not used within maxAge, or every entry when maxAge
+// is zero. It returns the number of entries and bytes removed.
+func (c *Cache) Prune(maxAge time.Duration) (int, int64, error) {
+	var (
+		removed int
+		freed   int64
+	)
+	cutoff := time.Now().Add(-maxAge)
+	err := c.walk(func(path string, info fs.FileInfo) error {
+		if maxAge > 0 && info.ModTime().After(cutoff) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove cache entry: %w", err)
+		}
+		removed++
+		freed += info.Size()
+		return nil
+	})
+	return removed, freed, err
+}
+
+// path shards entries by the first byte of their key to keep directories small
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key+".json")
+}
+
+// walk calls fn for every cache entry
+func (c *Cache) walk(fn func(path string, info fs.FileInfo) error) error {
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".json") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		return fn(path, info)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read cache: %w", err)
+	}
+	return nil
+}
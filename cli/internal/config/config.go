@@ -9,15 +9,86 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+
+	"golang.org/x/crypto/argon2"
 )
 
 const (
-	configFileName = ".raincheck"
-	keySize        = 32 // 256 bits for AES-256
+	dirName             = "raincheck"
+	credentialsFileName = "credentials"
+	keyFileName         = "key"
+	keySize             = 32 // 256 bits for AES-256
+	storeVersion        = 2
+
+	// Files used before the store moved to the XDG config directory
+	legacyConfigFileName = ".raincheck"
+	legacyKeyFileName    = ".raincheck_key"
 )
 
-type Config struct {
+// Environment variables read by the credential store
+const (
+	// EnvAPIKey provides an API key directly, bypassing the store (for CI)
+	EnvAPIKey = "RAINCHECK_API_KEY"
+	// EnvPassphrase unlocks a passphrase-protected store without a prompt
+	EnvPassphrase = "RAINCHECK_PASSPHRASE"
+)
+
+// argon2id parameters for passphrase-derived keys
+const (
+	kdfArgon2id  = "argon2id"
+	argonTime    = 1
+	argonMemory  = 64 * 1024 // KiB
+	argonThreads = 4
+	saltSize     = 16
+)
+
+// DefaultProfile is the profile used when none is selected
+const DefaultProfile = "default"
+
+// Profile holds the credentials for one backend
+type Profile struct {
 	APIKey string `json:"api_key"`
+	// Server is the backend URL, empty for the default backend
+	Server string `json:"server,omitempty"`
+}
+
+// Config is the encrypted credential store
+type Config struct {
+	// APIKey is the key stored by versions without profiles; it is moved
+	// into the default profile on load
+	APIKey string `json:"api_key,omitempty"`
+	// Current is the profile used when none is selected explicitly
+	Current  string             `json:"current,omitempty"`
+	Profiles map[string]Profile `json:"profiles,omitempty"`
+
+	// passphrase protects the store when set; otherwise a random key kept
+	// in a separate file is used
+	passphrase string
+}
+
+// envelope is the on-disk form of the store
+type envelope struct {
+	Version int `json:"version"`
+	// KDF names the key derivation function for passphrase-protected
+	// stores, empty when the key file is used
+	KDF     string `json:"kdf,omitempty"`
+	Salt    []byte `json:"salt,omitempty"`
+	Time    uint32 `json:"time,omitempty"`
+	Memory  uint32 `json:"memory,omitempty"`
+	Threads uint8  `json:"threads,omitempty"`
+	// Data is the nonce followed by the encrypted config
+	Data []byte `json:"data"`
+}
+
+// Dir returns the directory holding the credential store, which follows
+// XDG_CONFIG_HOME on Linux
+func Dir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate config directory: %w", err)
+	}
+	return filepath.Join(dir, dirName), nil
 }
 
 // generateKey generates a random encryption key
@@ -30,13 +101,8 @@ func generateKey() ([]byte, error) {
 }
 
 // getOrCreateKey retrieves the encryption key or creates a new one
-func getOrCreateKey() ([]byte, error) {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %w", err)
-	}
-
-	keyPath := filepath.Join(homeDir, ".raincheck_key")
+func getOrCreateKey(dir string) ([]byte, error) {
+	keyPath := filepath.Join(dir, keyFileName)
 
 	// Try to read existing key
 	if key, err := os.ReadFile(keyPath); err == nil {
@@ -57,107 +123,304 @@ func getOrCreateKey() ([]byte, error) {
 	return key, nil
 }
 
-// SaveAPIKey encrypts and saves the API key
-func SaveAPIKey(apiKey string) error {
-	key, err := getOrCreateKey()
+// deriveKey derives an encryption key from a passphrase
+func deriveKey(passphrase string, env envelope) []byte {
+	return argon2.IDKey([]byte(passphrase), env.Salt, env.Time, env.Memory, env.Threads, keySize)
+}
+
+// Load decrypts the credential store, returning an empty store when none
+// has been saved yet. A passphrase-protected store is unlocked with
+// RAINCHECK_PASSPHRASE or a prompt.
+func Load() (*Config, error) {
+	dir, err := Dir()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Create cipher block
-	block, err := aes.NewCipher(key)
+	data, err := os.ReadFile(filepath.Join(dir, credentialsFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return loadLegacy()
+	}
 	if err != nil {
-		return fmt.Errorf("failed to create cipher: %w", err)
+		return nil, fmt.Errorf("failed to read config: %w", err)
 	}
 
-	// Create GCM mode
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return fmt.Errorf("failed to create GCM: %w", err)
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("failed to parse credentials: %w", err)
+	}
+	if env.Version != storeVersion {
+		return nil, fmt.Errorf("unsupported credentials version %d", env.Version)
 	}
 
-	// Create nonce
-	nonce := make([]byte, gcm.NonceSize())
-	if _, err := rand.Read(nonce); err != nil {
-		return fmt.Errorf("failed to generate nonce: %w", err)
+	var (
+		key        []byte
+		passphrase string
+	)
+	switch env.KDF {
+	case "":
+		if key, err = os.ReadFile(filepath.Join(dir, keyFileName)); err != nil {
+			return nil, fmt.Errorf("failed to read encryption key: %w", err)
+		}
+	case kdfArgon2id:
+		if passphrase, err = ReadPassphrase("Passphrase: "); err != nil {
+			return nil, err
+		}
+		key = deriveKey(passphrase, env)
+	default:
+		return nil, fmt.Errorf("unsupported key derivation %q", env.KDF)
 	}
 
-	// Encrypt the API key
-	config := Config{APIKey: apiKey}
-	configBytes, err := json.Marshal(config)
+	plaintext, err := decrypt(key, env.Data)
 	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
+		if env.KDF != "" {
+			return nil, fmt.Errorf("failed to decrypt credentials (wrong passphrase?)")
+		}
+		return nil, err
 	}
 
-	ciphertext := gcm.Seal(nonce, nonce, configBytes, nil)
+	config, err := parse(plaintext)
+	if err != nil {
+		return nil, err
+	}
+	config.passphrase = passphrase
+	return config, nil
+}
 
-	// Save to file
+// loadLegacy reads a store saved in the home directory by older versions.
+// The next Save moves it to the config directory.
+func loadLegacy() (*Config, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		return fmt.Errorf("failed to get home directory: %w", err)
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
 	}
 
-	configPath := filepath.Join(homeDir, configFileName)
-	if err := os.WriteFile(configPath, ciphertext, 0600); err != nil {
-		return fmt.Errorf("failed to save config: %w", err)
+	ciphertext, err := os.ReadFile(filepath.Join(homeDir, legacyConfigFileName))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &Config{}, nil
+		}
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	key, err := os.ReadFile(filepath.Join(homeDir, legacyKeyFileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read encryption key: %w", err)
 	}
 
-	return nil
+	plaintext, err := decrypt(key, ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	return parse(plaintext)
 }
 
-// GetAPIKey retrieves and decrypts the API key
-func GetAPIKey() (string, error) {
-	key, err := getOrCreateKey()
+// parse unmarshals a decrypted store
+func parse(plaintext []byte) (*Config, error) {
+	var config Config
+	if err := json.Unmarshal(plaintext, &config); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+
+	// Move a key saved before profiles existed into the default profile
+	if config.APIKey != "" {
+		if _, ok := config.Profiles[DefaultProfile]; !ok {
+			config.SetProfile(DefaultProfile, Profile{APIKey: config.APIKey})
+		}
+		config.APIKey = ""
+	}
+
+	return &config, nil
+}
+
+// Save encrypts and saves the credential store. Stores without a passphrase
+// use a random key kept in a separate file.
+func (c *Config) Save() error {
+	dir, err := Dir()
 	if err != nil {
-		return "", err
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
-	// Read encrypted config
-	homeDir, err := os.UserHomeDir()
+	env := envelope{Version: storeVersion}
+	var key []byte
+	if c.passphrase != "" {
+		env.KDF = kdfArgon2id
+		env.Salt = make([]byte, saltSize)
+		if _, err := rand.Read(env.Salt); err != nil {
+			return fmt.Errorf("failed to generate salt: %w", err)
+		}
+		env.Time, env.Memory, env.Threads = argonTime, argonMemory, argonThreads
+		key = deriveKey(c.passphrase, env)
+	} else if key, err = getOrCreateKey(dir); err != nil {
+		return err
+	}
+
+	configBytes, err := json.Marshal(c)
 	if err != nil {
-		return "", fmt.Errorf("failed to get home directory: %w", err)
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	if env.Data, err = encrypt(key, configBytes); err != nil {
+		return err
 	}
 
-	configPath := filepath.Join(homeDir, configFileName)
-	ciphertext, err := os.ReadFile(configPath)
+	data, err := json.Marshal(env)
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return "", fmt.Errorf("no API key found. Please run 'raincheck login <apikey>' first")
+		return fmt.Errorf("failed to marshal credentials: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, credentialsFileName), data, 0600); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	// A passphrase-protected store must not leave a usable key behind
+	if c.passphrase != "" {
+		if err := os.Remove(filepath.Join(dir, keyFileName)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove old key: %w", err)
 		}
-		return "", fmt.Errorf("failed to read config: %w", err)
 	}
+	return removeLegacy()
+}
 
-	// Create cipher block
-	block, err := aes.NewCipher(key)
+// Remove deletes the credential store and its key
+func Remove() error {
+	dir, err := Dir()
 	if err != nil {
-		return "", fmt.Errorf("failed to create cipher: %w", err)
+		return err
+	}
+	for _, name := range []string{credentialsFileName, keyFileName} {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove credentials: %w", err)
+		}
 	}
+	return removeLegacy()
+}
 
-	// Create GCM mode
-	gcm, err := cipher.NewGCM(block)
+// removeLegacy deletes the store files of older versions
+func removeLegacy() error {
+	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		return "", fmt.Errorf("failed to create GCM: %w", err)
+		return fmt.Errorf("failed to get home directory: %w", err)
+	}
+	for _, name := range []string{legacyConfigFileName, legacyKeyFileName} {
+		if err := os.Remove(filepath.Join(homeDir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove old credentials: %w", err)
+		}
 	}
+	return nil
+}
 
-	// Extract nonce
-	nonceSize := gcm.NonceSize()
-	if len(ciphertext) < nonceSize {
-		return "", fmt.Errorf("ciphertext too short")
+// encrypt seals plaintext with AES-GCM, prefixing the nonce
+func encrypt(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
 	}
 
-	nonce, ciphertext := ciphertext[:nonceSize], ciphertext[nonceSize:]
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
 
-	// Decrypt
+// decrypt opens data produced by encrypt
+func decrypt(key, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonceSize := gcm.NonceSize()
+	if len(data) < nonceSize {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+
+	nonce, ciphertext := data[:nonceSize], data[nonceSize:]
 	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to decrypt: %w", err)
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
 	}
+	return plaintext, nil
+}
 
-	// Unmarshal config
-	var config Config
-	if err := json.Unmarshal(plaintext, &config); err != nil {
-		return "", fmt.Errorf("failed to unmarshal config: %w", err)
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+	return gcm, nil
+}
+
+// SetPassphrase protects the store with a passphrase from the next Save on.
+// An empty passphrase switches back to a key file.
+func (c *Config) SetPassphrase(passphrase string) {
+	c.passphrase = passphrase
+}
+
+// Protected reports whether the store is protected by a passphrase
+func (c *Config) Protected() bool {
+	return c.passphrase != ""
+}
+
+// SetProfile stores a profile, making it current if no profile is current
+func (c *Config) SetProfile(name string, p Profile) {
+	if c.Profiles == nil {
+		c.Profiles = make(map[string]Profile)
+	}
+	c.Profiles[name] = p
+	if c.Current == "" {
+		c.Current = name
 	}
+}
 
-	return config.APIKey, nil
+// DeleteProfile removes a profile and reports whether it existed
+func (c *Config) DeleteProfile(name string) bool {
+	if _, ok := c.Profiles[name]; !ok {
+		return false
+	}
+	delete(c.Profiles, name)
+	if c.Current == name {
+		c.Current = ""
+	}
+	return true
+}
+
+// ActiveName returns the profile to use: name when given, otherwise the
+// current profile, otherwise DefaultProfile
+func (c *Config) ActiveName(name string) string {
+	if name != "" {
+		return name
+	}
+	if c.Current != "" {
+		return c.Current
+	}
+	return DefaultProfile
+}
+
+// Profile returns the profile selected by name as described by ActiveName
+func (c *Config) Profile(name string) (Profile, error) {
+	name = c.ActiveName(name)
+	p, ok := c.Profiles[name]
+	if !ok || p.APIKey == "" {
+		if name == DefaultProfile {
+			return Profile{}, fmt.Errorf("no API key found. Please run 'raincheck login <apikey>' first")
+		}
+		return Profile{}, fmt.Errorf("no API key found for profile %q. Please run 'raincheck login --profile %s <apikey>' first", name, name)
+	}
+	return p, nil
+}
+
+// ProfileNames returns the names of all stored profiles in sorted order
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// stdin is shared by every read so that lines buffered by one read, such as
+// a piped confirmation, are not lost to the next
+var stdin = bufio.NewReader(os.Stdin)
+
+// ReadPassphrase returns RAINCHECK_PASSPHRASE when set, and otherwise reads
+// a passphrase from standard input after printing prompt. Echo is turned off
+// while typing when standard input is a terminal.
+func ReadPassphrase(prompt string) (string, error) {
+	if p := os.Getenv(EnvPassphrase); p != "" {
+		return p, nil
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	if isTerminal(os.Stdin) && setEcho(false) == nil {
+		defer func() {
+			setEcho(true)
+			fmt.Fprintln(os.Stderr)
+		}()
+	}
+
+	line, err := stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	passphrase := strings.TrimRight(line, "\r\n")
+	if passphrase == "" {
+		return "", fmt.Errorf("passphrase must not be empty")
+	}
+	return passphrase, nil
+}
+
+// NewPassphrase reads a passphrase twice and checks that both entries match
+func NewPassphrase() (string, error) {
+	if p := os.Getenv(EnvPassphrase); p != "" {
+		return p, nil
+	}
+
+	first, err := ReadPassphrase("New passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	second, err := ReadPassphrase("Confirm passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if first != second {
+		return "", fmt.Errorf("passphrases do not match")
+	}
+	return first, nil
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// setEcho toggles terminal echo with stty. It fails on systems without
+// stty, in which case the passphrase is read with echo on.
+func setEcho(on bool) error {
+	arg := "-echo"
+	if on {
+		arg = "echo"
+	}
+	cmd := exec.Command("stty", arg)
+	cmd.Stdin = os.Stdin
+	return cmd.Run()
+}
@@ -0,0 +1,152 @@
+package dashboard
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"raincheck/internal/report"
+	"raincheck/internal/secrets"
+)
+
+//go:embed index.html
+var indexHTML []byte
+
+// maxSourceSize limits the size of source files served for snippets
+const maxSourceSize = 2 << 20
+
+// Server serves the dashboard for a single review run
+type Server struct {
+	report  *report.Report
+	sources map[string]string // report path -> path on disk
+}
+
+// New creates a dashboard server for rep. Only files listed in the report
+// can be read through the source endpoint.
+func New(rep *report.Report) *Server {
+	s := &Server{report: rep, sources: make(map[string]string)}
+	for _, f := range rep.Files {
+		path := filepath.FromSlash(f.Path)
+		if !filepath.IsAbs(path) && rep.Dir != "" {
+			path = filepath.Join(rep.Dir, path)
+		}
+		s.sources[f.Path] = path
+	}
+	return s
+}
+
+// Handler returns the HTTP handler serving the dashboard page and its data
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleIndex)
+	mux.HandleFunc("/api/report", s.handleReport)
+	mux.HandleFunc("/api/source", s.handleSource)
+	return mux
+}
+
+// Listen binds addr and returns the listener together with the dashboard URL
+func Listen(addr string) (net.Listener, string, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	return ln, "http://" + ln.Addr().String() + "/", nil
+}
+
+// Serve serves the dashboard on ln until ctx is cancelled
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	srv := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			return fmt.Errorf("failed to stop dashboard: %w", err)
+		}
+		if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}
+}
+
+// Open launches the system browser on url
+func Open(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to open browser: %w", err)
+	}
+	go cmd.Wait()
+	return nil
+}
+
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(indexHTML)
+}
+
+func (s *Server) handleReport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := report.Write(w, report.FormatJSON, s.report); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) handleSource(w http.ResponseWriter, r *http.Request) {
+	path, ok := s.sources[r.URL.Query().Get("path")]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		http.Error(w, "source file is no longer available", http.StatusNotFound)
+		return
+	}
+	if info.Size() > maxSourceSize {
+		http.Error(w, "source file is too large to display", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		http.Error(w, "failed to read source file", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(secrets.RedactContent(path, content))
+}
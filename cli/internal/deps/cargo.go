@@ -0,0 +1,66 @@
+package deps
+
+import "strings"
+
+// parseCargoLock reads the packages of a Cargo.lock. Workspace members and
+// path dependencies have no source and are not published crates, so they
+// are skipped; the crates they depend on are the direct dependencies.
+func parseCargoLock(content []byte) ([]Package, error) {
+	tables := parseTOMLTables(content, "package")
+
+	versions := make(map[string][]string)
+	for _, t := range tables {
+		versions[t.values["name"]] = append(versions[t.values["name"]], t.values["version"])
+	}
+
+	direct := make(map[string]bool)
+	for _, t := range tables {
+		if t.values["source"] != "" {
+			continue
+		}
+		for _, dep := range t.arrays["dependencies"] {
+			if id := cargoDependency(dep, versions); id != "" {
+				direct[id] = true
+			}
+		}
+	}
+
+	var pkgs []Package
+	for _, t := range tables {
+		name, version, source := t.values["name"], t.values["version"], t.values["source"]
+		if name == "" || version == "" || !strings.HasPrefix(source, "registry+") {
+			continue
+		}
+		p := Package{
+			Ecosystem: EcosystemCargo,
+			Name:      name,
+			Version:   version,
+			Line:      t.line,
+			Direct:    direct[name+"@"+version],
+		}
+		if checksum := t.values["checksum"]; checksum != "" {
+			p.Hashes = []Hash{{Algorithm: HashSHA256, Value: checksum}}
+		}
+		for _, dep := range t.arrays["dependencies"] {
+			if id := cargoDependency(dep, versions); id != "" {
+				p.Dependencies = append(p.Dependencies, id)
+			}
+		}
+		pkgs = append(pkgs, p)
+	}
+	return pkgs, nil
+}
+
+// cargoDependency resolves an entry of a dependencies array, "name",
+// "name version" or "name version (source)", to a package ID. The version
+// is only given when several versions of the crate are locked.
+func cargoDependency(dep string, versions map[string][]string) string {
+	fields := strings.Fields(dep)
+	switch {
+	case len(fields) >= 2:
+		return fields[0] + "@" + fields[1]
+	case len(fields) == 1 && len(versions[fields[0]]) == 1:
+		return fields[0] + "@" + versions[fields[0]][0]
+	}
+	return ""
+}
@@ -0,0 +1,175 @@
+package deps
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Ecosystems as named by OSV advisories
+const (
+	EcosystemGo    = "Go"
+	EcosystemNPM   = "npm"
+	EcosystemPyPI  = "PyPI"
+	EcosystemCargo = "crates.io"
+	EcosystemMaven = "Maven"
+)
+
+// Package is a dependency pinned to a version by a manifest or lockfile
+type Package struct {
+	Ecosystem string
+	Name      string
+	Version   string
+	// Line is where the package is declared in the manifest, 0 if unknown
+	Line int
+	// Direct is set for dependencies the project declares itself rather
+	// than ones pulled in by other packages
+	Direct bool
+	// Dev is set for development and test only dependencies
+	Dev bool
+	// Hashes are the checksums the lockfile records for the package
+	Hashes []Hash
+	// Dependencies are the IDs of the packages this one requires, when the
+	// lockfile records them
+	Dependencies []string
+	// License is the license expression the lockfile records for the
+	// package, "" when it records none
+	License string
+}
+
+// ID returns the package as name@version
+func (p Package) ID() string {
+	return p.Name + "@" + p.Version
+}
+
+// Hash is a checksum of a package; Value is hex encoded
+type Hash struct {
+	Algorithm string
+	Value     string
+}
+
+// Hash algorithms, named as in CycloneDX
+const (
+	HashSHA1   = "SHA-1"
+	HashSHA256 = "SHA-256"
+	HashSHA384 = "SHA-384"
+	HashSHA512 = "SHA-512"
+)
+
+// parsers maps manifest file names to their parser
+var parsers = map[string]func(content []byte) ([]Package, error){
+	"go.mod":            parseGoMod,
+	"go.sum":            parseGoSum,
+	"package.json":      parsePackageJSON,
+	"package-lock.json": parsePackageLock,
+	"requirements.txt":  parseRequirements,
+	"poetry.lock":       parsePoetryLock,
+	"Cargo.lock":        parseCargoLock,
+	"pom.xml":           parsePom,
+}
+
+// IsManifest reports whether path names a manifest or lockfile that Parse
+// understands
+func IsManifest(path string) bool {
+	_, ok := parsers[filepath.Base(path)]
+	return ok
+}
+
+// Superseded reports whether the manifest at path adds nothing when the
+// sibling files in names are also scanned, such as package.json next to
+// package-lock.json or go.sum next to go.mod
+func Superseded(path string, names map[string]bool) bool {
+	switch filepath.Base(path) {
+	case "package.json":
+		return names["package-lock.json"]
+	case "go.sum":
+		return names["go.mod"]
+	}
+	return false
+}
+
+// ChecksumFile returns the name of the file next to a manifest that records
+// the checksums the manifest lacks, go.sum for go.mod, or "" if there is
+// none
+func ChecksumFile(path string) string {
+	if filepath.Base(path) == "go.mod" {
+		return "go.sum"
+	}
+	return ""
+}
+
+// AddChecksums copies the checksums recorded in content, the checksum file
+// of the manifest pkgs were parsed from, onto the matching packages
+func AddChecksums(pkgs []Package, content []byte) {
+	hashes := goSumHashes(content)
+	for i := range pkgs {
+		if h, ok := hashes[pkgs[i].ID()]; ok {
+			pkgs[i].Hashes = append(pkgs[i].Hashes, h)
+		}
+	}
+}
+
+// Parse returns the packages pinned by the manifest at path. Dependencies
+// declared with a version range rather than an exact version are skipped
+// since the installed version is unknown.
+func Parse(path string, content []byte) ([]Package, error) {
+	parse, ok := parsers[filepath.Base(path)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported manifest %s", filepath.Base(path))
+	}
+	pkgs, err := parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filepath.Base(path), err)
+	}
+	return pkgs, nil
+}
+
+// pypiSeparators matches the runs of separators PEP 503 treats as equal
+var pypiSeparators = regexp.MustCompile(`[-_.]+`)
+
+// NormalizeName returns the canonical form of a package name in ecosystem,
+// which is how advisories refer to it
+func NormalizeName(ecosystem, name string) string {
+	switch ecosystem {
+	case EcosystemPyPI:
+		return pypiSeparators.ReplaceAllString(strings.ToLower(name), "-")
+	case EcosystemCargo:
+		return strings.ReplaceAll(name, "_", "-")
+	}
+	return name
+}
+
+// lineIndex finds the lines of declarations in a manifest. Lookups start
+// where the previous one matched, since manifests mostly list packages in
+// the order they are looked up, and wrap around once.
+type lineIndex struct {
+	content []byte
+	starts  []int
+	cursor  int
+}
+
+func newLineIndex(content []byte) *lineIndex {
+	starts := []int{0}
+	for i, c := range content {
+		if c == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return &lineIndex{content: content, starts: starts}
+}
+
+// find returns the 1-based line of the next occurrence of needle, or 0
+// when it does not occur
+func (l *lineIndex) find(needle string) int {
+	i := bytes.Index(l.content[l.cursor:], []byte(needle))
+	if i >= 0 {
+		i += l.cursor
+	} else if i = bytes.Index(l.content, []byte(needle)); i < 0 {
+		return 0
+	}
+	l.cursor = i
+	return sort.SearchInts(l.starts, i+1)
+}
@@ -0,0 +1,145 @@
+package deps
+
+import (
+	"sort"
+	"strings"
+)
+
+// goModule is a module path and version from a go.mod directive
+type goModule struct {
+	path, version string
+}
+
+// parseGoMod reads the require directives of a go.mod file, applying
+// replace directives. Modules replaced by a local directory are skipped.
+func parseGoMod(content []byte) ([]Package, error) {
+	var (
+		pkgs     []Package
+		replaces = make(map[goModule]goModule)
+		block    string
+	)
+	for i, raw := range strings.Split(string(content), "\n") {
+		line, comment, _ := strings.Cut(raw, "//")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		verb := block
+		switch {
+		case block != "" && fields[0] == ")":
+			block = ""
+			continue
+		case block == "" && len(fields) == 2 && fields[1] == "(":
+			block = fields[0]
+			continue
+		case block == "":
+			verb, fields = fields[0], fields[1:]
+		}
+
+		switch verb {
+		case "require":
+			if len(fields) < 2 {
+				continue
+			}
+			pkgs = append(pkgs, Package{
+				Ecosystem: EcosystemGo,
+				Name:      unquoteGo(fields[0]),
+				Version:   fields[1],
+				Line:      i + 1,
+				Direct:    strings.TrimSpace(comment) != "indirect",
+			})
+		case "replace":
+			old, repl, ok := parseGoReplace(fields)
+			if ok {
+				replaces[old] = repl
+			}
+		}
+	}
+
+	kept := pkgs[:0]
+	for _, p := range pkgs {
+		repl, ok := replaces[goModule{p.Name, p.Version}]
+		if !ok {
+			repl, ok = replaces[goModule{path: p.Name}]
+		}
+		if ok {
+			if repl.version == "" {
+				continue
+			}
+			p.Name, p.Version = repl.path, repl.version
+		}
+		kept = append(kept, p)
+	}
+	return kept, nil
+}
+
+// parseGoReplace parses the fields of "old [v] => new [v]"
+func parseGoReplace(fields []string) (goModule, goModule, bool) {
+	arrow := -1
+	for i, f := range fields {
+		if f == "=>" {
+			arrow = i
+		}
+	}
+	if arrow < 1 || arrow > 2 || arrow == len(fields)-1 {
+		return goModule{}, goModule{}, false
+	}
+
+	old := goModule{path: unquoteGo(fields[0])}
+	if arrow == 2 {
+		old.version = fields[1]
+	}
+	repl := goModule{path: unquoteGo(fields[arrow+1])}
+	if len(fields) > arrow+2 {
+		repl.version = fields[arrow+2]
+	}
+	return old, repl, true
+}
+
+func unquoteGo(s string) string {
+	return strings.Trim(s, "\"`")
+}
+
+// parseGoSum reads the modules listed in a go.sum file. Only the highest
+// version of each module is kept, since go.sum also records versions that
+// were considered during resolution but not selected.
+func parseGoSum(content []byte) ([]Package, error) {
+	latest := make(map[string]Package)
+	for i, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || strings.HasSuffix(fields[1], "/go.mod") {
+			continue
+		}
+		p := Package{Ecosystem: EcosystemGo, Name: fields[0], Version: fields[1], Line: i + 1}
+		if h, ok := parseGoHash(fields[2]); ok {
+			p.Hashes = []Hash{h}
+		}
+		if cur, ok := latest[p.Name]; !ok || CompareVersions(EcosystemGo, p.Version, cur.Version) > 0 {
+			latest[p.Name] = p
+		}
+	}
+
+	pkgs := make([]Package, 0, len(latest))
+	for _, p := range latest {
+		pkgs = append(pkgs, p)
+	}
+	sort.Slice(pkgs, func(a, b int) bool { return pkgs[a].Line < pkgs[b].Line })
+	return pkgs, nil
+}
+
+// goSumHashes returns the hash of each module's file tree in a go.sum,
+// keyed by package ID. The hashes of go.mod files alone are skipped.
+func goSumHashes(content []byte) map[string]Hash {
+	hashes := make(map[string]Hash)
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || strings.HasSuffix(fields[1], "/go.mod") {
+			continue
+		}
+		if h, ok := parseGoHash(fields[2]); ok {
+			hashes[fields[0]+"@"+fields[1]] = h
+		}
+	}
+	return hashes
+}
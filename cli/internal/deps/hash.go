@@ -0,0 +1,66 @@
+package deps
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// hashAlgorithms maps the algorithm prefixes used by lockfiles to their
+// names
+var hashAlgorithms = map[string]string{
+	"sha1":   HashSHA1,
+	"sha256": HashSHA256,
+	"sha384": HashSHA384,
+	"sha512": HashSHA512,
+}
+
+// parseIntegrity parses a Subresource Integrity value such as npm's
+// "sha512-<base64>", which may list several space-separated hashes
+func parseIntegrity(integrity string) []Hash {
+	var hashes []Hash
+	for _, part := range strings.Fields(integrity) {
+		alg, value, ok := strings.Cut(part, "-")
+		if !ok {
+			continue
+		}
+		if h, ok := base64Hash(hashAlgorithms[alg], value); ok {
+			hashes = append(hashes, h)
+		}
+	}
+	return hashes
+}
+
+// parseHexHash parses "sha256:<hex>" as written by pip and Poetry
+func parseHexHash(s string) (Hash, bool) {
+	alg, value, ok := strings.Cut(strings.TrimSpace(s), ":")
+	name := hashAlgorithms[strings.ToLower(alg)]
+	if !ok || name == "" {
+		return Hash{}, false
+	}
+	if _, err := hex.DecodeString(value); err != nil {
+		return Hash{}, false
+	}
+	return Hash{Algorithm: name, Value: strings.ToLower(value)}, true
+}
+
+// parseGoHash parses a go.sum "h1:<base64>" hash, the SHA-256 of the
+// module's file tree
+func parseGoHash(s string) (Hash, bool) {
+	value, ok := strings.CutPrefix(s, "h1:")
+	if !ok {
+		return Hash{}, false
+	}
+	return base64Hash(HashSHA256, value)
+}
+
+func base64Hash(algorithm, value string) (Hash, bool) {
+	if algorithm == "" {
+		return Hash{}, false
+	}
+	raw, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return Hash{}, false
+	}
+	return Hash{Algorithm: algorithm, Value: hex.EncodeToString(raw)}, true
+}
@@ -0,0 +1,105 @@
+package deps
+
+import (
+	"bytes"
+	"encoding/xml"
+	"regexp"
+	"strings"
+)
+
+type pomDependency struct {
+	GroupID    string `xml:"groupId"`
+	ArtifactID string `xml:"artifactId"`
+	Version    string `xml:"version"`
+	Scope      string `xml:"scope"`
+}
+
+type pomProperty struct {
+	XMLName xml.Name
+	Value   string `xml:",chardata"`
+}
+
+type pomProject struct {
+	GroupID string `xml:"groupId"`
+	Version string `xml:"version"`
+	Parent  struct {
+		GroupID string `xml:"groupId"`
+		Version string `xml:"version"`
+	} `xml:"parent"`
+	Properties struct {
+		Entries []pomProperty `xml:",any"`
+	} `xml:"properties"`
+	Dependencies         []pomDependency `xml:"dependencies>dependency"`
+	DependencyManagement struct {
+		Dependencies []pomDependency `xml:"dependencies>dependency"`
+	} `xml:"dependencyManagement"`
+}
+
+var pomReference = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// parsePom reads the dependencies of a Maven pom.xml, resolving versions
+// from properties and the dependencyManagement section of the same file.
+// Versions inherited from a parent POM or given as ranges are unknown, so
+// those dependencies are skipped.
+func parsePom(content []byte) ([]Package, error) {
+	var pom pomProject
+	if err := xml.NewDecoder(bytes.NewReader(content)).Decode(&pom); err != nil {
+		return nil, err
+	}
+
+	props := map[string]string{
+		"project.version":        pom.Version,
+		"project.groupId":        pom.GroupID,
+		"project.parent.version": pom.Parent.Version,
+		"project.parent.groupId": pom.Parent.GroupID,
+	}
+	if props["project.version"] == "" {
+		props["project.version"] = pom.Parent.Version
+	}
+	if props["project.groupId"] == "" {
+		props["project.groupId"] = pom.Parent.GroupID
+	}
+	for _, p := range pom.Properties.Entries {
+		props[p.XMLName.Local] = strings.TrimSpace(p.Value)
+	}
+	resolve := func(s string) string {
+		// Properties may refer to other properties
+		for i := 0; i < 5 && strings.Contains(s, "${"); i++ {
+			s = pomReference.ReplaceAllStringFunc(s, func(ref string) string {
+				if v, ok := props[ref[2:len(ref)-1]]; ok {
+					return v
+				}
+				return ref
+			})
+		}
+		return strings.TrimSpace(s)
+	}
+
+	managed := make(map[string]string)
+	for _, d := range pom.DependencyManagement.Dependencies {
+		managed[resolve(d.GroupID)+":"+resolve(d.ArtifactID)] = resolve(d.Version)
+	}
+
+	lines := newLineIndex(content)
+	var pkgs []Package
+	for _, d := range pom.Dependencies {
+		name := resolve(d.GroupID) + ":" + resolve(d.ArtifactID)
+		version := resolve(d.Version)
+		if version == "" {
+			version = managed[name]
+		}
+		if version == "" || strings.ContainsAny(version, "[]()$,") {
+			continue
+		}
+		scope := strings.TrimSpace(d.Scope)
+		pkgs = append(pkgs, Package{
+			Ecosystem: EcosystemMaven,
+			Name:      name,
+			Version:   version,
+			Line:      lines.find("<artifactId>" + strings.TrimSpace(d.ArtifactID) + "</artifactId>"),
+			Direct:    true,
+			Dev:       scope == "test",
+		})
+	}
+	return pkgs, nil
+}
@@ -0,0 +1,247 @@
+package deps
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// exactVersion matches a package.json dependency pinned to one version
+var exactVersion = regexp.MustCompile(`^=?v?(\d+\.\d+\.\d+(?:[-+][0-9A-Za-z.+-]*)?)$`)
+
+type packageJSON struct {
+	Dependencies         map[string]string `json:"dependencies"`
+	DevDependencies      map[string]string `json:"devDependencies"`
+	OptionalDependencies map[string]string `json:"optionalDependencies"`
+}
+
+// parsePackageJSON reads the dependencies of a package.json that are pinned
+// to an exact version. Ranges need package-lock.json to resolve.
+func parsePackageJSON(content []byte) ([]Package, error) {
+	var manifest packageJSON
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, err
+	}
+
+	lines := newLineIndex(content)
+	var pkgs []Package
+	add := func(deps map[string]string, dev bool) {
+		for _, name := range sortedKeys(deps) {
+			m := exactVersion.FindStringSubmatch(strings.TrimSpace(deps[name]))
+			if m == nil {
+				continue
+			}
+			pkgs = append(pkgs, Package{
+				Ecosystem: EcosystemNPM,
+				Name:      name,
+				Version:   m[1],
+				Line:      lines.find(`"` + name + `"`),
+				Direct:    true,
+				Dev:       dev,
+			})
+		}
+	}
+	add(manifest.Dependencies, false)
+	add(manifest.OptionalDependencies, false)
+	add(manifest.DevDependencies, true)
+	return pkgs, nil
+}
+
+type packageLock struct {
+	LockfileVersion int                       `json:"lockfileVersion"`
+	Packages        map[string]lockPackage    `json:"packages"`
+	Dependencies    map[string]lockDependency `json:"dependencies"`
+}
+
+// lockPackage is an entry of the "packages" map of lockfile version 2 and 3
+type lockPackage struct {
+	Name                 string            `json:"name"`
+	Version              string            `json:"version"`
+	Dev                  bool              `json:"dev"`
+	Link                 bool              `json:"link"`
+	Integrity            string            `json:"integrity"`
+	License              lockLicense       `json:"license"`
+	Dependencies         map[string]string `json:"dependencies"`
+	DevDependencies      map[string]string `json:"devDependencies"`
+	OptionalDependencies map[string]string `json:"optionalDependencies"`
+}
+
+// lockLicense is the license of a locked package, which npm copies from the
+// package's package.json: usually an expression, but old packages give an
+// object with a type
+type lockLicense string
+
+func (l *lockLicense) UnmarshalJSON(data []byte) error {
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		*l = lockLicense(s)
+		return nil
+	}
+	var obj struct {
+		Type string `json:"type"`
+	}
+	if json.Unmarshal(data, &obj) == nil {
+		*l = lockLicense(obj.Type)
+	}
+	// Anything else is not worth failing the whole lockfile over
+	return nil
+}
+
+// lockDependency is an entry of the nested "dependencies" map of lockfile
+// version 1
+type lockDependency struct {
+	Version      string                    `json:"version"`
+	Dev          bool                      `json:"dev"`
+	Integrity    string                    `json:"integrity"`
+	Requires     map[string]string         `json:"requires"`
+	Dependencies map[string]lockDependency `json:"dependencies"`
+}
+
+// parsePackageLock reads every installed package of a package-lock.json
+func parsePackageLock(content []byte) ([]Package, error) {
+	var lock packageLock
+	if err := json.Unmarshal(content, &lock); err != nil {
+		return nil, err
+	}
+	if len(lock.Packages) > 0 {
+		return parseLockPackages(content, lock.Packages), nil
+	}
+
+	// Version 1 hoists dependencies to the top level without saying which
+	// of them the project declares, so top-level packages no other package
+	// requires are taken as the direct dependencies
+	lines := newLineIndex(content)
+	var pkgs []Package
+	// scopes holds the dependency maps enclosing the current one, innermost
+	// last, which is where Node looks up required packages
+	var walk func(deps map[string]lockDependency, scopes []map[string]lockDependency)
+	walk = func(deps map[string]lockDependency, scopes []map[string]lockDependency) {
+		scopes = append(scopes, deps)
+		for _, name := range sortedKeys(deps) {
+			dep := deps[name]
+			// Dependencies installed from git or a tarball have no version
+			if exactVersion.MatchString(dep.Version) {
+				p := Package{
+					Ecosystem: EcosystemNPM,
+					Name:      name,
+					Version:   dep.Version,
+					Line:      lines.find(`"` + name + `": {`),
+					Direct:    len(scopes) == 1,
+					Dev:       dep.Dev,
+					Hashes:    parseIntegrity(dep.Integrity),
+				}
+				inner := append(scopes, dep.Dependencies)
+				for _, req := range sortedKeys(dep.Requires) {
+					for i := len(inner) - 1; i >= 0; i-- {
+						if r, ok := inner[i][req]; ok {
+							p.Dependencies = append(p.Dependencies, req+"@"+r.Version)
+							break
+						}
+					}
+				}
+				pkgs = append(pkgs, p)
+			}
+			walk(dep.Dependencies, scopes)
+		}
+	}
+	walk(lock.Dependencies, nil)
+
+	required := make(map[string]bool)
+	for _, p := range pkgs {
+		for _, id := range p.Dependencies {
+			required[id] = true
+		}
+	}
+	for i := range pkgs {
+		pkgs[i].Direct = pkgs[i].Direct && !required[pkgs[i].ID()]
+	}
+	return pkgs, nil
+}
+
+// parseLockPackages reads the "packages" map, keyed by install path such as
+// node_modules/a/node_modules/b
+func parseLockPackages(content []byte, packages map[string]lockPackage) []Package {
+	root := packages[""]
+	direct := make(map[string]bool)
+	for _, deps := range []map[string]string{root.Dependencies, root.DevDependencies, root.OptionalDependencies} {
+		for name := range deps {
+			direct[name] = true
+		}
+	}
+
+	// Top-level installs come first so a package installed at several depths
+	// is reported where the project declares it
+	paths := sortedKeys(packages)
+	sort.SliceStable(paths, func(a, b int) bool {
+		return strings.Count(paths[a], "node_modules/") < strings.Count(paths[b], "node_modules/")
+	})
+
+	lines := newLineIndex(content)
+	var pkgs []Package
+	for _, path := range paths {
+		p := packages[path]
+		i := strings.LastIndex(path, "node_modules/")
+		if i < 0 || p.Link || p.Version == "" {
+			continue
+		}
+		name := path[i+len("node_modules/"):]
+		if p.Name != "" {
+			name = p.Name
+		}
+		pkg := Package{
+			Ecosystem: EcosystemNPM,
+			Name:      name,
+			Version:   p.Version,
+			Line:      lines.find(`"` + path + `"`),
+			Direct:    direct[name] && i == 0,
+			Dev:       p.Dev,
+			Hashes:    parseIntegrity(p.Integrity),
+			License:   string(p.License),
+		}
+		for _, deps := range []map[string]string{p.Dependencies, p.OptionalDependencies} {
+			for _, dep := range sortedKeys(deps) {
+				if resolved, ok := resolveNodeModule(packages, path, dep); ok {
+					pkg.Dependencies = append(pkg.Dependencies, dep+"@"+resolved.Version)
+				}
+			}
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	return pkgs
+}
+
+// resolveNodeModule finds the package that the package installed at from
+// gets when it requires name, searching node_modules directories from
+// from up to the project root as Node does
+func resolveNodeModule(packages map[string]lockPackage, from, name string) (lockPackage, bool) {
+	dir := from
+	for {
+		candidate := "node_modules/" + name
+		if dir != "" {
+			candidate = dir + "/" + candidate
+		}
+		if p, ok := packages[candidate]; ok && p.Version != "" {
+			return p, true
+		}
+		if dir == "" {
+			return lockPackage{}, false
+		}
+		// Leave the node_modules directory dir is installed in
+		i := strings.LastIndex(dir, "node_modules/")
+		if i < 0 {
+			dir = ""
+		} else {
+			dir = strings.TrimSuffix(dir[:i], "/")
+		}
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,41 @@
+package deps
+
+import (
+	"net/url"
+	"strings"
+)
+
+// purlTypes maps ecosystems to package URL types
+var purlTypes = map[string]string{
+	EcosystemGo:    "golang",
+	EcosystemNPM:   "npm",
+	EcosystemPyPI:  "pypi",
+	EcosystemCargo: "cargo",
+	EcosystemMaven: "maven",
+}
+
+// PURL returns the package URL of p as defined by
+// https://github.com/package-url/purl-spec, such as
+// pkg:npm/%40babel/core@7.22.0
+func (p Package) PURL() string {
+	name := p.Name
+	switch p.Ecosystem {
+	case EcosystemPyPI:
+		name = NormalizeName(EcosystemPyPI, name)
+	case EcosystemMaven:
+		// group:artifact becomes the namespace and name
+		name = strings.Replace(name, ":", "/", 1)
+	}
+
+	segments := strings.Split(name, "/")
+	for i, s := range segments {
+		segments[i] = purlEscape(s)
+	}
+	return "pkg:" + purlTypes[p.Ecosystem] + "/" + strings.Join(segments, "/") + "@" + purlEscape(p.Version)
+}
+
+// purlEscape percent-encodes a purl segment, including the "@" of npm
+// scopes
+func purlEscape(s string) string {
+	return strings.ReplaceAll(url.PathEscape(s), "@", "%40")
+}
@@ -0,0 +1,121 @@
+package deps
+
+import (
+	"regexp"
+	"strings"
+)
+
+// pinnedRequirement matches "name[extras] == version" in requirements.txt,
+// ignoring environment markers after a semicolon
+var pinnedRequirement = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._-]*)\s*(?:\[[^\]]*\])?\s*===?\s*([A-Za-z0-9.!+_-]+)\s*(?:;.*)?$`)
+
+// requirementOption matches per-requirement options such as --hash=...
+var requirementOption = regexp.MustCompile(`\s--?[a-z-]+(?:[= ]\S+)?`)
+
+// requirementHash matches the --hash options of pip's hash-checking mode
+var requirementHash = regexp.MustCompile(`--hash[= ](\S+)`)
+
+// parseRequirements reads the requirements of a requirements.txt that are
+// pinned with == or ===. Options, includes and URLs are skipped.
+func parseRequirements(content []byte) ([]Package, error) {
+	var pkgs []Package
+	lines := strings.Split(string(content), "\n")
+	for i := 0; i < len(lines); i++ {
+		start := i
+		line := strings.TrimRight(lines[i], "\r")
+		for strings.HasSuffix(line, `\`) && i+1 < len(lines) {
+			i++
+			line = strings.TrimSuffix(line, `\`) + " " + strings.TrimRight(lines[i], "\r")
+		}
+
+		if j := strings.Index(line, " #"); j >= 0 {
+			line = line[:j]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "-") {
+			continue
+		}
+		var hashes []Hash
+		for _, m := range requirementHash.FindAllStringSubmatch(line, -1) {
+			if h, ok := parseHexHash(m[1]); ok {
+				hashes = append(hashes, h)
+			}
+		}
+		line = strings.TrimSpace(requirementOption.ReplaceAllString(" "+line, ""))
+
+		m := pinnedRequirement.FindStringSubmatch(line)
+		if m == nil || strings.Contains(m[2], "*") {
+			continue
+		}
+		pkgs = append(pkgs, Package{
+			Ecosystem: EcosystemPyPI,
+			Name:      m[1],
+			Version:   m[2],
+			Line:      start + 1,
+			Direct:    true,
+			Hashes:    hashes,
+		})
+	}
+	return pkgs, nil
+}
+
+// parsePoetryLock reads the packages of a poetry.lock. The lockfile does
+// not say which packages the project declares, so packages no other locked
+// package depends on are taken as the direct dependencies.
+func parsePoetryLock(content []byte) ([]Package, error) {
+	tables := parseTOMLTables(content, "package")
+	required := make(map[string]bool)
+	versions := make(map[string]string)
+	for _, t := range tables {
+		for _, name := range t.subKeys["dependencies"] {
+			required[NormalizeName(EcosystemPyPI, name)] = true
+		}
+		versions[NormalizeName(EcosystemPyPI, t.values["name"])] = t.values["version"]
+	}
+
+	var pkgs []Package
+	for _, t := range tables {
+		name, version := t.values["name"], t.values["version"]
+		if name == "" || version == "" {
+			continue
+		}
+		p := Package{
+			Ecosystem: EcosystemPyPI,
+			Name:      name,
+			Version:   version,
+			Line:      t.line,
+			Direct:    !required[NormalizeName(EcosystemPyPI, name)],
+			Dev:       poetryDev(t),
+		}
+		// Each distribution file of the release has its own hash
+		for _, s := range t.arrays["files"] {
+			if h, ok := parseHexHash(s); ok {
+				p.Hashes = append(p.Hashes, h)
+			}
+		}
+		// Optional dependencies of extras may not be locked
+		for _, dep := range t.subKeys["dependencies"] {
+			if v := versions[NormalizeName(EcosystemPyPI, dep)]; v != "" {
+				p.Dependencies = append(p.Dependencies, dep+"@"+v)
+			}
+		}
+		pkgs = append(pkgs, p)
+	}
+	return pkgs, nil
+}
+
+// poetryDev reports whether a poetry.lock package is only needed for
+// development, from the category of older lockfiles or the groups of newer
+// ones
+func poetryDev(t *tomlTable) bool {
+	if category := t.values["category"]; category != "" {
+		return category == "dev"
+	}
+	groups := t.arrays["groups"]
+	for _, g := range groups {
+		if g == "main" {
+			return false
+		}
+	}
+	return len(groups) > 0
+}
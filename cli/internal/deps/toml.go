@@ -0,0 +1,140 @@
+package deps
+
+import (
+	"strings"
+)
+
+// tomlTable is one [[name]] entry of a lockfile. Only the subset of TOML
+// that Cargo and Poetry write is understood: string values, arrays of
+// strings and inline tables, and sub-tables such as [package.dependencies].
+type tomlTable struct {
+	line   int
+	values map[string]string
+	// arrays holds every string in an array value, including the strings
+	// of inline tables inside it
+	arrays map[string][]string
+	// subKeys holds the keys of sub-tables, keyed by sub-table name
+	subKeys map[string][]string
+}
+
+// parseTOMLTables returns the entries of the [[name]] array of tables
+func parseTOMLTables(content []byte, name string) []*tomlTable {
+	var (
+		tables  []*tomlTable
+		current *tomlTable
+		sub     string
+	)
+	lines := strings.Split(string(content), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(stripTOMLComment(lines[i]))
+		switch {
+		case line == "":
+			continue
+		case line == "[["+name+"]]":
+			current = &tomlTable{line: i + 1, values: make(map[string]string), arrays: make(map[string][]string), subKeys: make(map[string][]string)}
+			tables = append(tables, current)
+			sub = ""
+			continue
+		case strings.HasPrefix(line, "["):
+			header := strings.Trim(line, "[]")
+			if current != nil && strings.HasPrefix(header, name+".") {
+				sub = strings.TrimPrefix(header, name+".")
+			} else {
+				current = nil
+			}
+			continue
+		case current == nil:
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.Trim(strings.TrimSpace(key), `"'`)
+		value = strings.TrimSpace(value)
+
+		// Arrays may span lines until the brackets balance
+		for strings.HasPrefix(value, "[") && bracketDepth(value) > 0 && i+1 < len(lines) {
+			i++
+			value += " " + strings.TrimSpace(stripTOMLComment(lines[i]))
+		}
+
+		if sub != "" {
+			current.subKeys[sub] = append(current.subKeys[sub], key)
+			continue
+		}
+		if strings.HasPrefix(value, "[") {
+			current.arrays[key] = tomlStrings(value)
+		} else if strs := tomlStrings(value); len(strs) > 0 {
+			current.values[key] = strs[0]
+		}
+	}
+	return tables
+}
+
+// tomlStrings returns the basic and literal strings in value in order
+func tomlStrings(value string) []string {
+	var strs []string
+	for i := 0; i < len(value); i++ {
+		quote := value[i]
+		if quote != '"' && quote != '\'' {
+			continue
+		}
+		var b strings.Builder
+		for i++; i < len(value) && value[i] != quote; i++ {
+			if quote == '"' && value[i] == '\\' && i+1 < len(value) {
+				i++
+			}
+			b.WriteByte(value[i])
+		}
+		strs = append(strs, b.String())
+	}
+	return strs
+}
+
+// bracketDepth returns how many brackets in value are still open, ignoring
+// brackets inside strings
+func bracketDepth(value string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		}
+	}
+	return depth
+}
+
+// stripTOMLComment removes a # comment that is not inside a string
+func stripTOMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
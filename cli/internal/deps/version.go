@@ -0,0 +1,305 @@
+package deps
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// CompareVersions compares two versions by the rules of ecosystem and
+// returns -1, 0 or 1. Versions that do not follow the rules are compared
+// segment by segment, which orders the common cases correctly.
+func CompareVersions(ecosystem, a, b string) int {
+	switch ecosystem {
+	case EcosystemPyPI:
+		return comparePEP440(a, b)
+	case EcosystemMaven:
+		return compareMaven(a, b)
+	}
+	return compareSemver(a, b)
+}
+
+// compareSemver follows semantic versioning: numeric core versions, then a
+// release ranks above its pre-releases, whose dot-separated identifiers
+// compare numerically or lexically. Build metadata is ignored.
+func compareSemver(a, b string) int {
+	coreA, preA := splitSemver(a)
+	coreB, preB := splitSemver(b)
+	if c := compareSegments(coreA, coreB); c != 0 {
+		return c
+	}
+
+	switch {
+	case preA == "" && preB == "":
+		return 0
+	case preA == "":
+		return 1
+	case preB == "":
+		return -1
+	}
+
+	idsA, idsB := strings.Split(preA, "."), strings.Split(preB, ".")
+	for i := 0; i < len(idsA) && i < len(idsB); i++ {
+		na, errA := strconv.ParseUint(idsA[i], 10, 64)
+		nb, errB := strconv.ParseUint(idsB[i], 10, 64)
+		var c int
+		switch {
+		case errA == nil && errB == nil:
+			c = compareInts(na, nb)
+		case errA == nil:
+			c = -1
+		case errB == nil:
+			c = 1
+		default:
+			c = strings.Compare(idsA[i], idsB[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return compareInts(uint64(len(idsA)), uint64(len(idsB)))
+}
+
+// splitSemver returns the numeric core and pre-release of a version such
+// as v1.2.3-rc.1+build
+func splitSemver(v string) ([]string, string) {
+	v = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(v), "="), "v")
+	v, _, _ = strings.Cut(v, "+")
+	core, pre, _ := strings.Cut(v, "-")
+	return strings.Split(core, "."), pre
+}
+
+// compareSegments compares dot-separated numeric segments, treating
+// missing segments as zero
+func compareSegments(a, b []string) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var sa, sb string
+		if i < len(a) {
+			sa = a[i]
+		}
+		if i < len(b) {
+			sb = b[i]
+		}
+		na, errA := strconv.ParseUint(orZero(sa), 10, 64)
+		nb, errB := strconv.ParseUint(orZero(sb), 10, 64)
+		if errA != nil || errB != nil {
+			if c := strings.Compare(sa, sb); c != 0 {
+				return c
+			}
+			continue
+		}
+		if c := compareInts(na, nb); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func orZero(s string) string {
+	if s == "" {
+		return "0"
+	}
+	return s
+}
+
+func compareInts(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// pep440 matches a PEP 440 version, capturing the epoch, release,
+// pre-release phase and number, post-release marker and number, and dev
+// marker and number
+var pep440 = regexp.MustCompile(`^v?(?:(\d+)!)?(\d+(?:\.\d+)*)(?:[-_.]?(a|b|c|rc|alpha|beta|pre|preview)[-_.]?(\d*))?(?:(-)(\d+)|[-_.]?(post|rev|r)[-_.]?(\d*))?(?:[-_.]?(dev)[-_.]?(\d*))?(?:\+.*)?$`)
+
+// pep440Version holds the parts of a PEP 440 version that affect ordering.
+// Absent post and dev releases are -1.
+type pep440Version struct {
+	epoch   uint64
+	release []string
+	// phase ranks a dev release of the final version (-1), alpha (0),
+	// beta (1), release candidate (2) and the final release (3)
+	phase    int
+	phaseNum uint64
+	post     int64
+	dev      int64
+}
+
+func parsePEP440(v string) (pep440Version, bool) {
+	m := pep440.FindStringSubmatch(strings.ToLower(strings.TrimSpace(v)))
+	if m == nil {
+		return pep440Version{}, false
+	}
+	p := pep440Version{release: strings.Split(m[2], "."), phase: 3, post: -1, dev: -1}
+	p.epoch, _ = strconv.ParseUint(m[1], 10, 64)
+	switch m[3] {
+	case "a", "alpha":
+		p.phase = 0
+	case "b", "beta":
+		p.phase = 1
+	case "c", "rc", "pre", "preview":
+		p.phase = 2
+	}
+	p.phaseNum, _ = strconv.ParseUint(m[4], 10, 64)
+	if m[5] != "" || m[7] != "" {
+		p.post, _ = strconv.ParseInt(m[6]+m[8], 10, 64)
+	}
+	if m[9] != "" {
+		p.dev, _ = strconv.ParseInt(m[10], 10, 64)
+		// A dev release of the final version sorts before its pre-releases
+		if p.phase == 3 && p.post < 0 {
+			p.phase = -1
+		}
+	}
+	return p, true
+}
+
+// comparePEP440 orders Python versions as described in PEP 440
+func comparePEP440(a, b string) int {
+	pa, okA := parsePEP440(a)
+	pb, okB := parsePEP440(b)
+	if !okA || !okB {
+		return compareSemver(a, b)
+	}
+	if c := compareInts(pa.epoch, pb.epoch); c != 0 {
+		return c
+	}
+	if c := compareSegments(pa.release, pb.release); c != 0 {
+		return c
+	}
+	if c := compareInts(uint64(pa.phase+1), uint64(pb.phase+1)); c != 0 {
+		return c
+	}
+	if c := compareInts(pa.phaseNum, pb.phaseNum); c != 0 {
+		return c
+	}
+	if c := compareInts(uint64(pa.post+1), uint64(pb.post+1)); c != 0 {
+		return c
+	}
+	// Without a dev segment a version ranks above its dev releases
+	switch {
+	case pa.dev == pb.dev:
+		return 0
+	case pa.dev < 0:
+		return 1
+	case pb.dev < 0:
+		return -1
+	}
+	return compareInts(uint64(pa.dev), uint64(pb.dev))
+}
+
+// mavenQualifiers ranks the well-known Maven qualifiers; the empty
+// qualifier is the release itself
+var mavenQualifiers = map[string]int{
+	"alpha": 0, "a": 0,
+	"beta": 1, "b": 1,
+	"milestone": 2, "m": 2,
+	"rc": 3, "cr": 3,
+	"snapshot": 4,
+	"":         5, "ga": 5, "final": 5, "release": 5,
+	"sp": 6,
+}
+
+// mavenTokens splits a Maven version into numbers and qualifiers at dots,
+// dashes and transitions between digits and letters
+func mavenTokens(v string) []string {
+	var tokens []string
+	var cur strings.Builder
+	flush := func() {
+		tokens = append(tokens, cur.String())
+		cur.Reset()
+	}
+	v = strings.ToLower(strings.TrimSpace(v))
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		if c == '.' || c == '-' || c == '_' {
+			flush()
+			continue
+		}
+		if cur.Len() > 0 {
+			prev := v[i-1]
+			if isDigit(prev) != isDigit(c) {
+				flush()
+			}
+		}
+		cur.WriteByte(c)
+	}
+	flush()
+
+	// Trailing zeros and release qualifiers do not change the version
+	for len(tokens) > 1 && isNumberOrRelease(tokens[len(tokens)-1]) {
+		tokens = tokens[:len(tokens)-1]
+	}
+	return tokens
+}
+
+// isNumberOrRelease reports whether a token is zero or names the release
+func isNumberOrRelease(s string) bool {
+	if rank, ok := mavenQualifiers[s]; ok && rank == 5 {
+		return true
+	}
+	_, err := strconv.ParseUint(s, 10, 64)
+	return err == nil && strings.Trim(s, "0") == ""
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// compareMaven approximates Maven's ComparableVersion ordering: numbers
+// compare numerically, known qualifiers by rank with unknown ones after
+// them, and a number ranks above any qualifier.
+func compareMaven(a, b string) int {
+	ta, tb := mavenTokens(a), mavenTokens(b)
+	for i := 0; i < len(ta) || i < len(tb); i++ {
+		var sa, sb string
+		if i < len(ta) {
+			sa = ta[i]
+		}
+		if i < len(tb) {
+			sb = tb[i]
+		}
+		if c := compareMavenToken(sa, sb); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func compareMavenToken(a, b string) int {
+	na, errA := strconv.ParseUint(a, 10, 64)
+	nb, errB := strconv.ParseUint(b, 10, 64)
+	switch {
+	case errA == nil && errB == nil:
+		return compareInts(na, nb)
+	case errA == nil:
+		// A missing token is the release qualifier, below any number but
+		// "0" equals nothing
+		if b == "" && na == 0 {
+			return 0
+		}
+		return 1
+	case errB == nil:
+		if a == "" && nb == 0 {
+			return 0
+		}
+		return -1
+	}
+
+	ra, knownA := mavenQualifiers[a]
+	rb, knownB := mavenQualifiers[b]
+	switch {
+	case knownA && knownB:
+		return compareInts(uint64(ra), uint64(rb))
+	case knownA:
+		return -1
+	case knownB:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
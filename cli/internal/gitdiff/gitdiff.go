@@ -0,0 +1,266 @@
+package gitdiff
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Options selects which changes to diff. At most one of Staged, Base and
+// Range should be set; with none set the working tree is compared to HEAD.
+type Options struct {
+	Staged bool   // compare the index to HEAD
+	Base   string // compare the working tree to the merge base with this ref
+	Range  string // compare two commits, e.g. "main..feature"
+}
+
+// validate rejects refs that git would read as options
+func (o Options) validate() error {
+	for _, ref := range []string{o.Base, o.Range} {
+		if strings.HasPrefix(ref, "-") {
+			return fmt.Errorf("invalid revision %q", ref)
+		}
+	}
+	return nil
+}
+
+// revision returns the git revision holding the new side of the diff, ""
+// for the index, and false when the new side is the working tree
+func (o Options) revision() (string, bool) {
+	switch {
+	case o.Staged:
+		return "", true
+	case o.Range != "":
+		rev := o.Range
+		if i := strings.Index(rev, "..."); i >= 0 {
+			rev = rev[i+3:]
+		} else if i := strings.Index(rev, ".."); i >= 0 {
+			rev = rev[i+2:]
+		} else if strings.HasSuffix(rev, "^!") {
+			rev = strings.TrimSuffix(rev, "^!")
+		} else {
+			// A single commit is compared to the working tree
+			return "", false
+		}
+		if rev == "" {
+			rev = "HEAD"
+		}
+		return rev, true
+	}
+	return "", false
+}
+
+// FromGit reports whether the new side of the diff is read from the index
+// or a commit rather than the working tree
+func (o Options) FromGit() bool {
+	_, ok := o.revision()
+	return ok
+}
+
+// Content returns the new version of a changed file, from the index or the
+// commit the diff selects, or from the working tree
+func Content(ctx context.Context, root string, opts Options, path string) ([]byte, error) {
+	rev, ok := opts.revision()
+	if !ok {
+		return os.ReadFile(filepath.Join(root, filepath.FromSlash(path)))
+	}
+	return git(ctx, root, "show", "--no-textconv", rev+":"+path)
+}
+
+// LineRange is an inclusive range of line numbers in the new file
+type LineRange struct {
+	Start int
+	End   int
+}
+
+// FileChange describes the added or modified lines of a single file
+type FileChange struct {
+	Path   string // path relative to the repository root
+	Ranges []LineRange
+}
+
+// Contains reports whether line falls inside one of the changed ranges
+func (c FileChange) Contains(line int) bool {
+	for _, r := range c.Ranges {
+		if line >= r.Start && line <= r.End {
+			return true
+		}
+	}
+	return false
+}
+
+// RepoRoot returns the top-level directory of the repository containing dir
+func RepoRoot(ctx context.Context, dir string) (string, error) {
+	out, err := git(ctx, dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Changes returns the files added, copied, modified or renamed in the diff
+// selected by opts, along with the line ranges that changed in each
+func Changes(ctx context.Context, root string, opts Options) ([]FileChange, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	args := []string{"diff", "--unified=0", "--no-color", "--no-ext-diff", "--src-prefix=a/", "--dst-prefix=b/", "--diff-filter=ACMR"}
+
+	switch {
+	case opts.Staged:
+		args = append(args, "--cached")
+	case opts.Range != "":
+		args = append(args, "--end-of-options", opts.Range)
+	case opts.Base != "":
+		mergeBase, err := git(ctx, root, "merge-base", "--end-of-options", opts.Base, "HEAD")
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, strings.TrimSpace(string(mergeBase)))
+	default:
+		args = append(args, "HEAD")
+	}
+	args = append(args, "--")
+
+	out, err := git(ctx, root, args...)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := Parse(out)
+	if err != nil {
+		return nil, err
+	}
+
+	// git diff leaves out new files that were never added
+	if !opts.FromGit() {
+		untracked, err := untrackedChanges(ctx, root)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, untracked...)
+	}
+	return changes, nil
+}
+
+// untrackedChanges returns the files git does not track and does not
+// ignore, with a range covering every line
+func untrackedChanges(ctx context.Context, root string) ([]FileChange, error) {
+	out, err := git(ctx, root, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []FileChange
+	for _, path := range strings.Split(string(out), "\x00") {
+		if path == "" {
+			continue
+		}
+		changes = append(changes, FileChange{Path: path, Ranges: []LineRange{{Start: 1, End: math.MaxInt}}})
+	}
+	return changes, nil
+}
+
+// Parse extracts file changes from unified diff output
+func Parse(diff []byte) ([]FileChange, error) {
+	var (
+		changes []FileChange
+		current *FileChange
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(diff))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			current = nil
+
+		case strings.HasPrefix(line, "+++ "):
+			// git terminates names containing spaces with a tab
+			path := strings.TrimSuffix(strings.TrimPrefix(line, "+++ "), "\t")
+			if path == "/dev/null" {
+				current = nil
+				continue
+			}
+			path = unquote(path)
+			path = strings.TrimPrefix(path, "b/")
+			changes = append(changes, FileChange{Path: path})
+			current = &changes[len(changes)-1]
+
+		case strings.HasPrefix(line, "@@ ") && current != nil:
+			r, err := parseHunkHeader(line)
+			if err != nil {
+				return nil, err
+			}
+			if r.End >= r.Start {
+				current.Ranges = append(current.Ranges, r)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read diff: %w", err)
+	}
+
+	return changes, nil
+}
+
+// parseHunkHeader returns the new-file line range of a "@@ -a,b +c,d @@" header
+func parseHunkHeader(line string) (LineRange, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || !strings.HasPrefix(fields[2], "+") {
+		return LineRange{}, fmt.Errorf("malformed hunk header %q", line)
+	}
+
+	spec := strings.TrimPrefix(fields[2], "+")
+	start, count := spec, "1"
+	if i := strings.IndexByte(spec, ','); i >= 0 {
+		start, count = spec[:i], spec[i+1:]
+	}
+
+	s, err := strconv.Atoi(start)
+	if err != nil {
+		return LineRange{}, fmt.Errorf("malformed hunk header %q", line)
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil {
+		return LineRange{}, fmt.Errorf("malformed hunk header %q", line)
+	}
+
+	return LineRange{Start: s, End: s + n - 1}, nil
+}
+
+// unquote decodes a path that git quoted because of special characters
+func unquote(path string) string {
+	if len(path) >= 2 && path[0] == '"' && path[len(path)-1] == '"' {
+		if p, err := strconv.Unquote(path); err == nil {
+			return p
+		}
+	}
+	return path
+}
+
+func git(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return nil, fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	return out, nil
+}
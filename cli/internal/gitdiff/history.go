@@ -0,0 +1,91 @@
+package gitdiff
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// AddedLine is a line added to a file by a commit
+type AddedLine struct {
+	Commit string // full commit hash
+	Path   string // path relative to the repository root
+	Line   int    // line number in the file as of the commit
+	Text   string
+}
+
+// History calls fn for every line added by the commits reachable from any
+// ref, newest commit first. Binary files and merge commits are skipped.
+func History(ctx context.Context, root string, fn func(AddedLine)) error {
+	cmd := exec.CommandContext(ctx, "git", "log", "--all", "-p", "--unified=0", "--no-color", "--no-ext-diff",
+		"--src-prefix=a/", "--dst-prefix=b/", "--diff-filter=ACMR", "--format=%x00%H")
+	cmd.Dir = root
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to run git log: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to run git log: %w", err)
+	}
+
+	var (
+		commit, path string
+		line         int
+		header       bool // between "diff --git" and the first hunk
+	)
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		text := scanner.Text()
+
+		switch {
+		case strings.HasPrefix(text, "\x00"):
+			commit, path, header = text[1:], "", false
+
+		case strings.HasPrefix(text, "diff --git "):
+			path, header = "", true
+
+		case header && strings.HasPrefix(text, "+++ "):
+			path = strings.TrimSuffix(strings.TrimPrefix(text, "+++ "), "\t")
+			if path == "/dev/null" {
+				path = ""
+				continue
+			}
+			path = strings.TrimPrefix(unquote(path), "b/")
+
+		case strings.HasPrefix(text, "@@ ") && path != "":
+			header = false
+			r, err := parseHunkHeader(text)
+			if err != nil {
+				cmd.Process.Kill()
+				cmd.Wait()
+				return err
+			}
+			line = r.Start
+
+		case !header && strings.HasPrefix(text, "+") && path != "":
+			fn(AddedLine{Commit: commit, Path: path, Line: line, Text: text[1:]})
+			line++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return fmt.Errorf("failed to read git log: %w", err)
+	}
+
+	if err := cmd.Wait(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return fmt.Errorf("git log: %s", msg)
+	}
+	return nil
+}
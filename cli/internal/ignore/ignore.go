@@ -0,0 +1,228 @@
+package ignore
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Names of the ignore files read in every directory, in order of precedence
+const (
+	GitIgnore       = ".gitignore"
+	RaincheckIgnore = ".raincheckignore"
+)
+
+// rule is a single pattern from an ignore file
+type rule struct {
+	base     string   // slash-separated directory of the ignore file, "" for the root
+	segments []string // pattern split on "/"
+	negate   bool
+	dirOnly  bool
+	anchored bool // the pattern is matched against the full path below base
+}
+
+// Matcher decides whether paths below a root directory are ignored by the
+// .gitignore and .raincheckignore files found along the way. Rules follow
+// gitignore semantics: later and deeper rules override earlier ones, "!"
+// re-includes a path, a trailing "/" only matches directories, and nothing
+// below an ignored directory can be re-included.
+type Matcher struct {
+	root   string
+	names  []string
+	rules  []rule
+	loaded map[string]bool
+}
+
+// New creates a matcher for the tree at root reading the given ignore file
+// names in each directory. When root is a git work tree, .git/info/exclude is
+// read as well.
+func New(root string, names ...string) *Matcher {
+	m := &Matcher{root: root, names: names, loaded: make(map[string]bool)}
+	if f, err := os.Open(filepath.Join(root, ".git", "info", "exclude")); err == nil {
+		m.rules = append(m.rules, parse(f, "")...)
+		f.Close()
+	}
+	return m
+}
+
+// Root returns the directory paths are matched relative to
+func (m *Matcher) Root() string {
+	return m.root
+}
+
+// Ignored reports whether path, absolute or relative to the root, is
+// ignored. Ignore files in the directories leading to path are loaded on
+// first use.
+func (m *Matcher) Ignored(p string, isDir bool) bool {
+	rel, ok := m.rel(p)
+	if !ok || rel == "" {
+		return false
+	}
+
+	parts := strings.Split(rel, "/")
+	m.load("")
+	for i := 1; i < len(parts); i++ {
+		dir := strings.Join(parts[:i], "/")
+		if m.match(dir, true) {
+			return true
+		}
+		m.load(dir)
+	}
+	return m.match(rel, isDir)
+}
+
+// rel converts p to a slash-separated path relative to the root
+func (m *Matcher) rel(p string) (string, bool) {
+	if filepath.IsAbs(p) {
+		r, err := filepath.Rel(m.root, p)
+		if err != nil || r == ".." || strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+			return "", false
+		}
+		p = r
+	}
+	p = filepath.ToSlash(p)
+	if p == "." {
+		return "", true
+	}
+	return strings.TrimPrefix(p, "./"), true
+}
+
+// load reads the ignore files of the slash-separated directory dir once.
+// Unreadable ignore files are skipped like missing ones.
+func (m *Matcher) load(dir string) {
+	if m.loaded[dir] {
+		return
+	}
+	m.loaded[dir] = true
+
+	for _, name := range m.names {
+		f, err := os.Open(filepath.Join(m.root, filepath.FromSlash(dir), name))
+		if err != nil {
+			continue
+		}
+		m.rules = append(m.rules, parse(f, dir)...)
+		f.Close()
+	}
+}
+
+// match applies every loaded rule to rel; the last matching rule decides
+func (m *Matcher) match(rel string, isDir bool) bool {
+	ignored := false
+	for _, r := range m.rules {
+		if r.matches(rel, isDir) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+func (r rule) matches(rel string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if r.base != "" {
+		if !strings.HasPrefix(rel, r.base+"/") {
+			return false
+		}
+		rel = rel[len(r.base)+1:]
+	}
+
+	parts := strings.Split(rel, "/")
+	if !r.anchored {
+		ok, err := path.Match(r.segments[0], parts[len(parts)-1])
+		return err == nil && ok
+	}
+	return matchSegments(r.segments, parts)
+}
+
+// parse reads gitignore rules from r for an ignore file in the
+// slash-separated directory base ("" for the root)
+func parse(r io.Reader, base string) []rule {
+	var rules []rule
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if rule, ok := parseLine(scanner.Text(), base); ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+func parseLine(line, base string) (rule, bool) {
+	line = strings.TrimSuffix(line, "\r")
+	line = trimTrailingSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule{}, false
+	}
+
+	r := rule{base: base}
+	switch {
+	case strings.HasPrefix(line, "!"):
+		r.negate = true
+		line = line[1:]
+	case strings.HasPrefix(line, `\!`), strings.HasPrefix(line, `\#`):
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	// A slash at the start or in the middle anchors the pattern to base
+	if strings.Contains(line, "/") {
+		r.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return rule{}, false
+	}
+
+	// gitignore writes negated character classes as [!...], path.Match as [^...]
+	line = strings.ReplaceAll(line, "[!", "[^")
+	r.segments = strings.Split(line, "/")
+	return r, true
+}
+
+// trimTrailingSpace removes trailing spaces unless they are escaped
+func trimTrailingSpace(line string) string {
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	if strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-2] + " "
+	}
+	return line
+}
+
+// matchSegments matches path segments against pattern segments where "**"
+// matches any number of segments
+func matchSegments(pat, parts []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			rest := pat[1:]
+			// A trailing "**" matches everything inside, but not the
+			// directory itself
+			if len(rest) == 0 {
+				return len(parts) > 0
+			}
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(rest, parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pat[0], parts[0]); err != nil || !ok {
+			return false
+		}
+		pat, parts = pat[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
@@ -0,0 +1,49 @@
+package lastrun
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"raincheck/internal/report"
+)
+
+// Path returns the file holding the last review run of the project at root.
+// Runs are kept in the user cache directory so reviews never leave files in
+// the project.
+func Path(root string) (string, error) {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate cache directory: %w", err)
+	}
+
+	sum := sha256.Sum256([]byte(filepath.Clean(root)))
+	return filepath.Join(cache, "raincheck", "runs", hex.EncodeToString(sum[:8])+".json"), nil
+}
+
+// Save records rep as the last review run of the project at root
+func Save(root string, rep *report.Report) error {
+	path, err := Path(root)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create run directory: %w", err)
+	}
+	return report.WriteFile(path, report.FormatJSON, rep)
+}
+
+// Load reads the last review run of the project at root
+func Load(root string) (*report.Report, error) {
+	path, err := Path(root)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no review run found for %s; run raincheck review first", root)
+	}
+	return report.ReadJSON(path)
+}
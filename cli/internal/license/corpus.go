@@ -0,0 +1,248 @@
+package license
+
+import (
+	"embed"
+	"hash/fnv"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// corpusFS holds the reference texts of the licenses Identify recognizes,
+// one file per SPDX identifier
+//
+//go:embed corpus/*.txt
+var corpusFS embed.FS
+
+// Coverage is the share of a license's reference text that must appear in a
+// file for the license to be identified
+const Coverage = 0.9
+
+// variant is a license whose text is a lightly edited copy of another
+// license in the corpus, identified by a phrase the base license lacks
+type variant struct {
+	id, base, marker string
+	coverage         float64
+}
+
+// variants are checked when the base license's reference text is largely
+// present. The AGPL only adds a network clause to the GPL, in place of the
+// GPL's section on combining with AGPL code.
+var variants = []variant{
+	{id: "AGPL-3.0-only", base: "GPL-3.0-only", marker: "remote network interaction use with the gnu general public license", coverage: 0.75},
+}
+
+// template is the shingled reference text of a license
+type template struct {
+	id       string
+	shingles map[uint64]bool
+}
+
+var (
+	loadCorpus sync.Once
+	templates  []template
+)
+
+// corpus returns the templates of every license in the corpus
+func corpus() []template {
+	loadCorpus.Do(func() {
+		entries, err := corpusFS.ReadDir("corpus")
+		if err != nil {
+			panic(err)
+		}
+		for _, e := range entries {
+			text, err := corpusFS.ReadFile("corpus/" + e.Name())
+			if err != nil {
+				panic(err)
+			}
+			words := normalize(string(text))
+			// Appendices explaining how to apply the license are often
+			// left out of copies
+			if i := indexWords(words, "end", "of", "terms", "and", "conditions"); i > 0 {
+				words = words[:i]
+			}
+			templates = append(templates, template{
+				id:       strings.TrimSuffix(e.Name(), path.Ext(e.Name())),
+				shingles: shingles(words),
+			})
+		}
+	})
+	return templates
+}
+
+// match is a license whose reference text is present in a file
+type match struct {
+	template
+	coverage float64
+}
+
+// matched returns how many shingles of the file the match explains
+func (m match) matched() float64 {
+	return m.coverage * float64(len(m.shingles))
+}
+
+// Identify returns the SPDX identifiers of the licenses whose full text
+// appears in text, such as the contents of a LICENSE file. Files holding
+// several licenses yield each of them; nil means no license was recognized.
+func Identify(text string) []string {
+	words := normalize(text)
+	found := shingles(words)
+	if len(found) == 0 {
+		return nil
+	}
+	joined := " " + strings.Join(words, " ") + " "
+
+	var matches []match
+	for _, t := range corpus() {
+		coverage := overlap(t.shingles, found)
+		id := t.id
+		for _, v := range variants {
+			if v.base == t.id && coverage >= v.coverage && strings.Contains(joined, " "+v.marker+" ") {
+				id = v.id
+			}
+		}
+		if coverage >= Coverage || id != t.id {
+			m := match{template: t, coverage: coverage}
+			m.id = id
+			matches = append(matches, m)
+		}
+	}
+
+	if len(matches) == 0 {
+		return identifyNotice(joined)
+	}
+
+	// A license whose text is mostly contained in a match explaining more
+	// of the file, such as BSD-2-Clause within BSD-3-Clause, is not
+	// reported separately
+	sort.SliceStable(matches, func(a, b int) bool {
+		return matches[a].matched() > matches[b].matched()
+	})
+	var accepted []match
+	var ids []string
+	for _, m := range matches {
+		redundant := false
+		for _, a := range accepted {
+			if overlap(m.shingles, a.shingles) >= 0.8 {
+				redundant = true
+				break
+			}
+		}
+		if !redundant {
+			accepted = append(accepted, m)
+			ids = append(ids, m.id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+var (
+	// gnuNotice matches the notice recommended by the GNU licenses, such as
+	// "under the terms of the GNU General Public License as published by
+	// the Free Software Foundation, either version 3 of the License, or (at
+	// your option) any later version"
+	gnuNotice = regexp.MustCompile(`gnu (general|lesser general|library general|affero general) public license as published by the free software foundation (?:either )?version ([23])( 1)?(?: of the license)?( or at your option any later version)?`)
+	// notices are other short license grants that stand in for the full text
+	notices = []struct {
+		pattern *regexp.Regexp
+		id      string
+	}{
+		{regexp.MustCompile(`licensed under the apache license version 2 0`), "Apache-2.0"},
+		{regexp.MustCompile(`subject to the terms of the mozilla public license v 2 0`), "MPL-2.0"},
+		{regexp.MustCompile(`(?:licensed|released) under the mit license`), "MIT"},
+	}
+)
+
+// gnuLicenses maps the GNU license names to SPDX identifier prefixes
+var gnuLicenses = map[string]string{
+	"general":         "GPL",
+	"lesser general":  "LGPL",
+	"library general": "LGPL",
+	"affero general":  "AGPL",
+}
+
+// identifyNotice recognizes the short grant of a license that points to its
+// full text, in the normalized words of a file
+func identifyNotice(joined string) []string {
+	var ids []string
+	for _, m := range gnuNotice.FindAllStringSubmatch(joined, -1) {
+		version := m[2] + ".0"
+		if m[3] != "" {
+			version = m[2] + ".1"
+		}
+		id := gnuLicenses[m[1]] + "-" + version + "-only"
+		if m[4] != "" {
+			id = gnuLicenses[m[1]] + "-" + version + "-or-later"
+		}
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	for _, n := range notices {
+		if n.pattern.MatchString(joined) && !slices.Contains(ids, n.id) {
+			ids = append(ids, n.id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+var (
+	copyrightLine = regexp.MustCompile(`(?im)^\W*(copyright\s*(\(c\)|©|\d)|\(c\)|©).*$`)
+	nonWord       = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// normalize reduces a license text to its words, ignoring case,
+// punctuation, layout and copyright lines
+func normalize(text string) []string {
+	text = copyrightLine.ReplaceAllString(text, "")
+	text = strings.ToLower(text)
+	text = strings.NewReplacer("licence", "license", "&", " and ").Replace(text)
+	return strings.Fields(nonWord.ReplaceAllString(text, " "))
+}
+
+// shingles returns the hashes of every run of three consecutive words
+func shingles(words []string) map[uint64]bool {
+	set := make(map[uint64]bool)
+	for i := 0; i+3 <= len(words); i++ {
+		h := fnv.New64a()
+		h.Write([]byte(words[i] + " " + words[i+1] + " " + words[i+2]))
+		set[h.Sum64()] = true
+	}
+	return set
+}
+
+// overlap returns the share of the shingles of a that are also in b
+func overlap(a, b map[uint64]bool) float64 {
+	if len(a) == 0 {
+		return 0
+	}
+	n := 0
+	for s := range a {
+		if b[s] {
+			n++
+		}
+	}
+	return float64(n) / float64(len(a))
+}
+
+// indexWords returns where the run of words starts in words, or -1
+func indexWords(words []string, run ...string) int {
+	for i := 0; i+len(run) <= len(words); i++ {
+		found := true
+		for j, w := range run {
+			if words[i+j] != w {
+				found = false
+				break
+			}
+		}
+		if found {
+			return i
+		}
+	}
+	return -1
+}
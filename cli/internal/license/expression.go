@@ -0,0 +1,345 @@
+package license
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// knownIDs lists the SPDX identifiers recognized in license fields besides
+// those of the corpus
+var knownIDs = []string{
+	"0BSD", "AFL-3.0", "AGPL-1.0-only", "AGPL-1.0-or-later", "AGPL-3.0-only", "AGPL-3.0-or-later",
+	"Apache-1.1", "Apache-2.0", "Artistic-1.0", "Artistic-2.0", "BlueOak-1.0.0", "BSD-1-Clause",
+	"BSD-2-Clause", "BSD-3-Clause", "BSD-3-Clause-Clear", "BSD-4-Clause", "BSL-1.0", "BUSL-1.1",
+	"CC-BY-3.0", "CC-BY-4.0", "CC-BY-SA-3.0", "CC-BY-SA-4.0", "CC-BY-NC-4.0", "CC0-1.0",
+	"CDDL-1.0", "CDDL-1.1", "CPL-1.0", "ECL-2.0", "EPL-1.0", "EPL-2.0", "EUPL-1.1", "EUPL-1.2",
+	"GPL-1.0-only", "GPL-1.0-or-later", "GPL-2.0-only", "GPL-2.0-or-later", "GPL-3.0-only", "GPL-3.0-or-later",
+	"ISC", "LGPL-2.0-only", "LGPL-2.0-or-later", "LGPL-2.1-only", "LGPL-2.1-or-later",
+	"LGPL-3.0-only", "LGPL-3.0-or-later", "MIT", "MIT-0", "MPL-1.0", "MPL-1.1", "MPL-2.0",
+	"MPL-2.0-no-copyleft-exception", "MS-PL", "MS-RL", "NCSA", "ODbL-1.0", "OFL-1.1", "OpenSSL",
+	"OSL-3.0", "PostgreSQL", "PSF-2.0", "Python-2.0", "Ruby", "SSPL-1.0", "Unicode-DFS-2016",
+	"Unicode-3.0", "Unlicense", "UPL-1.0", "Vim", "W3C", "WTFPL", "X11", "Zlib", "ZPL-2.1",
+}
+
+// deprecatedIDs maps SPDX identifiers that were replaced to their successors
+var deprecatedIDs = map[string]string{
+	"agpl-1.0": "AGPL-1.0-only", "agpl-3.0": "AGPL-3.0-only", "agpl-3.0+": "AGPL-3.0-or-later",
+	"gpl-1.0": "GPL-1.0-only", "gpl-1.0+": "GPL-1.0-or-later",
+	"gpl-2.0": "GPL-2.0-only", "gpl-2.0+": "GPL-2.0-or-later",
+	"gpl-3.0": "GPL-3.0-only", "gpl-3.0+": "GPL-3.0-or-later",
+	"lgpl-2.0": "LGPL-2.0-only", "lgpl-2.0+": "LGPL-2.0-or-later",
+	"lgpl-2.1": "LGPL-2.1-only", "lgpl-2.1+": "LGPL-2.1-or-later",
+	"lgpl-3.0": "LGPL-3.0-only", "lgpl-3.0+": "LGPL-3.0-or-later",
+}
+
+// aliases maps license names as written in package metadata, reduced by
+// aliasKey, to SPDX identifiers
+var aliases = map[string]string{
+	"apache2": "Apache-2.0", "apache20": "Apache-2.0", "asl20": "Apache-2.0", "apachesoftware20": "Apache-2.0",
+	"mit": "MIT", "expat": "MIT", "isc": "ISC", "unlicense": "Unlicense", "zlib": "Zlib",
+	"bsd2": "BSD-2-Clause", "bsd2clause": "BSD-2-Clause", "simplifiedbsd": "BSD-2-Clause", "freebsd": "BSD-2-Clause",
+	"bsd3": "BSD-3-Clause", "bsd3clause": "BSD-3-Clause", "newbsd": "BSD-3-Clause", "revisedbsd": "BSD-3-Clause", "modifiedbsd": "BSD-3-Clause",
+	"cc0": "CC0-1.0", "cc010": "CC0-1.0", "cc010universal": "CC0-1.0",
+	"mozillapublic20": "MPL-2.0", "mpl20": "MPL-2.0", "mozillapublic11": "MPL-1.1", "mpl11": "MPL-1.1",
+	"eclipsepublic10": "EPL-1.0", "epl10": "EPL-1.0", "eclipsepublic20": "EPL-2.0", "epl20": "EPL-2.0",
+	"boost10": "BSL-1.0", "bsl10": "BSL-1.0", "cddl10": "CDDL-1.0", "psf": "PSF-2.0", "python20": "Python-2.0",
+	"gpl2": "GPL-2.0-only", "gnugpl2": "GPL-2.0-only", "gnugeneralpublic2": "GPL-2.0-only",
+	"gpl3": "GPL-3.0-only", "gnugpl3": "GPL-3.0-only", "gnugeneralpublic3": "GPL-3.0-only",
+	"lgpl21": "LGPL-2.1-only", "gnulessergeneralpublic21": "LGPL-2.1-only",
+	"lgpl3": "LGPL-3.0-only", "gnulessergeneralpublic3": "LGPL-3.0-only",
+	"agpl3": "AGPL-3.0-only", "gnuafferogeneralpublic3": "AGPL-3.0-only",
+}
+
+var (
+	canonicalIDs = func() map[string]string {
+		ids := make(map[string]string)
+		for _, id := range knownIDs {
+			ids[strings.ToLower(id)] = id
+		}
+		return ids
+	}()
+	aliasVersion = regexp.MustCompile(`\bv(\d)`)
+	aliasNoise   = regexp.MustCompile(`\b(the|license|licence|software|version)\b|[^a-z0-9]+`)
+)
+
+// aliasKey reduces a license name such as "The Apache Software License,
+// Version 2.0" to the form used by aliases, "apache20"
+func aliasKey(name string) string {
+	key := aliasVersion.ReplaceAllString(strings.ToLower(name), "$1")
+	return aliasNoise.ReplaceAllString(key, "")
+}
+
+// Normalize maps a license name to its SPDX identifier. Names that are
+// neither SPDX identifiers nor known aliases are returned unchanged, and
+// names that only refer elsewhere, such as npm's "SEE LICENSE IN
+// LICENSE.txt", yield "".
+func Normalize(name string) string {
+	name = strings.TrimSpace(name)
+	lower := strings.ToLower(name)
+	switch {
+	case name == "", strings.HasPrefix(lower, "see license in"), lower == "unknown", lower == "noassertion":
+		return ""
+	case strings.HasPrefix(lower, "licenseref-"):
+		return name
+	}
+	if id, ok := deprecatedIDs[lower]; ok {
+		return id
+	}
+	if id, ok := canonicalIDs[lower]; ok {
+		return id
+	}
+	if id, ok := aliases[aliasKey(name)]; ok {
+		return id
+	}
+	return name
+}
+
+// Expr is a parsed SPDX license expression. Leaves have an ID; inner nodes
+// combine their operands with AND or OR.
+type Expr struct {
+	ID       string
+	Op       string
+	Operands []*Expr
+}
+
+// String renders the expression in SPDX syntax
+func (e *Expr) String() string {
+	if e.Op == "" {
+		return e.ID
+	}
+	parts := make([]string, len(e.Operands))
+	for i, o := range e.Operands {
+		parts[i] = o.String()
+		if o.Op != "" && o.Op != e.Op {
+			parts[i] = "(" + parts[i] + ")"
+		}
+	}
+	return strings.Join(parts, " "+e.Op+" ")
+}
+
+// IDs returns the license identifiers in the expression
+func (e *Expr) IDs() []string {
+	if e.Op == "" {
+		return []string{e.ID}
+	}
+	var ids []string
+	for _, o := range e.Operands {
+		ids = append(ids, o.IDs()...)
+	}
+	return ids
+}
+
+// Parse parses an SPDX license expression such as "MIT OR Apache-2.0".
+// Exceptions added with WITH are dropped. A string that is not a valid
+// expression, such as "Apache License, Version 2.0", is taken as the name
+// of a single license. Parse returns nil when no license is named.
+func Parse(s string) *Expr {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	if e, ok := parseExpression(s); ok {
+		return e
+	}
+	if id := Normalize(strings.Trim(s, "()")); id != "" {
+		return &Expr{ID: id}
+	}
+	return nil
+}
+
+// Combine joins the licenses found for one package with op, AND when all
+// of them apply and OR when any may be chosen
+func Combine(op string, ids []string) *Expr {
+	var operands []*Expr
+	for _, id := range ids {
+		if e := Parse(id); e != nil {
+			operands = append(operands, e)
+		}
+	}
+	switch len(operands) {
+	case 0:
+		return nil
+	case 1:
+		return operands[0]
+	}
+	return &Expr{Op: op, Operands: operands}
+}
+
+// idSyntax matches SPDX license identifiers
+var idSyntax = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9.+-]*$`)
+
+// parseExpression parses s strictly as an SPDX license expression
+func parseExpression(s string) (*Expr, bool) {
+	p := &exprParser{tokens: tokenize(s)}
+	e, err := p.or()
+	if err != nil || p.pos != len(p.tokens) {
+		return nil, false
+	}
+	return e, true
+}
+
+func tokenize(s string) []string {
+	s = strings.NewReplacer("(", " ( ", ")", " ) ").Replace(s)
+	return strings.Fields(s)
+}
+
+type exprParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *exprParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *exprParser) binary(op string, operand func() (*Expr, error)) (*Expr, error) {
+	first, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	e := &Expr{Op: op, Operands: []*Expr{first}}
+	for strings.EqualFold(p.peek(), op) {
+		p.pos++
+		next, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		e.Operands = append(e.Operands, next)
+	}
+	if len(e.Operands) == 1 {
+		return first, nil
+	}
+	return e, nil
+}
+
+func (p *exprParser) or() (*Expr, error) {
+	return p.binary("OR", p.and)
+}
+
+func (p *exprParser) and() (*Expr, error) {
+	return p.binary("AND", p.with)
+}
+
+func (p *exprParser) with() (*Expr, error) {
+	e, err := p.atom()
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(p.peek(), "WITH") {
+		p.pos += 2
+		if p.pos > len(p.tokens) {
+			return nil, fmt.Errorf("missing exception after WITH")
+		}
+	}
+	return e, nil
+}
+
+func (p *exprParser) atom() (*Expr, error) {
+	tok := p.peek()
+	switch {
+	case tok == "":
+		return nil, fmt.Errorf("unexpected end of expression")
+	case tok == "(":
+		p.pos++
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return e, nil
+	case tok == ")", strings.EqualFold(tok, "AND"), strings.EqualFold(tok, "OR"), strings.EqualFold(tok, "WITH"):
+		return nil, fmt.Errorf("unexpected %q", tok)
+	}
+	// Punctuation is not valid in identifiers, so a word of a license name
+	// such as "License," makes the whole string a name
+	if !idSyntax.MatchString(tok) {
+		return nil, fmt.Errorf("invalid license identifier %q", tok)
+	}
+	p.pos++
+	return &Expr{ID: Normalize(tok)}, nil
+}
+
+// Verdicts of a license under a policy, in increasing order of severity
+const (
+	Allowed = iota
+	NotAllowed
+	Denied
+)
+
+// Policy restricts the licenses that may be used. Patterns are matched
+// case-insensitively and may use * wildcards; a pattern without an -only or
+// -or-later suffix matches both, so "GPL-*" covers every GPL version.
+type Policy struct {
+	// Allow lists the permitted licenses; when empty every license that is
+	// not denied is permitted
+	Allow []string
+	// Deny lists the forbidden licenses
+	Deny []string
+}
+
+// Enabled reports whether the policy restricts any license
+func (p Policy) Enabled() bool {
+	return len(p.Allow) > 0 || len(p.Deny) > 0
+}
+
+// Evaluate returns the verdict on an expression together with the
+// licenses causing it. Any permitted choice satisfies OR, while AND needs
+// every operand to be permitted.
+func (p Policy) Evaluate(e *Expr) (int, []string) {
+	switch e.Op {
+	case "":
+		switch {
+		case matchAny(p.Deny, e.ID):
+			return Denied, []string{e.ID}
+		case len(p.Allow) > 0 && !matchAny(p.Allow, e.ID):
+			return NotAllowed, []string{e.ID}
+		}
+		return Allowed, nil
+	case "OR":
+		verdict, offending := Denied, []string(nil)
+		for i, o := range e.Operands {
+			v, ids := p.Evaluate(o)
+			if i == 0 || v < verdict {
+				verdict, offending = v, ids
+			}
+		}
+		return verdict, offending
+	}
+
+	verdict, offending := Allowed, []string(nil)
+	for _, o := range e.Operands {
+		v, ids := p.Evaluate(o)
+		if v > verdict {
+			verdict, offending = v, ids
+		} else if v == verdict && v != Allowed {
+			offending = append(offending, ids...)
+		}
+	}
+	return verdict, offending
+}
+
+// orLater matches the suffixes naming the versions a license applies to
+var orLater = regexp.MustCompile(`(?i)(-only|-or-later|\+)$`)
+
+func matchAny(patterns []string, id string) bool {
+	id = strings.ToLower(id)
+	base := orLater.ReplaceAllString(id, "")
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if ok, _ := path.Match(pattern, id); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, base); ok {
+			return true
+		}
+	}
+	return false
+}
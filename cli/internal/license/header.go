@@ -0,0 +1,41 @@
+package license
+
+import (
+	"regexp"
+	"strings"
+)
+
+// HeaderLines is how many lines at the top of a source file are searched
+// for an SPDX header
+const HeaderLines = 50
+
+// spdxHeader matches an SPDX-License-Identifier tag, stopping before the
+// end of a block comment
+var spdxHeader = regexp.MustCompile(`SPDX-License-Identifier:\s*(.*?)\s*(?:\*/|-->|\*\)|-\}|$)`)
+
+// Header is an SPDX-License-Identifier tag in a source file
+type Header struct {
+	Line       int
+	Expression string
+}
+
+// FindHeaders returns the SPDX-License-Identifier tags in the first
+// HeaderLines lines of content
+func FindHeaders(content []byte) []Header {
+	var headers []Header
+	lines := strings.SplitN(string(content), "\n", HeaderLines+1)
+	for i, line := range lines {
+		if i == HeaderLines {
+			break
+		}
+		// Prose mentioning the tag does not parse as an expression
+		m := spdxHeader.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		if _, ok := parseExpression(m[1]); ok {
+			headers = append(headers, Header{Line: i + 1, Expression: m[1]})
+		}
+	}
+	return headers
+}
@@ -0,0 +1,155 @@
+package license
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"raincheck/internal/deps"
+)
+
+// Installed finds the installed copy of a dependency declared by the
+// manifest in manifestDir: the Go module cache, the Cargo registry, a
+// node_modules directory above the manifest or the local Maven repository.
+// It reports false when the package is not installed on this machine.
+func Installed(manifestDir string, p deps.Package) (Vendored, bool) {
+	switch p.Ecosystem {
+	case deps.EcosystemGo:
+		if dir := goModDir(p); dir != "" {
+			return installedDir(dir, p)
+		}
+	case deps.EcosystemCargo:
+		if dir := cargoDir(p); dir != "" {
+			return installedDir(dir, p)
+		}
+	case deps.EcosystemNPM:
+		for dir := manifestDir; ; dir = filepath.Dir(dir) {
+			if pkg := filepath.Join(dir, "node_modules", filepath.FromSlash(p.Name)); isDir(pkg) {
+				if v, ok := installedDir(pkg, p); ok && (v.Version == "" || v.Version == p.Version) {
+					return v, true
+				}
+			}
+			if filepath.Dir(dir) == dir {
+				break
+			}
+		}
+	case deps.EcosystemMaven:
+		return mavenPom(p)
+	}
+	return Vendored{}, false
+}
+
+// installedDir reads the package installed in dir
+func installedDir(dir string, p deps.Package) (Vendored, bool) {
+	v, ok, err := readVendored(filepath.Dir(dir), dir)
+	if err != nil || !ok {
+		return Vendored{}, false
+	}
+	if v.Version == "" {
+		v.Name, v.Version = p.Name, p.Version
+	}
+	return v, true
+}
+
+// goModDir returns the directory of a module in the module cache, or ""
+func goModDir(p deps.Package) string {
+	cache := os.Getenv("GOMODCACHE")
+	if cache == "" {
+		gopath := os.Getenv("GOPATH")
+		if gopath == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return ""
+			}
+			gopath = filepath.Join(home, "go")
+		}
+		cache = filepath.Join(filepath.SplitList(gopath)[0], "pkg", "mod")
+	}
+	dir := filepath.Join(cache, filepath.FromSlash(escapeModulePath(p.Name)+"@"+escapeModulePath(p.Version)))
+	if !isDir(dir) {
+		return ""
+	}
+	return dir
+}
+
+// escapeModulePath encodes a module path or version as the module cache
+// does, replacing upper case letters with "!" and their lower case
+func escapeModulePath(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if unicode.IsUpper(r) {
+			b.WriteByte('!')
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// cargoDir returns the directory of a crate unpacked in the Cargo registry,
+// or ""
+func cargoDir(p deps.Package) string {
+	home := os.Getenv("CARGO_HOME")
+	if home == "" {
+		userHome, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		home = filepath.Join(userHome, ".cargo")
+	}
+	// There is one source directory per registry index
+	matches, _ := filepath.Glob(filepath.Join(home, "registry", "src", "*", p.Name+"-"+p.Version))
+	for _, dir := range matches {
+		if isDir(dir) {
+			return dir
+		}
+	}
+	return ""
+}
+
+// mavenPom reads the licenses a Maven artifact declares in its pom.xml in
+// the local repository. Licenses inherited from parent poms are not read.
+func mavenPom(p deps.Package) (Vendored, bool) {
+	group, artifact, ok := strings.Cut(p.Name, ":")
+	if !ok {
+		return Vendored{}, false
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return Vendored{}, false
+	}
+	dir := filepath.Join(append([]string{home, ".m2", "repository"}, strings.Split(group, ".")...)...)
+	path := filepath.Join(dir, artifact, p.Version, artifact+"-"+p.Version+".pom")
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Vendored{}, false
+	}
+
+	var pom struct {
+		Licenses []struct {
+			Name string `xml:"name"`
+		} `xml:"licenses>license"`
+	}
+	if xml.Unmarshal(content, &pom) != nil {
+		return Vendored{}, false
+	}
+	v := Vendored{Name: p.Name, Version: p.Version, Dir: filepath.Dir(path)}
+	var names []string
+	for _, l := range pom.Licenses {
+		if id := Normalize(l.Name); id != "" {
+			names = append(names, id)
+		}
+	}
+	// A pom listing several licenses offers them as alternatives
+	if e := Combine("OR", names); e != nil {
+		v.Declared, v.Manifest = e.String(), path
+	}
+	return v, true
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,81 @@
+package license
+
+import (
+	"fmt"
+	"strings"
+
+	"raincheck/internal/api"
+)
+
+// UnknownType is the finding type of licenses that could not be identified
+const UnknownType = "unknown-license"
+
+// Finding is a license the policy forbids or that could not be identified
+type Finding struct {
+	Line     int
+	Severity string
+	// Type is the offending license, or UnknownType
+	Type        string
+	Description string
+	Suggestion  string
+}
+
+// Check evaluates the license of subject, such as "dependency
+// lodash@4.17.21", against the policy. A nil expression is reported with
+// the unknown severity, or not at all when it is empty. Check returns nil
+// when the license is permitted.
+func (p Policy) Check(subject string, e *Expr, line int, unknown string) *Finding {
+	if e == nil {
+		if unknown == "" {
+			return nil
+		}
+		return &Finding{
+			Line:        line,
+			Severity:    api.NormalizeSeverity(unknown),
+			Type:        UnknownType,
+			Description: fmt.Sprintf("%s has no license that could be identified", subject),
+			Suggestion:  "Check the license terms by hand and declare them with an SPDX identifier",
+		}
+	}
+
+	verdict, offending := p.Evaluate(e)
+	switch verdict {
+	case Denied:
+		return &Finding{
+			Line:        line,
+			Severity:    api.SeverityError,
+			Type:        offending[0],
+			Description: fmt.Sprintf("%s is licensed under %s, which the license policy denies (%s)", subject, e, strings.Join(offending, ", ")),
+			Suggestion:  fmt.Sprintf("Remove or replace %s, or obtain it under a license the policy allows", subject),
+		}
+	case NotAllowed:
+		return &Finding{
+			Line:        line,
+			Severity:    api.SeverityWarning,
+			Type:        offending[0],
+			Description: fmt.Sprintf("%s is licensed under %s, which is not among the allowed licenses (%s)", subject, e, strings.Join(offending, ", ")),
+			Suggestion:  "Review the license and add it to licenses.allow in .raincheck.yml if it is acceptable, or replace " + subject,
+		}
+	}
+	return nil
+}
+
+// Category turns findings into the Licenses report category
+func Category(findings []Finding) *api.Category {
+	issues := make([]api.Issue, 0, len(findings))
+	for _, f := range findings {
+		issues = append(issues, api.Issue{
+			Severity:    f.Severity,
+			Type:        f.Type,
+			Description: f.Description,
+			Line:        f.Line,
+			Suggestion:  f.Suggestion,
+		})
+	}
+	return api.NewCategory(issues)
+}
+
+// Analysis returns the report entry of a file with license findings
+func Analysis(findings []Finding) *api.AnalysisResponse {
+	return api.ScanAnalysis(api.CategoryLicenses, Category(findings))
+}
@@ -0,0 +1,306 @@
+package license
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// VendorDirs names the directories holding copies of third-party packages
+var VendorDirs = map[string]bool{
+	"vendor":       true,
+	"node_modules": true,
+	"third_party":  true,
+	"third-party":  true,
+	"thirdparty":   true,
+}
+
+// licenseFileName matches LICENSE, COPYING and similar files, with
+// suffixes such as LICENSE-MIT, LICENSE.md or COPYING.LESSER
+var licenseFileName = regexp.MustCompile(`(?i)^(un)?(licen[cs]e|copying|copyright)([-._].*)?$`)
+
+// IsLicenseFile reports whether the file at path holds license text
+func IsLicenseFile(path string) bool {
+	return licenseFileName.MatchString(filepath.Base(path))
+}
+
+// Vendored is a third-party package copied into a vendor directory
+type Vendored struct {
+	Name    string
+	Version string
+	// Dir is the directory holding the package
+	Dir string
+	// Declared is the license expression of the package manifest, "" when
+	// there is none
+	Declared string
+	// Manifest is the file declaring the license, with the line it is on
+	Manifest string
+	Line     int
+	// LicenseFiles are the license files at the top of Dir
+	LicenseFiles []string
+}
+
+// FindVendored lists the packages in the vendor directory root: the
+// modules of a Go vendor/modules.txt, the packages of a node_modules
+// directory, or otherwise each subdirectory, descending one level into
+// directories that only group packages, such as Composer vendor names
+func FindVendored(root string) ([]Vendored, error) {
+	if filepath.Base(root) == "vendor" {
+		if modules, err := os.ReadFile(filepath.Join(root, "modules.txt")); err == nil {
+			return goVendored(root, modules)
+		}
+	}
+
+	dirs, err := subdirs(root)
+	if err != nil {
+		return nil, err
+	}
+	var pkgs []Vendored
+	for _, dir := range dirs {
+		v, ok, err := readVendored(root, dir)
+		if err != nil {
+			return nil, err
+		}
+		// Scopes of node_modules and Composer vendor names group packages
+		if !ok || strings.HasPrefix(filepath.Base(dir), "@") {
+			inner, err := subdirs(dir)
+			if err != nil {
+				return nil, err
+			}
+			for _, d := range inner {
+				v, _, err := readVendored(root, d)
+				if err != nil {
+					return nil, err
+				}
+				pkgs = append(pkgs, v)
+			}
+			continue
+		}
+		pkgs = append(pkgs, v)
+	}
+	return pkgs, nil
+}
+
+// goVendored reads the modules listed by "# path version" lines of
+// vendor/modules.txt
+func goVendored(root string, modules []byte) ([]Vendored, error) {
+	var pkgs []Vendored
+	scanner := bufio.NewScanner(bytes.NewReader(modules))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || fields[0] != "#" {
+			continue
+		}
+		dir := filepath.Join(root, filepath.FromSlash(fields[1]))
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		v, _, err := readVendored(root, dir)
+		if err != nil {
+			return nil, err
+		}
+		v.Name, v.Version = fields[1], fields[2]
+		pkgs = append(pkgs, v)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read modules.txt: %w", err)
+	}
+	return pkgs, nil
+}
+
+// readVendored reads the package in dir and reports whether dir looks like
+// a package, having a license file or a manifest
+func readVendored(root, dir string) (Vendored, bool, error) {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return Vendored{}, false, err
+	}
+	v := Vendored{Name: filepath.ToSlash(rel), Dir: dir}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return Vendored{}, false, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+	manifest := false
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		switch name := e.Name(); {
+		case IsLicenseFile(name):
+			v.LicenseFiles = append(v.LicenseFiles, path)
+		case name == "package.json", name == "composer.json", name == "Cargo.toml":
+			manifest = true
+			if v.Declared == "" {
+				readManifest(&v, path)
+			}
+		}
+	}
+	sort.Strings(v.LicenseFiles)
+	return v, manifest || len(v.LicenseFiles) > 0, nil
+}
+
+// cargoLicense matches the license key of a Cargo.toml
+var cargoLicense = regexp.MustCompile(`(?m)^license\s*=\s*"([^"]*)"`)
+
+// readManifest reads the name, version and declared license of a vendored
+// package from its manifest, leaving v unchanged when it has none
+func readManifest(v *Vendored, path string) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	var declared string
+	key := []byte(`"license`)
+	if filepath.Base(path) == "Cargo.toml" {
+		if m := cargoLicense.FindSubmatch(content); m != nil {
+			declared = string(m[1])
+		}
+		key = []byte("\nlicense")
+	} else {
+		var meta struct {
+			Name     string          `json:"name"`
+			Version  string          `json:"version"`
+			License  json.RawMessage `json:"license"`
+			Licenses json.RawMessage `json:"licenses"`
+		}
+		if json.Unmarshal(content, &meta) != nil {
+			return
+		}
+		if meta.Name != "" {
+			v.Name, v.Version = meta.Name, meta.Version
+		}
+		declared = DeclaredLicense(meta.License)
+		if declared == "" {
+			declared = DeclaredLicense(meta.Licenses)
+		}
+	}
+	if declared == "" {
+		return
+	}
+	v.Declared, v.Manifest = declared, path
+	if i := bytes.Index(content, key); i >= 0 {
+		v.Line = bytes.Count(content[:i+1], []byte("\n")) + 1
+	}
+}
+
+// DeclaredLicense reads a license field of package metadata, which may be
+// an expression, an object with a type as in old npm packages, or a list
+// of alternatives as in Composer
+func DeclaredLicense(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var obj struct {
+		Type string `json:"type"`
+	}
+	if json.Unmarshal(raw, &obj) == nil {
+		return obj.Type
+	}
+	var list []json.RawMessage
+	if json.Unmarshal(raw, &list) != nil {
+		return ""
+	}
+	var alternatives []string
+	for _, item := range list {
+		if l := DeclaredLicense(item); l != "" {
+			alternatives = append(alternatives, l)
+		}
+	}
+	if len(alternatives) > 1 {
+		return "(" + strings.Join(alternatives, " OR ") + ")"
+	}
+	return strings.Join(alternatives, "")
+}
+
+// subdirs returns the directories in dir, skipping hidden ones such as
+// node_modules/.bin
+func subdirs(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+	var dirs []string
+	for _, e := range entries {
+		if e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
+			dirs = append(dirs, filepath.Join(dir, e.Name()))
+		}
+	}
+	return dirs, nil
+}
+
+// LicenseFile is a license file and the licenses identified in it
+type LicenseFile struct {
+	Path string
+	IDs  []string
+	// Recognized is false when the text matches no known license
+	Recognized bool
+}
+
+// IdentifyFiles identifies the licenses in a set of license files that
+// belong together, such as the files of a package or directory. The GPL
+// text shipped next to the LGPL 3.0, which only adds permissions to it, is
+// not reported.
+func IdentifyFiles(paths []string) ([]LicenseFile, error) {
+	var files []LicenseFile
+	lesser := false
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		ids := Identify(string(content))
+		for _, id := range ids {
+			lesser = lesser || strings.HasPrefix(id, "LGPL-3.0")
+		}
+		files = append(files, LicenseFile{Path: path, IDs: ids, Recognized: len(ids) > 0})
+	}
+	if lesser {
+		for i, f := range files {
+			var kept []string
+			for _, id := range f.IDs {
+				if !strings.HasPrefix(id, "GPL-3.0") {
+					kept = append(kept, id)
+				}
+			}
+			files[i].IDs = kept
+		}
+	}
+	return files, nil
+}
+
+// License returns the license of the package and the file it was found in:
+// the declared license when the manifest has one, otherwise the licenses
+// identified in its license files, which all apply. The expression is nil
+// when no license could be identified.
+func (v Vendored) License() (*Expr, string, int, error) {
+	if v.Declared != "" {
+		return Parse(v.Declared), v.Manifest, v.Line, nil
+	}
+	if len(v.LicenseFiles) == 0 {
+		return nil, v.Dir, 0, nil
+	}
+	files, err := IdentifyFiles(v.LicenseFiles)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	var ids []string
+	for _, f := range files {
+		for _, id := range f.IDs {
+			if !slices.Contains(ids, id) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return Combine("AND", ids), v.LicenseFiles[0], 0, nil
+}
@@ -0,0 +1,183 @@
+package patch
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConflictError reports a hunk whose context does not match the file
+type ConflictError struct {
+	Path string
+	Hunk int // 1-based hunk number
+	Line int // line the hunk expected to start at, 0 if unknown
+}
+
+func (e *ConflictError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s: hunk %d does not apply at line %d", e.Path, e.Hunk, e.Line)
+	}
+	return fmt.Sprintf("%s: hunk %d does not apply", e.Path, e.Hunk)
+}
+
+// Apply applies the patch to content and returns the patched content. Each
+// hunk must match exactly within maxOffset lines of its stated line,
+// adjusted for earlier hunks; hunks without line numbers must match exactly
+// once in the file. Only inserted and deleted lines change the file, so its
+// own context lines, indentation and line endings are kept. If any hunk
+// cannot be located a *ConflictError is returned.
+func (fp FilePatch) Apply(content []byte) ([]byte, error) {
+	result, _, err := fp.apply(content)
+	return result, err
+}
+
+// Anchor locates every hunk in content and rewrites the hunk headers with
+// the positions found. Generated diffs often carry missing or inaccurate
+// line numbers; anchoring them makes the saved patch precise.
+func (fp *FilePatch) Anchor(content []byte) error {
+	_, positions, err := fp.apply(content)
+	if err != nil {
+		return err
+	}
+
+	delta := 0
+	for i := range fp.Hunks {
+		h := &fp.Hunks[i]
+		old, new := h.counts()
+		h.OldCount, h.NewCount = old, new
+		h.NewStart = positions[i] + 1
+		h.OldStart = h.NewStart - delta
+		if old == 0 {
+			h.OldStart--
+		}
+		if new == 0 {
+			h.NewStart--
+		}
+		delta += new - old
+	}
+	return nil
+}
+
+// maxOffset is how many lines a hunk may move from its stated position
+const maxOffset = 3
+
+// apply patches content and returns the 0-based line at which each hunk was
+// placed in the partially patched file
+func (fp FilePatch) apply(content []byte) ([]byte, []int, error) {
+	newline := "\n"
+	if strings.Contains(string(content), "\r\n") {
+		newline = "\r\n"
+	}
+	text := strings.ReplaceAll(string(content), "\r\n", "\n")
+	trailingNewline := text == "" || strings.HasSuffix(text, "\n")
+	var lines []string
+	if text != "" {
+		lines = strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	}
+
+	positions := make([]int, 0, len(fp.Hunks))
+	offset := 0
+	for i, h := range fp.Hunks {
+		old, new := h.counts()
+		var context []string
+		for _, l := range h.Lines {
+			if l.Kind != Insert {
+				context = append(context, l.Text)
+			}
+		}
+
+		expected := -1
+		if h.OldStart > 0 || (h.OldStart == 0 && h.OldCount == 0 && old == 0) {
+			expected = h.OldStart - 1 + offset
+			if old == 0 {
+				// Pure insertions go after the stated line
+				expected = h.OldStart + offset
+			}
+		}
+
+		pos := locate(lines, context, expected)
+		if pos < 0 {
+			return nil, nil, &ConflictError{Path: fp.Path(), Hunk: i + 1, Line: h.OldStart}
+		}
+		positions = append(positions, pos)
+
+		updated := make([]string, 0, len(lines)-old+new)
+		updated = append(updated, lines[:pos]...)
+		k := pos
+		for _, l := range h.Lines {
+			switch l.Kind {
+			case Insert:
+				updated = append(updated, l.Text)
+			case Delete:
+				k++
+			default:
+				// Keep the file's own line rather than the patch's copy
+				updated = append(updated, lines[k])
+				k++
+			}
+		}
+		updated = append(updated, lines[k:]...)
+		lines = updated
+
+		if expected >= 0 {
+			offset += pos - expected + new - old
+		}
+	}
+
+	result := strings.Join(lines, newline)
+	if trailingNewline && len(lines) > 0 {
+		result += newline
+	}
+	return []byte(result), positions, nil
+}
+
+// locate finds where old occurs in lines within maxOffset lines of
+// expected, preferring the closest position. When expected is negative the
+// hunk has no position and old must occur exactly once in lines. It returns
+// -1 when old is not found.
+func locate(lines, old []string, expected int) int {
+	if len(old) == 0 {
+		if expected < 0 || expected > len(lines) {
+			return len(lines)
+		}
+		return expected
+	}
+
+	if expected < 0 {
+		found := -1
+		for pos := 0; pos+len(old) <= len(lines); pos++ {
+			if matches(lines, old, pos) {
+				if found >= 0 {
+					// Ambiguous without a line number
+					return -1
+				}
+				found = pos
+			}
+		}
+		return found
+	}
+
+	for delta := 0; delta <= maxOffset; delta++ {
+		if matches(lines, old, expected-delta) {
+			return expected - delta
+		}
+		if delta > 0 && matches(lines, old, expected+delta) {
+			return expected + delta
+		}
+	}
+	return -1
+}
+
+// matches reports whether old occurs in lines at pos. Lines must be equal,
+// except that an empty line in the patch matches a line of whitespace,
+// since editors and models often strip trailing whitespace from diffs.
+func matches(lines, old []string, pos int) bool {
+	if pos < 0 || pos+len(old) > len(lines) {
+		return false
+	}
+	for j, l := range old {
+		if lines[pos+j] != l && (l != "" || strings.TrimSpace(lines[pos+j]) != "") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,188 @@
+package patch
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BackupSuffix is appended to a file's name to form its backup
+const BackupSuffix = ".orig"
+
+// Change is a patched file ready to be written
+type Change struct {
+	Path    string // path on disk
+	Hunks   int
+	Create  bool // the file does not exist yet
+	content []byte
+	mode    os.FileMode
+}
+
+// Prepare applies every patch in memory against the files under dir. It
+// returns all conflicts joined in a single error so nothing is written unless
+// the whole patch applies cleanly.
+func Prepare(dir string, patches []FilePatch) ([]Change, error) {
+	var (
+		changes []Change
+		errs    []error
+	)
+
+	for _, fp := range patches {
+		if fp.NewPath == "/dev/null" {
+			errs = append(errs, fmt.Errorf("%s: deleting files is not supported", fp.OldPath))
+			continue
+		}
+		if fp.Path() == "" {
+			errs = append(errs, fmt.Errorf("patch has no file name"))
+			continue
+		}
+
+		path, err := localPath(dir, fp.Path())
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		change := Change{Path: path, Hunks: len(fp.Hunks), mode: 0644}
+
+		content, err := os.ReadFile(path)
+		switch {
+		case err == nil && fp.OldPath == "/dev/null":
+			errs = append(errs, fmt.Errorf("%s: file already exists", fp.Path()))
+			continue
+		case errors.Is(err, os.ErrNotExist) && fp.OldPath == "/dev/null":
+			change.Create = true
+		case err != nil:
+			errs = append(errs, fmt.Errorf("%s: %w", fp.Path(), err))
+			continue
+		default:
+			if info, err := os.Stat(path); err == nil {
+				change.mode = info.Mode().Perm()
+			}
+		}
+
+		change.content, err = fp.Apply(content)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		changes = append(changes, change)
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return changes, nil
+}
+
+// Write saves prepared changes. With backup set, each existing file is first
+// copied to a file with BackupSuffix so the change can be reverted; an
+// existing backup is never overwritten.
+func Write(changes []Change, backup bool) error {
+	if backup {
+		for _, c := range changes {
+			if c.Create {
+				continue
+			}
+			if _, err := os.Stat(c.Path + BackupSuffix); err == nil {
+				return fmt.Errorf("backup %s already exists; revert or remove it first", c.Path+BackupSuffix)
+			}
+		}
+		for _, c := range changes {
+			if c.Create {
+				continue
+			}
+			original, err := os.ReadFile(c.Path)
+			if err != nil {
+				return fmt.Errorf("failed to back up %s: %w", c.Path, err)
+			}
+			if err := os.WriteFile(c.Path+BackupSuffix, original, c.mode); err != nil {
+				return fmt.Errorf("failed to back up %s: %w", c.Path, err)
+			}
+		}
+	}
+
+	for _, c := range changes {
+		if err := writeAtomic(c.Path, c.content, c.mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Revert restores the backups of every file touched by patches and removes
+// files the patches created
+func Revert(dir string, patches []FilePatch) ([]string, error) {
+	var (
+		restored []string
+		errs     []error
+	)
+
+	for _, fp := range patches {
+		path, err := localPath(dir, fp.Path())
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if fp.OldPath == "/dev/null" {
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, fmt.Errorf("%s: %w", fp.Path(), err))
+				continue
+			}
+			restored = append(restored, path)
+			continue
+		}
+
+		if err := os.Rename(path+BackupSuffix, path); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				err = fmt.Errorf("no backup found at %s", path+BackupSuffix)
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", fp.Path(), err))
+			continue
+		}
+		restored = append(restored, path)
+	}
+
+	return restored, errors.Join(errs...)
+}
+
+// localPath joins the path of a patched file to dir, rejecting absolute
+// paths and paths that lead outside dir
+func localPath(dir, name string) (string, error) {
+	local := filepath.Clean(filepath.FromSlash(name))
+	if !filepath.IsLocal(local) {
+		return "", fmt.Errorf("%s: path is outside the project", name)
+	}
+	return filepath.Join(dir, local), nil
+}
+
+// writeAtomic replaces path with data by writing a temporary file in the
+// same directory and renaming it into place
+func writeAtomic(path string, data []byte, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+strings.TrimPrefix(filepath.Base(path), ".")+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package patch
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Line kinds in a hunk
+const (
+	Context = ' '
+	Delete  = '-'
+	Insert  = '+'
+)
+
+// Line is a single line of a hunk
+type Line struct {
+	Kind byte
+	Text string
+}
+
+// Hunk is a contiguous block of changes
+type Hunk struct {
+	OldStart int
+	OldCount int
+	NewStart int
+	NewCount int
+	Lines    []Line
+}
+
+// FilePatch holds the hunks for a single file
+type FilePatch struct {
+	OldPath string
+	NewPath string
+	Hunks   []Hunk
+}
+
+// Path returns the path of the file the patch applies to
+func (fp FilePatch) Path() string {
+	if fp.NewPath != "" && fp.NewPath != "/dev/null" {
+		return fp.NewPath
+	}
+	return fp.OldPath
+}
+
+// Parse reads a unified diff. Hunks that appear before any file header are
+// collected into a FilePatch with empty paths, which lets callers attach a
+// path to bare diffs.
+func Parse(diff string) ([]FilePatch, error) {
+	var (
+		patches []FilePatch
+		current *FilePatch
+		hunk    *Hunk
+	)
+
+	flushHunk := func() {
+		if hunk != nil && current != nil {
+			if hunk.OldCount < 0 {
+				hunk.OldCount, hunk.NewCount = hunk.counts()
+			}
+			current.Hunks = append(current.Hunks, *hunk)
+		}
+		hunk = nil
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(diff))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+
+		switch {
+		case strings.HasPrefix(line, "--- ") && (hunk == nil || hunk.full()):
+			flushHunk()
+			patches = append(patches, FilePatch{OldPath: headerPath(line[4:])})
+			current = &patches[len(patches)-1]
+
+		case strings.HasPrefix(line, "+++ ") && current != nil && hunk == nil && current.NewPath == "":
+			current.NewPath = headerPath(line[4:])
+
+		case strings.HasPrefix(line, "@@"):
+			flushHunk()
+			h, err := parseHunkHeader(line)
+			if err != nil {
+				return nil, err
+			}
+			if current == nil {
+				patches = append(patches, FilePatch{})
+				current = &patches[len(patches)-1]
+			}
+			hunk = &h
+
+		case hunk != nil && !hunk.full():
+			if line == `\ No newline at end of file` {
+				continue
+			}
+			kind := byte(Context)
+			text := line
+			if line != "" {
+				kind, text = line[0], line[1:]
+			}
+			switch kind {
+			case Context, Delete, Insert:
+				hunk.Lines = append(hunk.Lines, Line{Kind: kind, Text: text})
+			default:
+				// Anything else ends the hunk
+				flushHunk()
+			}
+
+		case strings.HasPrefix(line, `\ No newline at end of file`):
+			// Marker following a complete hunk
+		}
+	}
+	flushHunk()
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read diff: %w", err)
+	}
+
+	var result []FilePatch
+	for _, p := range patches {
+		if len(p.Hunks) > 0 {
+			result = append(result, p)
+		}
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("diff contains no hunks")
+	}
+	return result, nil
+}
+
+// counts returns the number of old and new lines in the hunk
+func (h *Hunk) counts() (old, new int) {
+	for _, l := range h.Lines {
+		switch l.Kind {
+		case Context:
+			old++
+			new++
+		case Delete:
+			old++
+		case Insert:
+			new++
+		}
+	}
+	return old, new
+}
+
+// full reports whether the hunk already holds all lines its header
+// promised. Hunks without a line count are never full.
+func (h *Hunk) full() bool {
+	if h.OldCount < 0 {
+		return false
+	}
+	old, new := h.counts()
+	return old >= h.OldCount && new >= h.NewCount
+}
+
+// String renders the patch as a unified diff
+func (fp FilePatch) String() string {
+	var b strings.Builder
+	old, new := "a/"+fp.OldPath, "b/"+fp.NewPath
+	if fp.OldPath == "/dev/null" {
+		old = fp.OldPath
+	}
+	if fp.NewPath == "/dev/null" {
+		new = fp.NewPath
+	}
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", old, new)
+	for _, h := range fp.Hunks {
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", h.OldStart, h.OldCount, h.NewStart, h.NewCount)
+		for _, l := range h.Lines {
+			fmt.Fprintf(&b, "%c%s\n", l.Kind, l.Text)
+		}
+	}
+	return b.String()
+}
+
+// headerPath extracts the path from a ---/+++ header, dropping timestamps
+// and the a/ or b/ prefix
+func headerPath(s string) string {
+	if i := strings.IndexByte(s, '\t'); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && s[0] == '"' {
+		if p, err := strconv.Unquote(s); err == nil {
+			s = p
+		}
+	}
+	if s == "/dev/null" {
+		return s
+	}
+	if strings.HasPrefix(s, "a/") || strings.HasPrefix(s, "b/") {
+		s = s[2:]
+	}
+	return s
+}
+
+// parseHunkHeader parses "@@ -a,b +c,d @@". Counts default to 1 when
+// omitted, and a header without ranges (as some generated diffs emit) is
+// treated as covering an unknown region to be located by context.
+func parseHunkHeader(line string) (Hunk, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+		return Hunk{OldCount: -1, NewCount: -1}, nil
+	}
+
+	parse := func(spec string) (int, int, error) {
+		start, count := spec, "1"
+		if i := strings.IndexByte(spec, ','); i >= 0 {
+			start, count = spec[:i], spec[i+1:]
+		}
+		s, err := strconv.Atoi(start)
+		if err != nil {
+			return 0, 0, fmt.Errorf("malformed hunk header %q", line)
+		}
+		n, err := strconv.Atoi(count)
+		if err != nil {
+			return 0, 0, fmt.Errorf("malformed hunk header %q", line)
+		}
+		return s, n, nil
+	}
+
+	oldStart, oldCount, err := parse(fields[1][1:])
+	if err != nil {
+		return Hunk{}, err
+	}
+	newStart, newCount, err := parse(fields[2][1:])
+	if err != nil {
+		return Hunk{}, err
+	}
+	return Hunk{OldStart: oldStart, OldCount: oldCount, NewStart: newStart, NewCount: newCount}, nil
+}
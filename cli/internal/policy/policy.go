@@ -0,0 +1,125 @@
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"raincheck/internal/api"
+	"raincheck/internal/report"
+)
+
+// Policy describes the conditions a review must meet to pass
+type Policy struct {
+	// FailOn fails the review when any issue is at or above this severity
+	FailOn string
+	// MinScore is the lowest overall score any file may have
+	MinScore float64
+	// MinScores maps category keys (e.g. "security") to the lowest score
+	// any file may have in that category
+	MinScores map[string]float64
+}
+
+// Violation describes a single failed policy condition
+type Violation struct {
+	Path    string
+	Message string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s", v.Path, v.Message)
+}
+
+// CategoryKeys lists the valid keys for MinScores
+func CategoryKeys() []string {
+	return api.CategoryKeys()
+}
+
+// Validate checks that the policy refers to known severities and categories
+func (p Policy) Validate() error {
+	if p.FailOn != "" && !api.ValidSeverity(p.FailOn) {
+		return fmt.Errorf("invalid fail-on severity %q (expected INFO, WARNING or ERROR)", p.FailOn)
+	}
+
+	known := CategoryKeys()
+	for key := range p.MinScores {
+		found := false
+		for _, k := range known {
+			if k == key {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown category %q in minimum scores (expected one of %s)", key, strings.Join(known, ", "))
+		}
+	}
+	return nil
+}
+
+// Enabled reports whether the policy has any conditions
+func (p Policy) Enabled() bool {
+	if p.FailOn != "" || p.MinScore > 0 {
+		return true
+	}
+	for _, min := range p.MinScores {
+		if min > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate checks every file in the report against the policy and returns
+// the violations in report order
+func (p Policy) Evaluate(rep *report.Report) []Violation {
+	var violations []Violation
+
+	for _, f := range rep.Files {
+		resp := f.Analysis
+
+		if p.MinScore > 0 && resp.OverallScore < p.MinScore {
+			violations = append(violations, Violation{
+				Path:    f.Path,
+				Message: fmt.Sprintf("overall score %.1f is below the minimum of %.1f", resp.OverallScore, p.MinScore),
+			})
+		}
+
+		for _, c := range resp.Categories() {
+			if min := p.MinScores[c.Key]; min > 0 && c.Category.Score < min {
+				violations = append(violations, Violation{
+					Path:    f.Path,
+					Message: fmt.Sprintf("%s score %.1f is below the minimum of %.1f", c.Name, c.Category.Score, min),
+				})
+			}
+		}
+
+		if p.FailOn != "" {
+			threshold := api.SeverityRank(p.FailOn)
+			counts := make(map[string]int)
+			for _, c := range resp.Categories() {
+				for _, issue := range c.Category.Issues {
+					if api.SeverityRank(issue.Severity) >= threshold {
+						counts[api.NormalizeSeverity(issue.Severity)]++
+					}
+				}
+			}
+
+			severities := make([]string, 0, len(counts))
+			for severity := range counts {
+				severities = append(severities, severity)
+			}
+			sort.Slice(severities, func(i, j int) bool {
+				return api.SeverityRank(severities[i]) > api.SeverityRank(severities[j])
+			})
+			for _, severity := range severities {
+				violations = append(violations, Violation{
+					Path:    f.Path,
+					Message: fmt.Sprintf("%d %s issue(s) found (fail-on %s)", counts[severity], severity, api.NormalizeSeverity(p.FailOn)),
+				})
+			}
+		}
+	}
+
+	return violations
+}
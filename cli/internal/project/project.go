@@ -0,0 +1,307 @@
+package project
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the name of the per-repository configuration file
+const FileName = ".raincheck.yml"
+
+// Config holds the per-repository settings read from .raincheck.yml
+type Config struct {
+	// Server is the backend URL used for analysis
+	Server string `yaml:"server"`
+	// Format is the default report format
+	Format string `yaml:"format"`
+	// Include limits reviews to files matching at least one glob
+	Include []string `yaml:"include"`
+	// Exclude skips files and directories matching any glob
+	Exclude []string `yaml:"exclude"`
+	// Extensions lists extra file extensions to treat as code
+	Extensions []string `yaml:"extensions"`
+	// MaxFileSize skips files larger than this many bytes
+	MaxFileSize Size `yaml:"max_file_size"`
+	// MinSeverity hides issues below this severity (INFO, WARNING or ERROR)
+	MinSeverity string `yaml:"min_severity"`
+	// FailOn fails the review when an issue at or above this severity is found
+	FailOn string `yaml:"fail_on"`
+	// MinScore fails the review when a file's overall score is below it
+	MinScore float64 `yaml:"min_score"`
+	// MinScores fails the review when a file scores below the minimum for a
+	// category, keyed by security, performance, code_quality,
+	// maintainability or best_practices
+	MinScores map[string]float64 `yaml:"min_scores"`
+	// Baseline is the baseline file of known findings, relative to Root
+	Baseline string `yaml:"baseline"`
+	// Timeout bounds each analysis request including retries (e.g. "5m")
+	Timeout time.Duration `yaml:"timeout"`
+	// Retries is how often a failed request is retried; nil uses the default
+	Retries *int `yaml:"retries"`
+	// Engine selects who analyzes files: remote (the default), local or hybrid
+	Engine string `yaml:"engine"`
+	// Licenses is the policy raincheck licenses enforces
+	Licenses LicensePolicy `yaml:"licenses"`
+
+	// Root is the directory containing the config file, or the starting
+	// directory when no config file was found. Globs are relative to Root.
+	Root string `yaml:"-"`
+	// Path is the config file that was loaded, empty if none
+	Path string `yaml:"-"`
+}
+
+// LicensePolicy restricts the licenses of dependencies, vendored packages
+// and source files. Entries are SPDX identifiers and may use * wildcards.
+type LicensePolicy struct {
+	// Allow lists the permitted licenses; when empty every license that is
+	// not denied is permitted
+	Allow []string `yaml:"allow"`
+	// Deny lists the forbidden licenses, such as "GPL-*"
+	Deny []string `yaml:"deny"`
+	// Unknown is the severity of licenses that cannot be identified
+	// (default INFO)
+	Unknown string `yaml:"unknown"`
+}
+
+// Analysis engines
+const (
+	// EngineRemote analyzes files with the backend
+	EngineRemote = "remote"
+	// EngineLocal runs only the built-in rules and works offline
+	EngineLocal = "local"
+	// EngineHybrid merges the built-in rules into the backend results
+	EngineHybrid = "hybrid"
+)
+
+// ValidEngine reports whether engine is empty or names a known engine
+func ValidEngine(engine string) bool {
+	switch engine {
+	case "", EngineRemote, EngineLocal, EngineHybrid:
+		return true
+	}
+	return false
+}
+
+// Size is a byte count that accepts suffixes such as KB and MB in YAML
+type Size int64
+
+// UnmarshalYAML parses sizes like 1048576, "512KB" or "1MB"
+func (s *Size) UnmarshalYAML(value *yaml.Node) error {
+	n, err := ParseSize(value.Value)
+	if err != nil {
+		return err
+	}
+	*s = n
+	return nil
+}
+
+// ParseSize parses a byte count with an optional B, KB, MB or GB suffix
+func ParseSize(value string) (Size, error) {
+	v := strings.ToUpper(strings.TrimSpace(value))
+	if v == "" {
+		return 0, nil
+	}
+
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		factor int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(v, unit.suffix) {
+			v = strings.TrimSpace(strings.TrimSuffix(v, unit.suffix))
+			multiplier = unit.factor
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	return Size(n * multiplier), nil
+}
+
+// Find looks for .raincheck.yml in dir and each of its parents. When no file
+// is found an empty config rooted at dir is returned.
+func Find(dir string) (*Config, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", dir, err)
+	}
+
+	for current := abs; ; {
+		candidate := filepath.Join(current, FileName)
+		if _, err := os.Stat(candidate); err == nil {
+			return Load(candidate)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to check %s: %w", candidate, err)
+		}
+
+		parent := filepath.Dir(current)
+		if parent == current {
+			break
+		}
+		current = parent
+	}
+
+	return &Config{Root: abs}, nil
+}
+
+// Load reads the config file at path
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	cfg.Path = abs
+	cfg.Root = filepath.Dir(abs)
+
+	for i, ext := range cfg.Extensions {
+		cfg.Extensions[i] = normalizeExtension(ext)
+	}
+
+	return &cfg, nil
+}
+
+// Rel returns path relative to the config root in slash form, or "" when
+// path lies outside the root
+func (c *Config) Rel(p string) string {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return ""
+	}
+	rel, err := filepath.Rel(c.Root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// Excluded reports whether path matches one of the exclude globs
+func (c *Config) Excluded(p string) bool {
+	rel := c.Rel(p)
+	if rel == "" || rel == "." {
+		return false
+	}
+	return matchAny(c.Exclude, rel)
+}
+
+// Included reports whether path matches the include globs. Every path is
+// included when no include globs are configured.
+func (c *Config) Included(p string) bool {
+	if len(c.Include) == 0 {
+		return true
+	}
+	rel := c.Rel(p)
+	return rel != "" && matchAny(c.Include, rel)
+}
+
+// HasExtension reports whether path has one of the extra configured extensions
+func (c *Config) HasExtension(p string) bool {
+	ext := strings.ToLower(filepath.Ext(p))
+	if ext == "" {
+		return false
+	}
+	for _, e := range c.Extensions {
+		if e == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// AddExtensions appends extra extensions, normalizing them to ".ext" form
+func (c *Config) AddExtensions(exts []string) {
+	for _, ext := range exts {
+		c.Extensions = append(c.Extensions, normalizeExtension(ext))
+	}
+}
+
+// TooLarge reports whether a file of the given size exceeds MaxFileSize
+func (c *Config) TooLarge(size int64) bool {
+	return c.MaxFileSize > 0 && size > int64(c.MaxFileSize)
+}
+
+func normalizeExtension(ext string) string {
+	ext = strings.ToLower(strings.TrimSpace(ext))
+	if ext != "" && !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
+
+func matchAny(patterns []string, rel string) bool {
+	for _, p := range patterns {
+		if Match(p, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match reports whether the slash-separated path matches a glob pattern.
+// Patterns support the path.Match syntax plus "**", which matches any number
+// of directories. A pattern without a slash matches the base name of the path
+// at any depth, and a pattern that matches a directory matches everything
+// inside it.
+func Match(pattern, name string) bool {
+	pattern = strings.TrimPrefix(strings.TrimSuffix(filepath.ToSlash(pattern), "/"), "./")
+	if pattern == "" {
+		return false
+	}
+	if !strings.Contains(pattern, "/") {
+		pattern = "**/" + pattern
+	}
+
+	pat := strings.Split(strings.TrimPrefix(pattern, "/"), "/")
+	parts := strings.Split(name, "/")
+
+	// A match on any leading directory covers the files below it
+	for i := 1; i <= len(parts); i++ {
+		if matchSegments(pat, parts[:i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchSegments(pat, parts []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			rest := pat[1:]
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(rest, parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pat[0], parts[0]); err != nil || !ok {
+			return false
+		}
+		pat, parts = pat[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
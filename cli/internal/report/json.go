@@ -0,0 +1,81 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"raincheck/internal/api"
+)
+
+type jsonFile struct {
+	Path              string                `json:"path"`
+	Analysis          *api.AnalysisResponse `json:"analysis"`
+	Baselined         []Finding             `json:"baselined,omitempty"`
+	Suppressed        []SuppressedFinding   `json:"suppressed,omitempty"`
+	StaleSuppressions []Suppression         `json:"stale_suppressions,omitempty"`
+}
+
+type jsonReport struct {
+	GeneratedAt time.Time  `json:"generated_at"`
+	Dir         string     `json:"dir,omitempty"`
+	Baseline    string     `json:"baseline,omitempty"`
+	Summary     Summary    `json:"summary"`
+	Files       []jsonFile `json:"files"`
+	Failures    []Failure  `json:"failures,omitempty"`
+}
+
+func writeJSON(w io.Writer, r *Report) error {
+	out := jsonReport{
+		GeneratedAt: r.Generated.UTC(),
+		Dir:         r.Dir,
+		Baseline:    r.Baseline,
+		Summary:     r.Summary(),
+		Files:       make([]jsonFile, 0, len(r.Files)),
+		Failures:    r.Failures,
+	}
+	for _, f := range r.Files {
+		out.Files = append(out.Files, jsonFile{
+			Path:              f.Path,
+			Analysis:          f.Analysis,
+			Baselined:         f.Baselined,
+			Suppressed:        f.Suppressed,
+			StaleSuppressions: f.StaleSuppressions,
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(out)
+}
+
+// ReadJSON loads a report previously written in the json format
+func ReadJSON(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report: %w", err)
+	}
+
+	var in jsonReport
+	if err := json.Unmarshal(data, &in); err != nil {
+		return nil, fmt.Errorf("failed to parse report %s: %w", path, err)
+	}
+
+	r := &Report{Generated: in.GeneratedAt, Baseline: in.Baseline, Dir: in.Dir, Failures: in.Failures, Clean: in.Summary.CleanFiles}
+	for _, f := range in.Files {
+		if f.Analysis == nil {
+			continue
+		}
+		r.AddFile(File{
+			Path:              f.Path,
+			Analysis:          f.Analysis,
+			Baselined:         f.Baselined,
+			Suppressed:        f.Suppressed,
+			StaleSuppressions: f.StaleSuppressions,
+		})
+	}
+	return r, nil
+}
@@ -0,0 +1,152 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"raincheck/internal/api"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+	SystemOut string          `xml:"system-out,omitempty"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Error     *junitFailure `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// writeJUnit renders one test suite per file and one test case per category.
+// A category fails when it contains WARNING or ERROR issues; INFO, baselined
+// and suppressed issues are reported as test output only, and stale
+// suppressions as output of the file's suite. Files that could not
+// be analyzed get a single test case with an error.
+func writeJUnit(w io.Writer, r *Report) error {
+	out := junitTestSuites{Name: toolName}
+	timestamp := r.Generated.UTC().Format("2006-01-02T15:04:05")
+
+	for _, f := range r.Files {
+		suite := junitTestSuite{Name: f.Path, Timestamp: timestamp}
+
+		for _, c := range f.Analysis.Categories() {
+			tc := junitTestCase{
+				Name:      c.Name,
+				ClassName: f.Path,
+			}
+
+			var failing, info []string
+			worst := ""
+			for _, issue := range c.Category.Issues {
+				severity := api.NormalizeSeverity(issue.Severity)
+				line := fmt.Sprintf("[%s] %s: %s", severity, issue.Type, issue.Description)
+				if issue.Line > 0 {
+					line += fmt.Sprintf(" (line %d)", issue.Line)
+				}
+				if issue.Suggestion != "" {
+					line += "\n  Suggestion: " + issue.Suggestion
+				}
+
+				if severity == api.SeverityInfo {
+					info = append(info, line)
+					continue
+				}
+				failing = append(failing, line)
+				if worst != api.SeverityError {
+					worst = severity
+				}
+			}
+
+			if len(failing) > 0 {
+				tc.Failure = &junitFailure{
+					Message: fmt.Sprintf("%d issue(s) found in %s (score %.1f/10)", len(failing), c.Name, c.Category.Score),
+					Type:    worst,
+					Body:    strings.Join(failing, "\n"),
+				}
+				suite.Failures++
+			}
+			for _, b := range f.Baselined {
+				if b.Category == c.Name {
+					info = append(info, fmt.Sprintf("[BASELINED] [%s] %s: %s", api.NormalizeSeverity(b.Issue.Severity), b.Issue.Type, b.Issue.Description))
+				}
+			}
+			for _, s := range f.Suppressed {
+				if s.Category == c.Name {
+					line := fmt.Sprintf("[SUPPRESSED] [%s] %s: %s", api.NormalizeSeverity(s.Issue.Severity), s.Issue.Type, s.Issue.Description)
+					if s.Suppression.Reason != "" {
+						line += " (reason: " + s.Suppression.Reason + ")"
+					}
+					info = append(info, line)
+				}
+			}
+			if len(info) > 0 {
+				tc.SystemOut = strings.Join(info, "\n")
+			}
+
+			suite.Cases = append(suite.Cases, tc)
+			suite.Tests++
+		}
+
+		var stale []string
+		for _, s := range f.StaleSuppressions {
+			stale = append(stale, fmt.Sprintf("[STALE SUPPRESSION] %s matches no finding", s))
+		}
+		suite.SystemOut = strings.Join(stale, "\n")
+
+		out.Tests += suite.Tests
+		out.Failures += suite.Failures
+		out.Suites = append(out.Suites, suite)
+	}
+
+	for _, f := range r.Failures {
+		out.Suites = append(out.Suites, junitTestSuite{
+			Name:      f.Path,
+			Tests:     1,
+			Errors:    1,
+			Timestamp: timestamp,
+			Cases: []junitTestCase{{
+				Name:      "Analysis",
+				ClassName: f.Path,
+				Error:     &junitFailure{Message: "failed to analyze file", Type: "AnalysisError", Body: f.Error},
+			}},
+		})
+		out.Tests++
+		out.Errors++
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,129 @@
+package report
+
+import (
+	"fmt"
+	"io"
+
+	"raincheck/internal/api"
+)
+
+func writeMarkdown(w io.Writer, r *Report) error {
+	summary := r.Summary()
+
+	// Write header
+	fmt.Fprintf(w, "# Code Review Report\n\n")
+	fmt.Fprintf(w, "Generated on: %s\n\n", r.Generated.Format("2006-01-02 15:04:05"))
+
+	// Write summary
+	fmt.Fprintf(w, "## Summary\n\n")
+	fmt.Fprintf(w, "- Total Files Scanned: %d\n", summary.TotalFiles)
+	fmt.Fprintf(w, "- Files with Issues: %d\n", summary.FilesWithIssues)
+	fmt.Fprintf(w, "- Total Issues Found: %d\n", summary.TotalIssues)
+	if r.Baseline != "" {
+		fmt.Fprintf(w, "- Baselined Issues: %d (baseline: %s)\n", summary.BaselinedIssues, r.Baseline)
+	}
+	if summary.SuppressedIssues > 0 {
+		fmt.Fprintf(w, "- Suppressed Issues: %d\n", summary.SuppressedIssues)
+	}
+	if summary.StaleSuppressions > 0 {
+		fmt.Fprintf(w, "- Stale Suppressions: %d\n", summary.StaleSuppressions)
+	}
+	if summary.FailedFiles > 0 {
+		fmt.Fprintf(w, "- Files Not Analyzed: %d\n", summary.FailedFiles)
+	}
+	if summary.TotalFiles > 0 {
+		fmt.Fprintf(w, "- Average Score: %.1f/10\n", summary.AverageScore)
+	}
+	fmt.Fprintf(w, "\n")
+
+	// Write files that could not be analyzed
+	if len(r.Failures) > 0 {
+		fmt.Fprintf(w, "## Files Not Analyzed\n\n")
+		for _, f := range r.Failures {
+			fmt.Fprintf(w, "- ⚠️ %s: %s\n", f.Path, f.Error)
+		}
+		fmt.Fprintf(w, "\n")
+	}
+
+	// Write detailed reports
+	fmt.Fprintf(w, "## Detailed Reports\n\n")
+	for _, f := range r.Files {
+		fmt.Fprintf(w, "### %s\n\n", f.Path)
+		fmt.Fprintf(w, "**Overall Score:** %.1f/10\n\n", f.Analysis.OverallScore)
+		if f.Analysis.Partial {
+			fmt.Fprintf(w, "⚠️ Partial analysis: lines %s could not be analyzed\n\n", f.Analysis.UnanalyzedLines())
+		}
+
+		// Write each category
+		for _, c := range f.Analysis.Categories() {
+			fmt.Fprintf(w, "#### %s (Score: %.1f/10)\n\n", c.Name, c.Category.Score)
+
+			if len(c.Category.Issues) == 0 {
+				fmt.Fprintf(w, "✓ No issues found\n\n")
+				continue
+			}
+
+			for _, issue := range c.Category.Issues {
+				severity := api.NormalizeSeverity(issue.Severity)
+				marker := ""
+				if r.Baseline != "" {
+					marker = "🆕 "
+				}
+				fmt.Fprintf(w, "- %s%s %s [%s] %s\n", marker, severityIcon(severity), severity, issue.Type, issue.Description)
+				if issue.Line > 0 {
+					fmt.Fprintf(w, "  - Line: %d\n", issue.Line)
+				}
+				if issue.Suggestion != "" {
+					fmt.Fprintf(w, "  - Suggestion: %s\n", issue.Suggestion)
+				}
+				fmt.Fprintf(w, "\n")
+			}
+		}
+
+		// Write baselined issues
+		if len(f.Baselined) > 0 {
+			fmt.Fprintf(w, "#### Baselined Issues (%d)\n\n", len(f.Baselined))
+			for _, b := range f.Baselined {
+				severity := api.NormalizeSeverity(b.Issue.Severity)
+				fmt.Fprintf(w, "- %s %s [%s] %s (%s)\n", severityIcon(severity), severity, b.Issue.Type, b.Issue.Description, b.Category)
+			}
+			fmt.Fprintf(w, "\n")
+		}
+
+		// Write suppressed issues
+		if len(f.Suppressed) > 0 {
+			fmt.Fprintf(w, "#### Suppressed Issues (%d)\n\n", len(f.Suppressed))
+			for _, s := range f.Suppressed {
+				severity := api.NormalizeSeverity(s.Issue.Severity)
+				fmt.Fprintf(w, "- 🔕 %s [%s] %s (%s)\n", severity, s.Issue.Type, s.Issue.Description, s.Category)
+				fmt.Fprintf(w, "  - By: `%s`\n", s.Suppression)
+				if s.Suppression.Reason != "" {
+					fmt.Fprintf(w, "  - Reason: %s\n", s.Suppression.Reason)
+				}
+			}
+			fmt.Fprintf(w, "\n")
+		}
+
+		// Write suppressions that no longer match any issue
+		if len(f.StaleSuppressions) > 0 {
+			fmt.Fprintf(w, "#### Stale Suppressions (%d)\n\n", len(f.StaleSuppressions))
+			for _, s := range f.StaleSuppressions {
+				fmt.Fprintf(w, "- `%s` matches no finding\n", s)
+			}
+			fmt.Fprintf(w, "\n")
+		}
+
+		// Write suggestions
+		if len(f.Analysis.Suggestions) > 0 {
+			fmt.Fprintf(w, "#### General Suggestions\n\n")
+			for _, suggestion := range f.Analysis.Suggestions {
+				fmt.Fprintf(w, "- %s\n", suggestion)
+			}
+			fmt.Fprintf(w, "\n")
+		}
+
+		fmt.Fprintf(w, "---\n\n")
+	}
+
+	return nil
+}
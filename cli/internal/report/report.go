@@ -0,0 +1,209 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"raincheck/internal/api"
+)
+
+// Supported output formats
+const (
+	FormatText     = "text"
+	FormatMarkdown = "markdown"
+	FormatJSON     = "json"
+	FormatSARIF    = "sarif"
+	FormatJUnit    = "junit"
+)
+
+// Formats lists every supported output format
+var Formats = []string{FormatText, FormatMarkdown, FormatJSON, FormatSARIF, FormatJUnit}
+
+// Finding is an issue together with the category it was reported in
+type Finding struct {
+	Category string    `json:"category"`
+	Issue    api.Issue `json:"issue"`
+}
+
+// Suppression is an inline raincheck:ignore comment
+type Suppression struct {
+	Line      int      `json:"line"`      // line of the comment
+	Directive string   `json:"directive"` // ignore, ignore-next-line or ignore-file
+	Selectors []string `json:"selectors,omitempty"`
+	Reason    string   `json:"reason,omitempty"`
+}
+
+// SuppressedFinding is an issue silenced by an inline suppression
+type SuppressedFinding struct {
+	Finding
+	Suppression Suppression `json:"suppression"`
+}
+
+// File holds the analysis result for a single file. Issues matched by the
+// baseline are moved out of Analysis into Baselined, and issues silenced by
+// inline comments into Suppressed.
+type File struct {
+	Path       string
+	Analysis   *api.AnalysisResponse
+	Baselined  []Finding
+	Suppressed []SuppressedFinding
+	// StaleSuppressions are inline suppressions that matched no issue
+	StaleSuppressions []Suppression
+}
+
+// Failure is a file that could not be analyzed
+type Failure struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// Report is the result of a review run
+type Report struct {
+	Generated time.Time
+	Files     []File
+	// Failures lists files whose analysis failed
+	Failures []Failure
+	// Baseline is the baseline file applied to the run, empty if none
+	Baseline string
+	// Dir is the directory file paths are relative to
+	Dir string
+	// Clean counts files scanned without findings that scans leave out of
+	// Files to keep reports of large trees short
+	Clean int
+}
+
+// Summary holds aggregate statistics for a report. Issue counts only
+// include new issues; baselined and suppressed issues are counted separately.
+// TotalFiles includes the CleanFiles that scans do not list.
+type Summary struct {
+	TotalFiles        int     `json:"total_files"`
+	FilesWithIssues   int     `json:"files_with_issues"`
+	TotalIssues       int     `json:"total_issues"`
+	BaselinedIssues   int     `json:"baselined_issues,omitempty"`
+	SuppressedIssues  int     `json:"suppressed_issues,omitempty"`
+	StaleSuppressions int     `json:"stale_suppressions,omitempty"`
+	FailedFiles       int     `json:"failed_files,omitempty"`
+	CleanFiles        int     `json:"clean_files,omitempty"`
+	AverageScore      float64 `json:"average_score"`
+}
+
+// New creates an empty report stamped with the current time
+func New() *Report {
+	return &Report{Generated: time.Now()}
+}
+
+// AddFile appends a file result to the report
+func (r *Report) AddFile(f File) {
+	r.Files = append(r.Files, f)
+}
+
+// AddFailure records a file that could not be analyzed
+func (r *Report) AddFailure(path string, err error) {
+	r.Failures = append(r.Failures, Failure{Path: path, Error: err.Error()})
+}
+
+// String describes the suppression as written in the source
+func (s Suppression) String() string {
+	text := "raincheck:" + s.Directive
+	if len(s.Selectors) > 0 {
+		text += " " + strings.Join(s.Selectors, ", ")
+	}
+	return fmt.Sprintf("%s (line %d)", text, s.Line)
+}
+
+// Findings lists the issues of an analysis in report order. The position of
+// a finding in this list is the issue number shown to users.
+func Findings(analysis *api.AnalysisResponse) []Finding {
+	var findings []Finding
+	for _, c := range analysis.Categories() {
+		for _, issue := range c.Category.Issues {
+			findings = append(findings, Finding{Category: c.Name, Issue: issue})
+		}
+	}
+	return findings
+}
+
+// Summary computes aggregate statistics over all files in the report
+func (r *Report) Summary() Summary {
+	var s Summary
+	var totalScore float64
+	for _, f := range r.Files {
+		s.TotalFiles++
+		totalScore += f.Analysis.OverallScore
+		if n := f.Analysis.IssueCount(); n > 0 {
+			s.FilesWithIssues++
+			s.TotalIssues += n
+		}
+		s.BaselinedIssues += len(f.Baselined)
+		s.SuppressedIssues += len(f.Suppressed)
+		s.StaleSuppressions += len(f.StaleSuppressions)
+	}
+	s.FailedFiles = len(r.Failures)
+	// Files without findings have the top score
+	s.CleanFiles = r.Clean
+	s.TotalFiles += r.Clean
+	totalScore += float64(r.Clean * api.MaxScore)
+	if s.TotalFiles > 0 {
+		s.AverageScore = totalScore / float64(s.TotalFiles)
+	}
+	return s
+}
+
+// ValidFormat reports whether format is a supported output format
+func ValidFormat(format string) bool {
+	for _, f := range Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// Write renders the report in the given format
+func Write(w io.Writer, format string, r *Report) error {
+	switch format {
+	case FormatText:
+		return writeText(w, r)
+	case FormatMarkdown:
+		return writeMarkdown(w, r)
+	case FormatJSON:
+		return writeJSON(w, r)
+	case FormatSARIF:
+		return writeSARIF(w, r)
+	case FormatJUnit:
+		return writeJUnit(w, r)
+	}
+	return fmt.Errorf("unknown format %q (supported: %s)", format, strings.Join(Formats, ", "))
+}
+
+// WriteFile renders the report to path, or to stdout when path is "-"
+func WriteFile(path, format string, r *Report) error {
+	if path == "" || path == "-" {
+		return Write(os.Stdout, format, r)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer file.Close()
+
+	if err := Write(file, format, r); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// severityIcon returns the emoji used for a severity in human readable output
+func severityIcon(severity string) string {
+	switch api.NormalizeSeverity(severity) {
+	case api.SeverityError:
+		return "🔴"
+	case api.SeverityWarning:
+		return "🟡"
+	}
+	return "🔵"
+}
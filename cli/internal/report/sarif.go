@@ -0,0 +1,236 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"raincheck/internal/api"
+)
+
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+	toolName     = "raincheck"
+	toolURI      = "https://github.com/PythonHacker24/fortifyscan"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool        sarifTool         `json:"tool"`
+	Invocations []sarifInvocation `json:"invocations"`
+	Results     []sarifResult     `json:"results"`
+}
+
+type sarifInvocation struct {
+	ExecutionSuccessful bool `json:"executionSuccessful"`
+	// Notifications report files that could not be analyzed and stale
+	// inline suppressions
+	Notifications []sarifNotification `json:"toolExecutionNotifications,omitempty"`
+}
+
+type sarifNotification struct {
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	Name             string       `json:"name"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+	Properties       sarifProps   `json:"properties"`
+}
+
+type sarifProps struct {
+	Tags []string `json:"tags,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+	// BaselineState is "new" or "unchanged" when a baseline was applied
+	BaselineState string             `json:"baselineState,omitempty"`
+	Suppressions  []sarifSuppression `json:"suppressions,omitempty"`
+}
+
+type sarifSuppression struct {
+	Kind          string `json:"kind"`
+	Justification string `json:"justification,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// sarifLevel maps a raincheck severity onto a SARIF result level
+func sarifLevel(severity string) string {
+	switch api.NormalizeSeverity(severity) {
+	case api.SeverityError:
+		return "error"
+	case api.SeverityWarning:
+		return "warning"
+	}
+	return "note"
+}
+
+// ruleID builds a stable SARIF rule identifier from a category and issue type
+func ruleID(category, issueType string) string {
+	slug := func(s string) string {
+		var b strings.Builder
+		dash := false
+		for _, r := range strings.ToLower(s) {
+			if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+				b.WriteRune(r)
+				dash = false
+			} else if !dash && b.Len() > 0 {
+				b.WriteByte('-')
+				dash = true
+			}
+		}
+		return strings.TrimSuffix(b.String(), "-")
+	}
+	id := slug(issueType)
+	if id == "" {
+		id = "issue"
+	}
+	return slug(category) + "/" + id
+}
+
+func writeSARIF(w io.Writer, r *Report) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           toolName,
+			InformationURI: toolURI,
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+	ruleIndex := make(map[string]int)
+
+	addResult := func(path, category string, issue api.Issue, baselineState string) *sarifResult {
+		id := ruleID(category, issue.Type)
+		idx, ok := ruleIndex[id]
+		if !ok {
+			idx = len(run.Tool.Driver.Rules)
+			ruleIndex[id] = idx
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+				ID:               id,
+				Name:             issue.Type,
+				ShortDescription: sarifMessage{Text: issue.Type},
+				Properties:       sarifProps{Tags: []string{category}},
+			})
+		}
+
+		text := issue.Description
+		if issue.Suggestion != "" {
+			text += "\nSuggestion: " + issue.Suggestion
+		}
+
+		location := sarifLocation{PhysicalLocation: sarifPhysicalLocation{
+			ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(path)},
+		}}
+		if issue.Line > 0 {
+			location.PhysicalLocation.Region = &sarifRegion{StartLine: issue.Line}
+		}
+
+		run.Results = append(run.Results, sarifResult{
+			RuleID:        id,
+			RuleIndex:     idx,
+			Level:         sarifLevel(issue.Severity),
+			Message:       sarifMessage{Text: text},
+			Locations:     []sarifLocation{location},
+			BaselineState: baselineState,
+		})
+		return &run.Results[len(run.Results)-1]
+	}
+
+	newState := ""
+	if r.Baseline != "" {
+		newState = "new"
+	}
+
+	for _, f := range r.Files {
+		for _, c := range f.Analysis.Categories() {
+			for _, issue := range c.Category.Issues {
+				addResult(f.Path, c.Name, issue, newState)
+			}
+		}
+		for _, b := range f.Baselined {
+			addResult(f.Path, b.Category, b.Issue, "unchanged")
+		}
+		for _, s := range f.Suppressed {
+			res := addResult(f.Path, s.Category, s.Issue, newState)
+			res.Suppressions = []sarifSuppression{{Kind: "inSource", Justification: s.Suppression.Reason}}
+		}
+	}
+
+	invocation := sarifInvocation{ExecutionSuccessful: len(r.Failures) == 0}
+	for _, f := range r.Files {
+		for _, s := range f.StaleSuppressions {
+			invocation.Notifications = append(invocation.Notifications, sarifNotification{
+				Level:   "warning",
+				Message: sarifMessage{Text: "Stale suppression matches no finding: " + s.String()},
+				Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(f.Path)},
+					Region:           &sarifRegion{StartLine: s.Line},
+				}}},
+			})
+		}
+	}
+	for _, f := range r.Failures {
+		invocation.Notifications = append(invocation.Notifications, sarifNotification{
+			Level:   "error",
+			Message: sarifMessage{Text: "Failed to analyze file: " + f.Error},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(f.Path)},
+			}}},
+		})
+	}
+	run.Invocations = []sarifInvocation{invocation}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []sarifRun{run},
+	})
+}
@@ -0,0 +1,138 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"raincheck/internal/api"
+)
+
+// PrintFile writes the human readable report for a single file
+func PrintFile(w io.Writer, f File) {
+	resp := f.Analysis
+
+	fmt.Fprintf(w, "\n📊 Code Analysis Report for %s\n", f.Path)
+	fmt.Fprintln(w, strings.Repeat("=", 80))
+
+	// Overall Score
+	fmt.Fprintf(w, "\n🏆 Overall Score: %.1f/10\n", resp.OverallScore)
+	fmt.Fprintln(w, strings.Repeat("-", 30))
+	if resp.Partial {
+		fmt.Fprintf(w, "⚠️  Partial analysis: lines %s could not be analyzed\n", resp.UnanalyzedLines())
+	}
+
+	// Print each category
+	for _, c := range resp.Categories() {
+		printCategory(w, c.Name, *c.Category)
+	}
+
+	// Print baselined issues
+	if len(f.Baselined) > 0 {
+		fmt.Fprintf(w, "\n🗂  Baselined Issues (%d)\n", len(f.Baselined))
+		fmt.Fprintln(w, strings.Repeat("-", 20))
+		for _, b := range f.Baselined {
+			fmt.Fprintf(w, "%s [%s] %s (%s)\n", severityIcon(b.Issue.Severity), b.Issue.Type, b.Issue.Description, b.Category)
+		}
+	}
+
+	// Print suppressed issues and suppressions that no longer match
+	if len(f.Suppressed) > 0 {
+		fmt.Fprintf(w, "\n🔕 Suppressed Issues (%d)\n", len(f.Suppressed))
+		fmt.Fprintln(w, strings.Repeat("-", 20))
+		for _, s := range f.Suppressed {
+			fmt.Fprintf(w, "%s [%s] %s (%s)\n", severityIcon(s.Issue.Severity), s.Issue.Type, s.Issue.Description, s.Category)
+			fmt.Fprintf(w, "   By: %s\n", s.Suppression)
+			if s.Suppression.Reason != "" {
+				fmt.Fprintf(w, "   Reason: %s\n", s.Suppression.Reason)
+			}
+		}
+	}
+	if len(f.StaleSuppressions) > 0 {
+		fmt.Fprintf(w, "\n🧟 Stale Suppressions (%d)\n", len(f.StaleSuppressions))
+		fmt.Fprintln(w, strings.Repeat("-", 20))
+		for _, s := range f.StaleSuppressions {
+			fmt.Fprintf(w, "• %s matches no finding\n", s)
+		}
+	}
+
+	// Print suggestions
+	if len(resp.Suggestions) > 0 {
+		fmt.Fprintf(w, "\n💡 General Suggestions\n")
+		fmt.Fprintln(w, strings.Repeat("-", 20))
+		for _, suggestion := range resp.Suggestions {
+			fmt.Fprintf(w, "• %s\n", suggestion)
+		}
+	}
+
+	fmt.Fprintln(w, strings.Repeat("=", 80))
+}
+
+// PrintSummary writes the human readable summary of a review run
+func PrintSummary(w io.Writer, s Summary) {
+	fmt.Fprintf(w, "\n📈 Review Summary\n")
+	fmt.Fprintln(w, strings.Repeat("=", 80))
+	fmt.Fprintf(w, "Total Files Scanned: %d\n", s.TotalFiles)
+	fmt.Fprintf(w, "Files with Issues: %d\n", s.FilesWithIssues)
+	fmt.Fprintf(w, "Total Issues Found: %d\n", s.TotalIssues)
+	if s.BaselinedIssues > 0 {
+		fmt.Fprintf(w, "Baselined Issues: %d\n", s.BaselinedIssues)
+	}
+	if s.SuppressedIssues > 0 {
+		fmt.Fprintf(w, "Suppressed Issues: %d\n", s.SuppressedIssues)
+	}
+	if s.StaleSuppressions > 0 {
+		fmt.Fprintf(w, "Stale Suppressions: %d\n", s.StaleSuppressions)
+	}
+	if s.FailedFiles > 0 {
+		fmt.Fprintf(w, "Files Not Analyzed: %d\n", s.FailedFiles)
+	}
+	if s.TotalFiles > 0 {
+		fmt.Fprintf(w, "Average Score: %.1f/10\n", s.AverageScore)
+	}
+	fmt.Fprintln(w, strings.Repeat("=", 80))
+}
+
+// PrintFailures lists the files that could not be analyzed
+func PrintFailures(w io.Writer, failures []Failure) {
+	if len(failures) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n⚠️  Files Not Analyzed (%d)\n", len(failures))
+	fmt.Fprintln(w, strings.Repeat("-", 20))
+	for _, f := range failures {
+		fmt.Fprintf(w, "• %s: %s\n", f.Path, f.Error)
+	}
+}
+
+func printCategory(w io.Writer, name string, category api.Category) {
+	fmt.Fprintf(w, "\n%s (Score: %.1f/10)\n", strings.ToUpper(name), category.Score)
+	fmt.Fprintln(w, strings.Repeat("-", len(name)+15))
+
+	if len(category.Issues) == 0 {
+		fmt.Fprintln(w, "✓ No issues found")
+		return
+	}
+
+	for _, issue := range category.Issues {
+		fmt.Fprintf(w, "%s [%s] %s\n", severityIcon(issue.Severity), issue.Type, issue.Description)
+		if issue.Line > 0 {
+			fmt.Fprintf(w, "   Line: %d\n", issue.Line)
+		}
+		if issue.Suggestion != "" {
+			fmt.Fprintf(w, "   💡 Suggestion: %s\n", issue.Suggestion)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+func writeText(w io.Writer, r *Report) error {
+	for _, f := range r.Files {
+		PrintFile(w, f)
+	}
+	if len(r.Files)+len(r.Failures) != 1 {
+		PrintSummary(w, r.Summary())
+	}
+	PrintFailures(w, r.Failures)
+	return nil
+}
@@ -0,0 +1,109 @@
+package review
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"raincheck/internal/api"
+)
+
+// Target is a file scheduled for review
+type Target struct {
+	Path    string // path relative to the review root, used in reports
+	AbsPath string // path used to read the file
+	// Load reads the content instead of AbsPath when set, e.g. to review
+	// the version of a file in a git commit
+	Load func(ctx context.Context) ([]byte, error)
+}
+
+// Result is the outcome of reviewing a single target
+type Result struct {
+	Target
+	Content  []byte
+	Analysis *api.AnalysisResponse
+	Err      error
+}
+
+// Analyzer analyzes the content of a single file
+type Analyzer func(ctx context.Context, target Target, content []byte) (*api.AnalysisResponse, error)
+
+// Run reviews targets with at most concurrency analyses in flight. Results are
+// returned in target order, and emit (if non-nil) is called once per target in
+// that same order as soon as all earlier targets have finished. Once ctx is
+// cancelled no new analyses are started and Run returns ctx.Err().
+func Run(ctx context.Context, targets []Target, concurrency int, analyze Analyzer, emit func(Result)) ([]Result, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make([]Result, len(targets))
+	done := make([]chan struct{}, len(targets))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = analyzeTarget(ctx, targets[i], analyze)
+				close(done[i])
+			}
+		}()
+	}
+
+	// Feed jobs until everything is queued or the run is cancelled
+	go func() {
+		defer close(jobs)
+		for i := range targets {
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Emit results in order while workers run
+	for i := range targets {
+		select {
+		case <-done[i]:
+			if emit != nil {
+				emit(results[i])
+			}
+		case <-ctx.Done():
+			wg.Wait()
+			return results, ctx.Err()
+		}
+	}
+
+	wg.Wait()
+	return results, nil
+}
+
+func analyzeTarget(ctx context.Context, target Target, analyze Analyzer) Result {
+	result := Result{Target: target}
+
+	var (
+		content []byte
+		err     error
+	)
+	if target.Load != nil {
+		content, err = target.Load(ctx)
+	} else {
+		content, err = os.ReadFile(target.AbsPath)
+	}
+	if err != nil {
+		result.Err = fmt.Errorf("failed to read file: %w", err)
+		return result
+	}
+
+	result.Content = content
+	result.Analysis, result.Err = analyze(ctx, target, content)
+	return result
+}
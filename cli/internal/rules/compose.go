@@ -0,0 +1,162 @@
+package rules
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"raincheck/internal/api"
+)
+
+// databasePorts are the default ports of data stores that should not be
+// reachable from outside the host
+var databasePorts = map[string]string{
+	"3306":  "MySQL",
+	"5432":  "PostgreSQL",
+	"6379":  "Redis",
+	"9200":  "Elasticsearch",
+	"11211": "Memcached",
+	"27017": "MongoDB",
+}
+
+// analyzeCompose checks the services of a Docker Compose file
+func analyzeCompose(content []byte) []finding {
+	c := &iacChecker{}
+	for _, doc := range parseYAML(content) {
+		services := field(doc, "services")
+		if services == nil || services.Kind != yaml.MappingNode {
+			continue
+		}
+		for i := 0; i+1 < len(services.Content); i += 2 {
+			c.checkService(services.Content[i].Value, services.Content[i].Line, services.Content[i+1])
+		}
+	}
+	return c.findings
+}
+
+// checkService checks one Compose service
+func (c *iacChecker) checkService(name string, line int, svc *yaml.Node) {
+	if svc.Kind != yaml.MappingNode {
+		return
+	}
+	// The image of a built service is the name given to the build result
+	if field(svc, "build") == nil {
+		c.checkImage(keyLine(svc, "image"), scalar(field(svc, "image")))
+		if field(svc, "healthcheck") == nil {
+			c.report(line, bestPractices, api.SeverityInfo, "Missing Health Check",
+				"Service "+name+" has no healthcheck, so depends_on cannot wait for it to be ready",
+				"Add a healthcheck that probes the service")
+		}
+	}
+
+	if isTrueNode(field(svc, "privileged")) {
+		c.report(keyLine(svc, "privileged"), security, api.SeverityError, "Privileged Container",
+			"Service "+name+" runs privileged, with full access to the host's devices and kernel",
+			"Remove privileged and grant only the capabilities the service needs with cap_add")
+	}
+	if user, _, _ := strings.Cut(scalar(field(svc, "user")), ":"); user == "root" || user == "0" {
+		c.report(keyLine(svc, "user"), security, api.SeverityWarning, "Runs As Root",
+			"Service "+name+" runs as root",
+			"Run the service as an unprivileged user")
+	}
+	for _, key := range []string{"network_mode", "pid", "ipc"} {
+		if scalar(field(svc, key)) == "host" {
+			c.report(keyLine(svc, key), security, api.SeverityWarning, "Host Namespace",
+				"Service "+name+" shares the host's "+strings.TrimSuffix(key, "_mode")+" namespace",
+				"Remove "+key+": host and expose only what the service needs")
+		}
+	}
+	if caps := field(svc, "cap_add"); caps != nil {
+		for _, capability := range caps.Content {
+			if v := strings.ToUpper(strings.TrimPrefix(capability.Value, "CAP_")); v == "ALL" || v == "SYS_ADMIN" {
+				c.report(capability.Line, security, api.SeverityWarning, "Dangerous Capability",
+					"Service "+name+" is granted "+capability.Value+", which is close to running privileged",
+					"Grant only the specific capabilities the service needs")
+			}
+		}
+	}
+
+	if volumes := field(svc, "volumes"); volumes != nil {
+		for _, v := range volumes.Content {
+			source := scalar(v)
+			if v.Kind == yaml.MappingNode {
+				source = scalar(field(v, "source"))
+			} else {
+				source, _, _ = strings.Cut(source, ":")
+			}
+			if strings.HasSuffix(source, "docker.sock") {
+				c.report(v.Line, security, api.SeverityError, "Docker Socket Mounted",
+					"Service "+name+" mounts the Docker socket, which gives it root access to the host",
+					"Do not mount the socket; use a socket proxy limited to the required API calls if needed")
+			}
+		}
+	}
+
+	if ports := field(svc, "ports"); ports != nil {
+		for _, p := range ports.Content {
+			c.checkPort(name, p)
+		}
+	}
+
+	env := field(svc, "environment")
+	switch {
+	case env == nil:
+	case env.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(env.Content); i += 2 {
+			c.checkEnv(env.Content[i].Line, env.Content[i].Value, scalar(env.Content[i+1]), true)
+		}
+	case env.Kind == yaml.SequenceNode:
+		for _, item := range env.Content {
+			key, value, _ := strings.Cut(item.Value, "=")
+			c.checkEnv(item.Line, key, value, true)
+		}
+	}
+
+	for _, key := range []string{"command", "entrypoint"} {
+		cmd := field(svc, key)
+		if cmd == nil {
+			continue
+		}
+		text := cmd.Value
+		if cmd.Kind == yaml.SequenceNode {
+			var args []string
+			for _, arg := range cmd.Content {
+				args = append(args, arg.Value)
+			}
+			text = strings.Join(args, " ")
+		}
+		if secretFlag.MatchString(text) {
+			c.report(cmd.Line, security, api.SeverityWarning, "Secret On Command Line",
+				"Service "+name+" passes a secret as a command line argument, which any process on the host can read with ps",
+				"Pass the secret in a file, e.g. a config file mounted from Compose secrets")
+		}
+	}
+}
+
+// checkPort reports data store ports published on every host interface
+func (c *iacChecker) checkPort(service string, p *yaml.Node) {
+	var hostIP, target string
+	if p.Kind == yaml.MappingNode {
+		hostIP, target = scalar(field(p, "host_ip")), scalar(field(p, "target"))
+		if scalar(field(p, "published")) == "" {
+			return
+		}
+	} else {
+		parts := strings.Split(strings.TrimSuffix(strings.TrimSuffix(p.Value, "/tcp"), "/udp"), ":")
+		if len(parts) < 2 {
+			// Only a container port, published on a random host port
+			return
+		}
+		target = parts[len(parts)-1]
+		if len(parts) == 3 {
+			hostIP = parts[0]
+		}
+	}
+	db, ok := databasePorts[target]
+	if !ok || (hostIP != "" && hostIP != "0.0.0.0" && hostIP != "::") {
+		return
+	}
+	c.report(p.Line, security, api.SeverityWarning, "Exposed Database Port",
+		"Service "+service+" publishes the "+db+" port on every interface of the host",
+		"Drop the port mapping so only other services can connect, or bind it to 127.0.0.1")
+}
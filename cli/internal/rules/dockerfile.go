@@ -0,0 +1,156 @@
+package rules
+
+import (
+	"regexp"
+	"strings"
+
+	"raincheck/internal/api"
+)
+
+// instruction is a Dockerfile instruction with its continuation lines joined
+type instruction struct {
+	line    int
+	keyword string
+	args    string
+}
+
+var (
+	pipeToShell   = regexp.MustCompile(`\b(curl|wget)\b[^|;&]*\|\s*(sudo\s+)?(sh|bash|zsh|ash)\b`)
+	worldWritable = regexp.MustCompile(`\bchmod\s+(-R\s+)?0?777\b`)
+	// envPair matches the name=value pairs of ENV and ARG
+	envPair = regexp.MustCompile(`([\w.-]+)=("[^"]*"|'[^']*'|\S*)`)
+)
+
+// parseDockerfile splits a Dockerfile into instructions, skipping comments
+func parseDockerfile(content []byte) []instruction {
+	var (
+		instructions []instruction
+		current      *instruction
+	)
+	lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "#") || (trimmed == "" && current == nil) {
+			continue
+		}
+		continued := strings.HasSuffix(trimmed, "\\")
+		trimmed = strings.TrimSuffix(trimmed, "\\")
+		if current == nil {
+			keyword, args, _ := strings.Cut(trimmed, " ")
+			instructions = append(instructions, instruction{line: i + 1, keyword: strings.ToUpper(keyword), args: strings.TrimSpace(args)})
+			current = &instructions[len(instructions)-1]
+		} else {
+			current.args += " " + trimmed
+		}
+		if !continued {
+			current = nil
+		}
+	}
+	return instructions
+}
+
+// analyzeDockerfile checks the final image for running as root, a missing
+// health check and secrets, and every stage for unpinned base images and
+// unsafe commands
+func analyzeDockerfile(content []byte) []finding {
+	c := &iacChecker{}
+	stages := make(map[string]bool)
+	var (
+		lastFrom, userLine int
+		user               string
+		healthcheck        bool
+	)
+	for _, in := range parseDockerfile(content) {
+		switch in.keyword {
+		case "FROM":
+			fields := strings.Fields(in.args)
+			// Skip options such as --platform
+			for len(fields) > 0 && strings.HasPrefix(fields[0], "--") {
+				fields = fields[1:]
+			}
+			if len(fields) == 0 {
+				continue
+			}
+			if !stages[strings.ToLower(fields[0])] {
+				c.checkImage(in.line, fields[0])
+			}
+			if len(fields) >= 3 && strings.EqualFold(fields[1], "AS") {
+				stages[strings.ToLower(fields[2])] = true
+			}
+			lastFrom, user, userLine, healthcheck = in.line, "", 0, false
+		case "USER":
+			user, userLine = strings.TrimSpace(in.args), in.line
+		case "HEALTHCHECK":
+			healthcheck = true
+		case "ENV", "ARG":
+			for _, p := range dockerPairs(in) {
+				if !secretEnvName.MatchString(p[0]) {
+					continue
+				}
+				if in.keyword == "ARG" && p[1] == "" {
+					c.report(in.line, security, api.SeverityWarning, "Secret In Build Argument",
+						"Build argument "+p[0]+" is recorded in the image history, where anyone with the image can read it",
+						"Pass secrets to RUN with BuildKit secret mounts: RUN --mount=type=secret,id=...")
+					continue
+				}
+				c.checkEnv(in.line, p[0], strings.Trim(p[1], `"'`), false)
+			}
+		case "RUN":
+			if pipeToShell.MatchString(in.args) {
+				c.report(in.line, security, api.SeverityWarning, "Remote Script Execution",
+					"A script is downloaded and piped to a shell without checking its integrity",
+					"Download the script, verify its checksum or signature, then run it")
+			}
+			if worldWritable.MatchString(in.args) {
+				c.report(in.line, security, api.SeverityWarning, "World-Writable Files",
+					"chmod 777 lets any user in the container modify the files",
+					"Grant write access only to the user that needs it")
+			}
+		case "ADD":
+			if strings.Contains(in.args, "://") && !strings.Contains(in.args, "--checksum") {
+				c.report(in.line, bestPractices, api.SeverityInfo, "Unverified Download",
+					"ADD downloads a remote file without verifying its contents",
+					"Use ADD --checksum=sha256:... or download and verify the file in a RUN step")
+			}
+		case "MAINTAINER":
+			c.report(in.line, maintainability, api.SeverityInfo, "Deprecated Instruction",
+				"MAINTAINER is deprecated",
+				"Use LABEL org.opencontainers.image.authors=... instead")
+		}
+	}
+
+	if lastFrom == 0 {
+		return c.findings
+	}
+	if name, _, _ := strings.Cut(user, ":"); user == "" || name == "root" || name == "0" {
+		line := userLine
+		description := "The container runs as root because the final stage sets no USER"
+		if line == 0 {
+			line = lastFrom
+		} else {
+			description = "The container runs as root"
+		}
+		c.report(line, security, api.SeverityWarning, "Runs As Root", description,
+			"Create an unprivileged user and switch to it with USER before the entrypoint")
+	}
+	if !healthcheck {
+		c.report(lastFrom, bestPractices, api.SeverityInfo, "Missing Health Check",
+			"The image defines no HEALTHCHECK, so failures that leave the process running go unnoticed",
+			"Add a HEALTHCHECK that probes the service, or set one in the orchestrator")
+	}
+	return c.findings
+}
+
+// dockerPairs returns the name and value pairs of an ENV or ARG instruction,
+// which take either name=value pairs or, for ENV, "name value"
+func dockerPairs(in instruction) [][2]string {
+	if !strings.Contains(strings.Fields(in.args + " x")[0], "=") {
+		name, value, _ := strings.Cut(in.args, " ")
+		return [][2]string{{name, strings.TrimSpace(value)}}
+	}
+	var pairs [][2]string
+	for _, m := range envPair.FindAllStringSubmatch(in.args, -1) {
+		pairs = append(pairs, [2]string{m[1], m[2]})
+	}
+	return pairs
+}
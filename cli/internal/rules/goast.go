@@ -0,0 +1,326 @@
+package rules
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"raincheck/internal/api"
+)
+
+var (
+	secretIdent = regexp.MustCompile(`^` + secretName + `$`)
+	sqlKeyword  = regexp.MustCompile(`(?i)\b(select|insert\s+into|update|delete\s+from|where)\b`)
+)
+
+// sqlMethods maps database/sql style methods to the index of their query
+// argument
+var sqlMethods = map[string]int{
+	"Query":           0,
+	"QueryRow":        0,
+	"Exec":            0,
+	"Prepare":         0,
+	"QueryContext":    1,
+	"QueryRowContext": 1,
+	"ExecContext":     1,
+	"PrepareContext":  1,
+}
+
+var shells = map[string]bool{"sh": true, "bash": true, "zsh": true, "cmd": true, "cmd.exe": true, "powershell": true}
+
+// goChecker walks a parsed Go file keeping track of the enclosing nodes
+type goChecker struct {
+	fset     *token.FileSet
+	imports  map[string]string // local name to import path
+	stack    []ast.Node
+	findings []finding
+}
+
+// analyzeGo runs the go/ast rules. Files that do not parse are left to the
+// regex rules.
+func analyzeGo(path string, content []byte) []finding {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, content, parser.SkipObjectResolution)
+	if err != nil {
+		return nil
+	}
+
+	c := &goChecker{fset: fset, imports: make(map[string]string)}
+	for _, imp := range file.Imports {
+		importPath, _ := strconv.Unquote(imp.Path.Value)
+		name := importPath[strings.LastIndex(importPath, "/")+1:]
+		if importPath == "math/rand/v2" {
+			name = "rand"
+		}
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		c.imports[name] = importPath
+		c.checkImport(imp, importPath)
+	}
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		if n == nil {
+			c.stack = c.stack[:len(c.stack)-1]
+			return true
+		}
+		c.check(n)
+		c.stack = append(c.stack, n)
+		return true
+	})
+	return c.findings
+}
+
+func (c *goChecker) report(pos token.Pos, category, severity, issueType, description, suggestion string) {
+	c.findings = append(c.findings, finding{category: category, issue: api.Issue{
+		Severity:    severity,
+		Type:        issueType,
+		Description: description,
+		Line:        c.fset.Position(pos).Line,
+		Suggestion:  suggestion,
+	}})
+}
+
+func (c *goChecker) checkImport(imp *ast.ImportSpec, importPath string) {
+	switch importPath {
+	case "crypto/md5", "crypto/sha1":
+		c.report(imp.Pos(), security, api.SeverityWarning, "Weak Hash",
+			importPath+" is broken for security purposes",
+			"Use crypto/sha256 or better, or a password hash such as bcrypt or argon2")
+	case "crypto/des", "crypto/rc4":
+		c.report(imp.Pos(), security, api.SeverityWarning, "Weak Cipher",
+			importPath+" is not a secure cipher",
+			"Use crypto/aes with GCM or golang.org/x/crypto/chacha20poly1305")
+	}
+}
+
+func (c *goChecker) check(n ast.Node) {
+	switch n := n.(type) {
+	case *ast.CallExpr:
+		c.checkSQL(n)
+		c.checkCommand(n)
+		c.checkRand(n)
+	case *ast.KeyValueExpr:
+		if key, ok := n.Key.(*ast.Ident); ok && key.Name == "InsecureSkipVerify" && isTrue(n.Value) {
+			c.report(n.Pos(), security, api.SeverityError, "TLS Verification Disabled",
+				"InsecureSkipVerify accepts any certificate, allowing man-in-the-middle attacks",
+				"Keep verification on and add the required CA to RootCAs")
+		}
+	case *ast.DeferStmt:
+		if c.inLoop() {
+			c.report(n.Pos(), performance, api.SeverityWarning, "Defer In Loop",
+				"Deferred calls run when the function returns, so resources pile up until the loop ends",
+				"Move the loop body into a function or release the resource explicitly")
+		}
+	}
+}
+
+// checkSQL reports queries built by concatenation or fmt.Sprintf
+func (c *goChecker) checkSQL(call *ast.CallExpr) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return
+	}
+	idx, ok := sqlMethods[sel.Sel.Name]
+	if !ok || len(call.Args) <= idx {
+		return
+	}
+
+	query := ast.Unparen(call.Args[idx])
+	dynamic := false
+	switch q := query.(type) {
+	case *ast.BinaryExpr:
+		dynamic = q.Op == token.ADD && !isConstant(q) && hasSQLLiteral(q)
+	case *ast.CallExpr:
+		dynamic = c.isPkgCall(q, "fmt", "Sprintf") && len(q.Args) > 1 && hasSQLLiteral(q.Args[0])
+	}
+	if dynamic {
+		c.report(query.Pos(), security, api.SeverityError, "SQL Injection",
+			"The query passed to "+sel.Sel.Name+" is built from dynamic values",
+			"Use placeholders such as ? or $1 and pass the values as arguments")
+	}
+}
+
+// checkCommand reports exec.Command calls whose program, or shell script,
+// is not a constant
+func (c *goChecker) checkCommand(call *ast.CallExpr) {
+	var args []ast.Expr
+	switch {
+	case c.isPkgCall(call, "os/exec", "Command"):
+		args = call.Args
+	case c.isPkgCall(call, "os/exec", "CommandContext") && len(call.Args) > 0:
+		args = call.Args[1:]
+	default:
+		return
+	}
+	if len(args) == 0 {
+		return
+	}
+
+	name, constant := stringValue(args[0])
+	if !constant {
+		c.report(call.Pos(), security, api.SeverityWarning, "Command Injection",
+			"The program run by exec.Command is chosen at run time",
+			"Run a fixed program and validate any input passed as arguments")
+		return
+	}
+
+	if shells[strings.ToLower(name)] {
+		for i := 1; i < len(args)-1; i++ {
+			if flag, _ := stringValue(args[i]); flag == "-c" || strings.EqualFold(flag, "/c") || strings.EqualFold(flag, "-Command") {
+				if !isConstant(args[i+1]) {
+					c.report(call.Pos(), security, api.SeverityError, "Command Injection",
+						"A shell script built at run time is passed to "+name,
+						"Call the program directly with separate arguments instead of going through a shell")
+				}
+				return
+			}
+		}
+	}
+
+	for _, arg := range args[1:] {
+		switch a := ast.Unparen(arg).(type) {
+		case *ast.BinaryExpr:
+			if isConstant(a) {
+				continue
+			}
+		case *ast.CallExpr:
+			if !c.isPkgCall(a, "fmt", "Sprintf") {
+				continue
+			}
+		default:
+			continue
+		}
+		c.report(arg.Pos(), security, api.SeverityWarning, "Command Injection",
+			"A command argument is built from dynamic values",
+			"Validate the input and pass each value as its own argument")
+		return
+	}
+}
+
+// checkRand reports math/rand used for values that look like secrets
+func (c *goChecker) checkRand(call *ast.CallExpr) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	if !ok || (c.imports[pkg.Name] != "math/rand" && c.imports[pkg.Name] != "math/rand/v2") {
+		return
+	}
+	if name := c.secretContext(); name != "" {
+		c.report(call.Pos(), security, api.SeverityError, "Insecure Randomness",
+			"math/rand is predictable but is used to generate "+name,
+			"Use crypto/rand for secrets, tokens and keys")
+	}
+}
+
+// secretContext returns the name of the enclosing assignment target or
+// function when it looks like it holds a secret
+func (c *goChecker) secretContext() string {
+	for i := len(c.stack) - 1; i >= 0; i-- {
+		switch n := c.stack[i].(type) {
+		case *ast.AssignStmt:
+			for _, lhs := range n.Lhs {
+				if name := exprName(lhs); secretIdent.MatchString(name) {
+					return name
+				}
+			}
+		case *ast.ValueSpec:
+			for _, ident := range n.Names {
+				if secretIdent.MatchString(ident.Name) {
+					return ident.Name
+				}
+			}
+		case *ast.KeyValueExpr:
+			if name := exprName(n.Key); secretIdent.MatchString(name) {
+				return name
+			}
+		case *ast.FuncDecl:
+			if secretIdent.MatchString(n.Name.Name) {
+				return n.Name.Name
+			}
+			return ""
+		case *ast.FuncLit:
+			return ""
+		}
+	}
+	return ""
+}
+
+// inLoop reports whether the current node is inside a loop of the same
+// function
+func (c *goChecker) inLoop() bool {
+	for i := len(c.stack) - 1; i >= 0; i-- {
+		switch c.stack[i].(type) {
+		case *ast.ForStmt, *ast.RangeStmt:
+			return true
+		case *ast.FuncLit, *ast.FuncDecl:
+			return false
+		}
+	}
+	return false
+}
+
+// isPkgCall reports whether call calls function name of the package imported
+// from importPath
+func (c *goChecker) isPkgCall(call *ast.CallExpr, importPath, name string) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != name {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	return ok && c.imports[pkg.Name] == importPath
+}
+
+// exprName returns the identifier or field name of an assignment target
+func exprName(e ast.Expr) string {
+	switch e := e.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.SelectorExpr:
+		return e.Sel.Name
+	}
+	return ""
+}
+
+// isConstant reports whether e is built only from literals
+func isConstant(e ast.Expr) bool {
+	switch e := ast.Unparen(e).(type) {
+	case *ast.BasicLit:
+		return true
+	case *ast.BinaryExpr:
+		return isConstant(e.X) && isConstant(e.Y)
+	}
+	return false
+}
+
+// hasSQLLiteral reports whether e contains a string literal with SQL keywords
+func hasSQLLiteral(e ast.Expr) bool {
+	found := false
+	ast.Inspect(e, func(n ast.Node) bool {
+		if lit, ok := n.(*ast.BasicLit); ok && lit.Kind == token.STRING && sqlKeyword.MatchString(lit.Value) {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// stringValue returns the value of a string literal
+func stringValue(e ast.Expr) (string, bool) {
+	lit, ok := ast.Unparen(e).(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
+
+func isTrue(e ast.Expr) bool {
+	ident, ok := e.(*ast.Ident)
+	return ok && ident.Name == "true"
+}
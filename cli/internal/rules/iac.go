@@ -0,0 +1,230 @@
+package rules
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"raincheck/internal/api"
+)
+
+// Infrastructure-as-code formats the rules distinguish
+const (
+	langDockerfile = "dockerfile"
+	langCompose    = "compose"
+	langKubernetes = "kubernetes"
+	langTerraform  = "terraform"
+)
+
+var (
+	dockerfileName = regexp.MustCompile(`(?i)^((docker|container)file([.-].*)?|.*\.(docker|container)file)$`)
+	composeName    = regexp.MustCompile(`(?i)^(docker-)?compose([.-].*)?\.ya?ml$`)
+)
+
+// IsIaC reports whether path may be an infrastructure-as-code file by its
+// name: a Dockerfile, a Compose file, a Terraform file or any YAML file,
+// which IaCLanguage tells apart from other YAML by its content
+func IsIaC(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".tf", ".yaml", ".yml":
+		return true
+	}
+	// Source files such as dockerfile.go are not Dockerfiles
+	return dockerfileName.MatchString(filepath.Base(path)) && languageOf(path) == ""
+}
+
+// IaCLanguage returns the infrastructure-as-code format of a file, or ""
+// when it is none of them. YAML files are Kubernetes manifests when a
+// document has both apiVersion and kind.
+func IaCLanguage(path string, content []byte) string {
+	name := filepath.Base(path)
+	switch {
+	case !IsIaC(path):
+		return ""
+	case dockerfileName.MatchString(name):
+		return langDockerfile
+	case composeName.MatchString(name):
+		return langCompose
+	case strings.EqualFold(filepath.Ext(name), ".tf"):
+		return langTerraform
+	case isKubernetes(content):
+		return langKubernetes
+	}
+	return ""
+}
+
+// analyzeIaC runs the rules of an infrastructure-as-code format
+func analyzeIaC(lang string, content []byte) []finding {
+	switch lang {
+	case langDockerfile:
+		return analyzeDockerfile(content)
+	case langCompose:
+		return analyzeCompose(content)
+	case langKubernetes:
+		return analyzeKubernetes(content)
+	case langTerraform:
+		return analyzeTerraform(content)
+	}
+	return nil
+}
+
+// iacChecker collects the findings of the infrastructure rules
+type iacChecker struct {
+	findings []finding
+}
+
+func (c *iacChecker) report(line int, category, severity, issueType, description, suggestion string) {
+	c.findings = append(c.findings, finding{category: category, issue: api.Issue{
+		Severity:    severity,
+		Type:        issueType,
+		Description: description,
+		Line:        line,
+		Suggestion:  suggestion,
+	}})
+}
+
+// checkImage reports container images that are not pinned to a version
+func (c *iacChecker) checkImage(line int, image string) {
+	image = strings.TrimSpace(image)
+	// Images chosen by variables are resolved elsewhere
+	if image == "" || image == "scratch" || strings.Contains(image, "$") || strings.Contains(image, "{{") {
+		return
+	}
+	if strings.Contains(image, "@") {
+		return
+	}
+	name := image[strings.LastIndex(image, "/")+1:]
+	tag := ""
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		tag = name[i+1:]
+	}
+	switch tag {
+	case "":
+		c.report(line, bestPractices, api.SeverityWarning, "Unpinned Image",
+			"Image "+image+" has no tag, so the latest image is used and builds are not reproducible",
+			"Pin the image to a version tag or digest, e.g. "+image+":1.2.3")
+	case "latest":
+		c.report(line, bestPractices, api.SeverityWarning, "Unpinned Image",
+			"Image "+image+" uses the latest tag, which changes without notice",
+			"Pin the image to a version tag or digest")
+	}
+}
+
+// checkEnv reports secrets given in plaintext as environment variables.
+// Values taken from variables are still visible to anything that can
+// inspect the container, which is reported when references is set.
+func (c *iacChecker) checkEnv(line int, name, value string, references bool) {
+	if !secretEnvName.MatchString(name) {
+		return
+	}
+	switch {
+	case isReference(value):
+		if references {
+			c.report(line, security, api.SeverityInfo, "Secret In Environment",
+				name+" passes a secret through an environment variable, which is visible in docker inspect and to every process in the container",
+				"Mount the secret as a file with Compose secrets and read it from /run/secrets")
+		}
+	case !placeholderValue.MatchString(value):
+		c.report(line, security, api.SeverityError, "Plaintext Secret",
+			name+" is set to a plaintext secret that is committed with the configuration",
+			"Reference the secret from a secret store or a variable set at deploy time")
+	}
+}
+
+var (
+	// secretEnvName matches environment variable and attribute names that
+	// hold credentials
+	secretEnvName = regexp.MustCompile(`(?i)(passw(or)?d|passwd|secret|token|api_?key|access_?key|private_?key|credentials?)$`)
+	// placeholderValue matches values that are obviously not real secrets
+	placeholderValue = regexp.MustCompile(`(?i)^(changeme|change_me|password|example|xxx+|\*+|<[^>]*>|true|false|none|null)?$`)
+	// secretFlag matches a command line option taking a password or token
+	secretFlag = regexp.MustCompile(`(?i)--?[\w-]*(pass(word)?|secret|token|requirepass)[\w-]*[= ]\S`)
+)
+
+// isReference reports whether value is taken from a variable rather than
+// written out
+func isReference(value string) bool {
+	value = strings.TrimSpace(value)
+	return value == "" || strings.HasPrefix(value, "$") || strings.Contains(value, "${") || strings.Contains(value, "{{")
+}
+
+// parseYAML decodes every document of a YAML stream, or nil when it is not
+// valid YAML, such as a Helm template
+func parseYAML(content []byte) []*yaml.Node {
+	var docs []*yaml.Node
+	dec := yaml.NewDecoder(strings.NewReader(string(content)))
+	for {
+		var doc yaml.Node
+		if err := dec.Decode(&doc); err != nil {
+			break
+		}
+		if len(doc.Content) > 0 && doc.Content[0].Kind == yaml.MappingNode {
+			docs = append(docs, doc.Content[0])
+		}
+	}
+	return docs
+}
+
+// isKubernetes reports whether a YAML stream holds a Kubernetes object
+func isKubernetes(content []byte) bool {
+	for _, doc := range parseYAML(content) {
+		if field(doc, "apiVersion") != nil && field(doc, "kind") != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// field returns the value of key in a YAML mapping, or nil
+func field(m *yaml.Node, key string) *yaml.Node {
+	if m == nil || m.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// nested follows keys through nested YAML mappings, returning nil when one
+// is missing
+func nested(m *yaml.Node, keys ...string) *yaml.Node {
+	for _, key := range keys {
+		if m = field(m, key); m == nil {
+			return nil
+		}
+	}
+	return m
+}
+
+// scalar returns the value of a YAML scalar, or ""
+func scalar(n *yaml.Node) string {
+	if n == nil || n.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return n.Value
+}
+
+// isTrueNode reports whether n is the YAML boolean true
+func isTrueNode(n *yaml.Node) bool {
+	return strings.EqualFold(scalar(n), "true")
+}
+
+// keyLine returns the line of key in a YAML mapping, or the mapping's line
+func keyLine(m *yaml.Node, key string) int {
+	if m != nil && m.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(m.Content); i += 2 {
+			if m.Content[i].Value == key {
+				return m.Content[i].Line
+			}
+		}
+	}
+	if m == nil {
+		return 0
+	}
+	return m.Line
+}
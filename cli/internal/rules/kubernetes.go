@@ -0,0 +1,146 @@
+package rules
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"raincheck/internal/api"
+)
+
+// podSpecPaths locates the pod template of each workload kind
+var podSpecPaths = map[string][]string{
+	"Pod":         {"spec"},
+	"Deployment":  {"spec", "template", "spec"},
+	"StatefulSet": {"spec", "template", "spec"},
+	"DaemonSet":   {"spec", "template", "spec"},
+	"ReplicaSet":  {"spec", "template", "spec"},
+	"Job":         {"spec", "template", "spec"},
+	"CronJob":     {"spec", "jobTemplate", "spec", "template", "spec"},
+}
+
+// analyzeKubernetes checks the pod templates and secrets of Kubernetes
+// manifests
+func analyzeKubernetes(content []byte) []finding {
+	c := &iacChecker{}
+	for _, doc := range parseYAML(content) {
+		kind := scalar(field(doc, "kind"))
+		name := scalar(nested(doc, "metadata", "name"))
+		if kind == "Secret" {
+			for _, key := range []string{"stringData", "data"} {
+				if field(doc, key) != nil {
+					c.report(keyLine(doc, key), security, api.SeverityWarning, "Committed Secret",
+						"Secret "+name+" stores its values in the manifest, where base64 encoding does not protect them",
+						"Keep secret values out of the repository with Sealed Secrets, SOPS or an external secret store")
+				}
+			}
+			continue
+		}
+		keys, ok := podSpecPaths[kind]
+		if !ok {
+			continue
+		}
+		if spec := nested(doc, keys...); spec != nil {
+			// Jobs run to completion and need no probes
+			c.checkPodSpec(kind+" "+name, spec, kind != "Job" && kind != "CronJob")
+		}
+	}
+	return c.findings
+}
+
+// checkPodSpec checks a pod spec and its containers
+func (c *iacChecker) checkPodSpec(workload string, spec *yaml.Node, probes bool) {
+	for _, key := range []string{"hostNetwork", "hostPID", "hostIPC"} {
+		if isTrueNode(field(spec, key)) {
+			c.report(keyLine(spec, key), security, api.SeverityWarning, "Host Namespace",
+				workload+" sets "+key+", sharing a namespace with the node",
+				"Remove "+key+" unless the pod must manage the node")
+		}
+	}
+
+	if volumes := field(spec, "volumes"); volumes != nil {
+		for _, v := range volumes.Content {
+			hostPath := field(v, "hostPath")
+			if hostPath == nil {
+				continue
+			}
+			severity := api.SeverityWarning
+			description := workload + " mounts " + scalar(field(hostPath, "path")) + " from the node with hostPath, which exposes the node's files to the pod"
+			if p := scalar(field(hostPath, "path")); p == "/" || strings.HasSuffix(p, "docker.sock") || strings.HasSuffix(p, "containerd.sock") {
+				severity = api.SeverityError
+				description = workload + " mounts " + p + " from the node with hostPath, which gives it control of the node"
+			}
+			c.report(keyLine(v, "hostPath"), security, severity, "Host Path Mount", description,
+				"Use a persistent volume claim, configMap or emptyDir instead of hostPath")
+		}
+	}
+
+	podContext := field(spec, "securityContext")
+	for _, key := range []string{"initContainers", "containers"} {
+		containers := field(spec, key)
+		if containers == nil {
+			continue
+		}
+		for _, container := range containers.Content {
+			c.checkContainer(workload, container, podContext, probes && key == "containers")
+		}
+	}
+}
+
+// checkContainer checks a container of a pod spec. Its security context
+// overrides the pod's.
+func (c *iacChecker) checkContainer(workload string, container, podContext *yaml.Node, probes bool) {
+	name := scalar(field(container, "name"))
+	line := container.Line
+	where := "Container " + name + " of " + workload
+
+	c.checkImage(keyLine(container, "image"), scalar(field(container, "image")))
+
+	context := field(container, "securityContext")
+	setting := func(key string) *yaml.Node {
+		if v := field(context, key); v != nil {
+			return v
+		}
+		return field(podContext, key)
+	}
+	if isTrueNode(field(context, "privileged")) {
+		c.report(keyLine(context, "privileged"), security, api.SeverityError, "Privileged Container",
+			where+" runs privileged, with full access to the node's devices and kernel",
+			"Remove privileged and add only the capabilities the container needs")
+	}
+	if isTrueNode(field(context, "allowPrivilegeEscalation")) {
+		c.report(keyLine(context, "allowPrivilegeEscalation"), security, api.SeverityWarning, "Privilege Escalation",
+			where+" allows privilege escalation through setuid binaries",
+			"Set allowPrivilegeEscalation: false")
+	}
+	switch user := setting("runAsUser"); {
+	case scalar(user) == "0":
+		c.report(user.Line, security, api.SeverityWarning, "Runs As Root",
+			where+" runs as root",
+			"Run the container as a non-zero UID and set runAsNonRoot: true")
+	case user == nil && !isTrueNode(setting("runAsNonRoot")):
+		c.report(line, security, api.SeverityWarning, "Runs As Root",
+			where+" may run as root, since neither runAsNonRoot nor runAsUser is set",
+			"Set runAsNonRoot: true and runAsUser in the securityContext")
+	}
+
+	if nested(container, "resources", "limits") == nil {
+		c.report(line, performance, api.SeverityInfo, "Missing Resource Limits",
+			where+" has no resource limits, so it can starve other pods on the node",
+			"Set resources.limits for memory and cpu, along with requests")
+	}
+	if probes && field(container, "readinessProbe") == nil && field(container, "livenessProbe") == nil {
+		c.report(line, bestPractices, api.SeverityInfo, "Missing Health Check",
+			where+" has no readiness or liveness probe, so traffic reaches it before it is ready and hangs go unnoticed",
+			"Add a readinessProbe and a livenessProbe")
+	}
+
+	if env := field(container, "env"); env != nil {
+		for _, e := range env.Content {
+			if field(e, "valueFrom") != nil {
+				continue
+			}
+			c.checkEnv(e.Line, scalar(field(e, "name")), scalar(field(e, "value")), false)
+		}
+	}
+}
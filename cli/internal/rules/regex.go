@@ -0,0 +1,452 @@
+package rules
+
+import (
+	"regexp"
+	"strings"
+
+	"raincheck/internal/api"
+)
+
+// regexRule reports an issue on every line matching pattern
+type regexRule struct {
+	category    string
+	severity    string
+	issueType   string
+	description string
+	suggestion  string
+	// languages limits the rule to these languages; empty applies it to
+	// every file, including extensions without a known language
+	languages []string
+	pattern   *regexp.Regexp
+	// unless skips lines that also match this pattern
+	unless *regexp.Regexp
+	// comments also matches comment lines, which are skipped otherwise
+	comments bool
+}
+
+// lineComments lists the prefixes of comment lines per language
+var lineComments = map[string][]string{
+	langGo:     {"//", "/*", "* ", "*/"},
+	langJS:     {"//", "/*", "* ", "*/"},
+	langJava:   {"//", "/*", "* ", "*/"},
+	langKotlin: {"//", "/*", "* ", "*/"},
+	langC:      {"//", "/*", "* ", "*/"},
+	langSwift:  {"//", "/*", "* ", "*/"},
+	langRust:   {"//", "/*", "* ", "*/"},
+	langPython: {"#"},
+	langRuby:   {"#"},
+	langPHP:    {"//", "#", "/*", "* ", "*/"},
+}
+
+// secretName matches identifiers that usually hold credentials or other
+// values that must be unpredictable
+const secretName = `(?i)\w*(token|secret|passw(or)?d|passwd|api_?key|nonce|salt|otp|session_?id)\w*`
+
+var regexRules = []regexRule{
+	// Language independent
+	{
+		category:    security,
+		severity:    api.SeverityError,
+		issueType:   "Hardcoded Secret",
+		description: "A credential is assigned from a string literal",
+		suggestion:  "Load secrets from the environment or a secret manager instead of the source code",
+		pattern:     regexp.MustCompile(`(?i)\b\w*(password|passwd|secret|api_?key|access_?key|auth_?token|private_?key)\w*["']?\s*(:=|=|:)\s*["'][^"'\s]{8,}["']`),
+		unless:      regexp.MustCompile(`(?i)["'](changeme|password|example|xxx+|\*+|<[^>]*>|\$\{[^}]*\})["']`),
+	},
+	{
+		category:    maintainability,
+		severity:    api.SeverityInfo,
+		issueType:   "TODO Comment",
+		description: "Unfinished work is marked in the code",
+		suggestion:  "Resolve the note or track it in the issue tracker",
+		pattern:     regexp.MustCompile(`\b(TODO|FIXME|XXX)\b`),
+		comments:    true,
+	},
+
+	// SQL built by concatenation or interpolation
+	{
+		category:    security,
+		severity:    api.SeverityError,
+		issueType:   "SQL Injection",
+		description: "A SQL statement is built by concatenating strings",
+		suggestion:  "Use parameterized queries or prepared statements",
+		languages:   []string{langJS, langPython, langJava, langKotlin, langC, langRuby, langPHP, langSwift, langRust},
+		pattern:     regexp.MustCompile("(?i)[\"'\x60]\\s*(SELECT|INSERT\\s+INTO|UPDATE|DELETE\\s+FROM)\\b[^\"'\x60]*[\"'\x60]\\s*(\\+|\\.|%)\\s*[\\w$(]"),
+	},
+	{
+		category:    security,
+		severity:    api.SeverityError,
+		issueType:   "SQL Injection",
+		description: "A SQL statement interpolates values into the query text",
+		suggestion:  "Use parameterized queries or prepared statements",
+		languages:   []string{langJS, langPython, langRuby, langPHP, langKotlin, langSwift},
+		pattern:     regexp.MustCompile("(?i)(\\bf[\"']|[\"'\x60])\\s*(SELECT|INSERT\\s+INTO|UPDATE|DELETE\\s+FROM)\\b[^\"'\x60]*(\\{|\\$\\{|#\\{|\\$[a-z_]|\\\\\\()"),
+	},
+	{
+		category:    security,
+		severity:    api.SeverityError,
+		issueType:   "SQL Injection",
+		description: "Request input is passed to a database query",
+		suggestion:  "Use prepared statements with bound parameters",
+		languages:   []string{langPHP},
+		pattern:     regexp.MustCompile(`\b(mysql_query|mysqli_query|->query|->exec)\s*\(.*\$_(GET|POST|REQUEST|COOKIE)\b`),
+	},
+	{
+		category:    security,
+		severity:    api.SeverityError,
+		issueType:   "SQL Injection",
+		description: "A SQL fragment is interpolated into a query method",
+		suggestion:  "Pass values as bind parameters, e.g. where(\"id = ?\", id)",
+		languages:   []string{langRuby},
+		pattern:     regexp.MustCompile(`\.(where|find_by_sql|order|having|joins)\s*\(\s*"[^"]*#\{`),
+	},
+
+	// Code and command execution
+	{
+		category:    security,
+		severity:    api.SeverityError,
+		issueType:   "Code Injection",
+		description: "eval executes code built at run time",
+		suggestion:  "Avoid eval; parse the data or dispatch to known functions instead",
+		languages:   []string{langJS, langPython, langPHP, langRuby},
+		pattern:     regexp.MustCompile(`(^|[^\w.$])eval\s*\(`),
+	},
+	{
+		category:    security,
+		severity:    api.SeverityWarning,
+		issueType:   "Code Injection",
+		description: "new Function compiles code from a string",
+		suggestion:  "Avoid compiling code from strings",
+		languages:   []string{langJS},
+		pattern:     regexp.MustCompile(`\bnew\s+Function\s*\(`),
+	},
+	{
+		category:    security,
+		severity:    api.SeverityError,
+		issueType:   "Code Injection",
+		description: "exec runs Python code built at run time",
+		suggestion:  "Avoid exec; dispatch to known functions instead",
+		languages:   []string{langPython},
+		pattern:     regexp.MustCompile(`(^|[^\w.])exec\s*\(`),
+	},
+	{
+		category:    security,
+		severity:    api.SeverityError,
+		issueType:   "Command Injection",
+		description: "A shell command is built from dynamic values",
+		suggestion:  "Use execFile or spawn with an argument array and no shell",
+		languages:   []string{langJS},
+		pattern:     regexp.MustCompile("\\b(exec|execSync)\\s*\\(\\s*(\x60[^\x60]*\\$\\{|[\"'][^\"']*[\"']\\s*\\+|[\\w.]+\\s*\\+)"),
+	},
+	{
+		category:    security,
+		severity:    api.SeverityError,
+		issueType:   "Command Injection",
+		description: "A subprocess is started through the shell",
+		suggestion:  "Pass the command as a list and drop shell=True",
+		languages:   []string{langPython},
+		pattern:     regexp.MustCompile(`\bsubprocess\.\w+\(.*\bshell\s*=\s*True`),
+	},
+	{
+		category:    security,
+		severity:    api.SeverityWarning,
+		issueType:   "Command Injection",
+		description: "A command is run through the system shell",
+		suggestion:  "Use subprocess.run with a list of arguments",
+		languages:   []string{langPython},
+		pattern:     regexp.MustCompile(`\bos\.(system|popen)\s*\(`),
+	},
+	{
+		category:    security,
+		severity:    api.SeverityWarning,
+		issueType:   "Command Injection",
+		description: "Runtime.exec runs an external command",
+		suggestion:  "Use ProcessBuilder with separate arguments and validate any input",
+		languages:   []string{langJava, langKotlin},
+		pattern:     regexp.MustCompile(`\bRuntime\.getRuntime\(\)\.exec\s*\(`),
+	},
+	{
+		category:    security,
+		severity:    api.SeverityWarning,
+		issueType:   "Command Injection",
+		description: "system runs a command through the shell",
+		suggestion:  "Use an exec family function with a fixed program and argument list",
+		languages:   []string{langC},
+		pattern:     regexp.MustCompile(`(^|[^\w.>])system\s*\(`),
+	},
+	{
+		category:    security,
+		severity:    api.SeverityError,
+		issueType:   "Command Injection",
+		description: "A shell command includes a variable",
+		suggestion:  "Avoid the shell or escape arguments with escapeshellarg",
+		languages:   []string{langPHP},
+		pattern:     regexp.MustCompile(`\b(shell_exec|system|passthru|exec|popen|proc_open)\s*\([^)]*\$`),
+	},
+	{
+		category:    security,
+		severity:    api.SeverityError,
+		issueType:   "Command Injection",
+		description: "A shell command interpolates a value",
+		suggestion:  "Pass the program and its arguments separately, e.g. system(\"ls\", dir)",
+		languages:   []string{langRuby},
+		pattern:     regexp.MustCompile("(\x60[^\x60]*#\\{|%x[({][^)}]*#\\{|\\b(system|exec|spawn)\\s*\\(?\\s*\"[^\"]*#\\{)"),
+	},
+	{
+		category:    security,
+		severity:    api.SeverityWarning,
+		issueType:   "Command Injection",
+		description: "The program to run is not a string literal",
+		suggestion:  "Run a fixed program and validate any arguments",
+		languages:   []string{langRust},
+		pattern:     regexp.MustCompile(`\bCommand::new\s*\(\s*[^"\s)]`),
+	},
+
+	// Cross-site scripting and file inclusion
+	{
+		category:    security,
+		severity:    api.SeverityWarning,
+		issueType:   "Cross-Site Scripting",
+		description: "HTML is written without escaping",
+		suggestion:  "Use textContent or sanitize the HTML before inserting it",
+		languages:   []string{langJS},
+		pattern:     regexp.MustCompile(`(\.(innerHTML|outerHTML)\s*=[^=]|\bdangerouslySetInnerHTML\b|\bdocument\.write\s*\()`),
+	},
+	{
+		category:    security,
+		severity:    api.SeverityError,
+		issueType:   "Cross-Site Scripting",
+		description: "Request input is echoed without escaping",
+		suggestion:  "Escape output with htmlspecialchars",
+		languages:   []string{langPHP},
+		pattern:     regexp.MustCompile(`\b(echo|print)\b[^;]*\$_(GET|POST|REQUEST|COOKIE)\b`),
+		unless:      regexp.MustCompile(`htmlspecialchars|htmlentities`),
+	},
+	{
+		category:    security,
+		severity:    api.SeverityWarning,
+		issueType:   "Cross-Site Scripting",
+		description: "html_safe disables escaping",
+		suggestion:  "Sanitize the content or let the view escape it",
+		languages:   []string{langRuby},
+		pattern:     regexp.MustCompile(`\.html_safe\b|\braw\s*\(`),
+	},
+	{
+		category:    security,
+		severity:    api.SeverityError,
+		issueType:   "File Inclusion",
+		description: "A file chosen by request input is included",
+		suggestion:  "Map request values onto a fixed list of files",
+		languages:   []string{langPHP},
+		pattern:     regexp.MustCompile(`\b(include|require)(_once)?\b\s*\(?\s*\$_(GET|POST|REQUEST|COOKIE)`),
+	},
+
+	// Deserialization and transport security
+	{
+		category:    security,
+		severity:    api.SeverityWarning,
+		issueType:   "Insecure Deserialization",
+		description: "Untrusted data may be deserialized into objects",
+		suggestion:  "Use a data-only format such as JSON for untrusted input",
+		languages:   []string{langPython},
+		pattern:     regexp.MustCompile(`\b(pickle|cPickle|marshal)\.loads?\s*\(|\byaml\.load\s*\(`),
+		unless:      regexp.MustCompile(`SafeLoader|CSafeLoader`),
+	},
+	{
+		category:    security,
+		severity:    api.SeverityWarning,
+		issueType:   "Insecure Deserialization",
+		description: "Untrusted data may be deserialized into objects",
+		suggestion:  "Use a data-only format such as JSON for untrusted input",
+		languages:   []string{langJava, langKotlin},
+		pattern:     regexp.MustCompile(`\bnew\s+ObjectInputStream\s*\(|\bObjectInputStream\s*\(`),
+	},
+	{
+		category:    security,
+		severity:    api.SeverityWarning,
+		issueType:   "Insecure Deserialization",
+		description: "Untrusted data may be deserialized into objects",
+		suggestion:  "Use json_decode for untrusted input",
+		languages:   []string{langPHP},
+		pattern:     regexp.MustCompile(`\bunserialize\s*\(`),
+	},
+	{
+		category:    security,
+		severity:    api.SeverityWarning,
+		issueType:   "Insecure Deserialization",
+		description: "Untrusted data may be deserialized into objects",
+		suggestion:  "Use JSON or YAML.safe_load for untrusted input",
+		languages:   []string{langRuby},
+		pattern:     regexp.MustCompile(`\bMarshal\.load\b|\bYAML\.load\b`),
+	},
+	{
+		category:    security,
+		severity:    api.SeverityError,
+		issueType:   "TLS Verification Disabled",
+		description: "Certificate verification is turned off",
+		suggestion:  "Keep verification on and trust the required CA instead",
+		languages:   []string{langPython, langJS},
+		pattern:     regexp.MustCompile(`\bverify\s*=\s*False\b|\brejectUnauthorized\s*:\s*false\b|NODE_TLS_REJECT_UNAUTHORIZED\s*=\s*["']?0`),
+	},
+
+	// Cryptography
+	{
+		category:    security,
+		severity:    api.SeverityWarning,
+		issueType:   "Weak Hash",
+		description: "MD5 and SHA-1 are broken for security purposes",
+		suggestion:  "Use SHA-256 or better, or a password hash such as bcrypt or argon2",
+		languages:   []string{langJS, langPython, langJava, langKotlin, langC, langRuby, langPHP, langSwift, langRust},
+		pattern:     regexp.MustCompile(`(?i)(hashlib\.(md5|sha1)\s*\(|createHash\(\s*["'](md5|sha1)["']|getInstance\(\s*"(md5|sha-?1)"|Digest::(MD5|SHA1)\b|(^|[^\w.])(md5|sha1)\s*\(|\bInsecure\.(MD5|SHA1)\b)`),
+	},
+	{
+		category:    security,
+		severity:    api.SeverityError,
+		issueType:   "Insecure Randomness",
+		description: "A secret is generated with a predictable random number generator",
+		suggestion:  "Use a cryptographically secure generator such as crypto.randomBytes, secrets or SecureRandom",
+		languages:   []string{langJS, langPython, langJava, langKotlin, langC, langRuby, langPHP, langSwift},
+		pattern:     regexp.MustCompile(secretName + `\s*(=|:|\+=)[^=].*(Math\.random\(|\brandom\.(random|randint|choice|choices|randrange|getrandbits)\(|\bnew\s+Random\(|\bRandom\(\)\.|(^|[^\w.])(rand|mt_rand|random)\s*\(\)?|\brand\(\d*\))`),
+	},
+
+	// Memory safety
+	{
+		category:    security,
+		severity:    api.SeverityError,
+		issueType:   "Buffer Overflow",
+		description: "gets cannot limit the input to the buffer size",
+		suggestion:  "Use fgets with the buffer size",
+		languages:   []string{langC},
+		pattern:     regexp.MustCompile(`(^|[^\w.>])gets\s*\(`),
+	},
+	{
+		category:    security,
+		severity:    api.SeverityWarning,
+		issueType:   "Buffer Overflow",
+		description: "This function does not check the size of the destination buffer",
+		suggestion:  "Use a bounded variant such as strncpy, strncat or snprintf",
+		languages:   []string{langC},
+		pattern:     regexp.MustCompile(`(^|[^\w.>])(strcpy|strcat|sprintf|vsprintf)\s*\(`),
+	},
+	{
+		category:    security,
+		severity:    api.SeverityInfo,
+		issueType:   "Unsafe Block",
+		description: "The block opts out of Rust's memory safety checks",
+		suggestion:  "Document the invariants that make the block sound",
+		languages:   []string{langRust},
+		pattern:     regexp.MustCompile(`\bunsafe\s*\{`),
+	},
+
+	// Code quality and best practices
+	{
+		category:    codeQuality,
+		severity:    api.SeverityInfo,
+		issueType:   "Bare Except",
+		description: "A bare except also catches KeyboardInterrupt and SystemExit",
+		suggestion:  "Catch the specific exceptions, or at least Exception",
+		languages:   []string{langPython},
+		pattern:     regexp.MustCompile(`^\s*except\s*:`),
+	},
+	{
+		category:    codeQuality,
+		severity:    api.SeverityInfo,
+		issueType:   "Empty Catch Block",
+		description: "The exception is caught and silently ignored",
+		suggestion:  "Handle or log the exception",
+		languages:   []string{langJS, langJava, langKotlin, langPHP, langC},
+		pattern:     regexp.MustCompile(`\bcatch\s*(\([^)]*\))?\s*\{\s*\}`),
+	},
+	{
+		category:    codeQuality,
+		severity:    api.SeverityInfo,
+		issueType:   "Unchecked Unwrap",
+		description: "unwrap panics on errors and missing values",
+		suggestion:  "Propagate the error with ? or handle it explicitly",
+		languages:   []string{langRust},
+		pattern:     regexp.MustCompile(`\.unwrap\(\)`),
+	},
+	{
+		category:    codeQuality,
+		severity:    api.SeverityInfo,
+		issueType:   "Forced Unwrap",
+		description: "A forced try or cast crashes at run time on failure",
+		suggestion:  "Use try? or as? and handle the failure",
+		languages:   []string{langSwift},
+		pattern:     regexp.MustCompile(`\btry!|\bas!`),
+	},
+	{
+		category:    codeQuality,
+		severity:    api.SeverityInfo,
+		issueType:   "Non-null Assertion",
+		description: "!! throws a NullPointerException when the value is null",
+		suggestion:  "Use a safe call or an explicit null check",
+		languages:   []string{langKotlin},
+		pattern:     regexp.MustCompile(`[\w)\]]!!`),
+	},
+	{
+		category:    bestPractices,
+		severity:    api.SeverityInfo,
+		issueType:   "Debug Output",
+		description: "Debug output is left in the code",
+		suggestion:  "Use the project's logger or remove the statement",
+		languages:   []string{langJS, langJava},
+		pattern:     regexp.MustCompile(`\bconsole\.(log|debug)\s*\(|\bSystem\.(out|err)\.print(ln)?\s*\(|\.printStackTrace\(\)`),
+	},
+}
+
+// matchRegexRules applies the regex rules for lang to every line of content
+func matchRegexRules(lang string, content []byte) []finding {
+	var active []regexRule
+	for _, r := range regexRules {
+		if r.appliesTo(lang) {
+			active = append(active, r)
+		}
+	}
+
+	var findings []finding
+	lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		comment := isComment(lang, line)
+		for _, r := range active {
+			if comment && !r.comments {
+				continue
+			}
+			if !r.pattern.MatchString(line) || (r.unless != nil && r.unless.MatchString(line)) {
+				continue
+			}
+			findings = append(findings, finding{category: r.category, issue: api.Issue{
+				Severity:    r.severity,
+				Type:        r.issueType,
+				Description: r.description,
+				Line:        i + 1,
+				Suggestion:  r.suggestion,
+			}})
+		}
+	}
+	return findings
+}
+
+func (r regexRule) appliesTo(lang string) bool {
+	if len(r.languages) == 0 {
+		return true
+	}
+	for _, l := range r.languages {
+		if l == lang {
+			return true
+		}
+	}
+	return false
+}
+
+// isComment reports whether line is a comment line in lang
+func isComment(lang, line string) bool {
+	trimmed := strings.TrimSpace(line)
+	for _, prefix := range lineComments[lang] {
+		// A lone "*" continues a block comment; "*p" dereferences a pointer
+		if strings.HasPrefix(trimmed, prefix) || (prefix == "/*" && trimmed == "*") {
+			return true
+		}
+	}
+	return false
+}
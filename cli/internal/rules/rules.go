@@ -0,0 +1,179 @@
+package rules
+
+import (
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"raincheck/internal/api"
+)
+
+// Category keys used by the rules, matching api.NamedCategory.Key
+const (
+	security        = "security"
+	performance     = "performance"
+	codeQuality     = "code_quality"
+	maintainability = "maintainability"
+	bestPractices   = "best_practices"
+)
+
+// finding is an issue reported by a rule together with its category key
+type finding struct {
+	category string
+	issue    api.Issue
+}
+
+// Analyze runs the built-in rules for the language of path over content and
+// returns the findings in the shape of a backend analysis. Dockerfiles,
+// Compose files, Kubernetes manifests and Terraform files are checked with
+// the infrastructure rules instead. It never touches the network.
+func Analyze(path string, content []byte) *api.AnalysisResponse {
+	if lang := IaCLanguage(path, content); lang != "" {
+		return newResponse(analyzeIaC(lang, content))
+	}
+	lang := languageOf(path)
+
+	findings := matchRegexRules(lang, content)
+	if lang == langGo {
+		findings = append(findings, analyzeGo(path, content)...)
+	}
+	return newResponse(findings)
+}
+
+// newResponse groups findings by category, dropping duplicates reported
+// twice for the same line, and scores each category
+func newResponse(findings []finding) *api.AnalysisResponse {
+	resp := &api.AnalysisResponse{Suggestions: []string{}}
+	resp.AddStandardCategories()
+	categories := make(map[string]*api.Category)
+	for _, c := range resp.Categories() {
+		c.Category.Issues = []api.Issue{}
+		categories[c.Key] = c.Category
+	}
+
+	seen := make(map[string]bool)
+	for _, f := range findings {
+		key := issueKey(f.category, f.issue) + "\x00" + f.issue.Description
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		c := categories[f.category]
+		c.Issues = append(c.Issues, f.issue)
+	}
+
+	total := 0.0
+	for _, c := range resp.Categories() {
+		sort.SliceStable(c.Category.Issues, func(i, j int) bool {
+			return c.Category.Issues[i].Line < c.Category.Issues[j].Line
+		})
+		// Scored like the backend's categories, so score gates mean the
+		// same with every engine
+		c.Category.Score = api.Score(c.Category.Issues)
+		total += c.Category.Score
+	}
+	resp.OverallScore = total / float64(len(resp.Categories()))
+	return resp
+}
+
+// issueKey identifies an issue for deduplication by category, line and type
+func issueKey(category string, issue api.Issue) string {
+	return category + "\x00" + strings.ToLower(strings.TrimSpace(issue.Type)) + "\x00" + strconv.Itoa(issue.Line)
+}
+
+// Merge adds the local findings to a remote analysis. Issues both report on
+// the same line with the same type are kept once, and each score becomes the
+// lower of the two so that deterministic findings are never rated away.
+func Merge(remote, local *api.AnalysisResponse) *api.AnalysisResponse {
+	localCategories := make(map[string]api.NamedCategory)
+	for _, lc := range local.Categories() {
+		localCategories[lc.Key] = lc
+	}
+	for _, c := range remote.Categories() {
+		lc, ok := localCategories[c.Key]
+		if !ok {
+			continue
+		}
+
+		seen := make(map[string]bool)
+		for _, issue := range c.Category.Issues {
+			seen[issueKey(c.Key, issue)] = true
+		}
+		for _, issue := range lc.Category.Issues {
+			if !seen[issueKey(c.Key, issue)] {
+				c.Category.Issues = append(c.Category.Issues, issue)
+			}
+		}
+
+		if lc.Category.Score < c.Category.Score {
+			c.Category.Score = lc.Category.Score
+		}
+	}
+	if local.OverallScore < remote.OverallScore {
+		remote.OverallScore = local.OverallScore
+	}
+	remote.Suggestions = append(remote.Suggestions, local.Suggestions...)
+	return remote
+}
+
+// Languages the rules distinguish
+const (
+	langGo     = "go"
+	langJS     = "javascript"
+	langPython = "python"
+	langJava   = "java"
+	langKotlin = "kotlin"
+	langC      = "c"
+	langRuby   = "ruby"
+	langPHP    = "php"
+	langSwift  = "swift"
+	langRust   = "rust"
+)
+
+var extLanguages = map[string]string{
+	".go":    langGo,
+	".js":    langJS,
+	".jsx":   langJS,
+	".ts":    langJS,
+	".tsx":   langJS,
+	".py":    langPython,
+	".java":  langJava,
+	".kt":    langKotlin,
+	".c":     langC,
+	".h":     langC,
+	".cpp":   langC,
+	".hpp":   langC,
+	".rb":    langRuby,
+	".php":   langPHP,
+	".swift": langSwift,
+	".rs":    langRust,
+}
+
+// dialects are languages the rules check alike but that a reviewer should
+// tell apart
+var dialects = map[string]string{
+	".ts":  "typescript",
+	".tsx": "typescript",
+	".cpp": "cpp",
+	".hpp": "cpp",
+}
+
+// Language names the language of a file for a reviewer: its
+// infrastructure-as-code format or its programming language, or "" when
+// neither is known
+func Language(path string, content []byte) string {
+	if lang := IaCLanguage(path, content); lang != "" {
+		return lang
+	}
+	if lang, ok := dialects[strings.ToLower(filepath.Ext(path))]; ok {
+		return lang
+	}
+	return languageOf(path)
+}
+
+// languageOf returns the language of path by extension, or "" when only the
+// language independent rules apply
+func languageOf(path string) string {
+	return extLanguages[strings.ToLower(filepath.Ext(path))]
+}
@@ -0,0 +1,283 @@
+package rules
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"raincheck/internal/api"
+)
+
+// hclBlock is a block of a Terraform file, such as a resource, with the
+// attributes set directly in it
+type hclBlock struct {
+	line   int
+	kind   string
+	labels []string
+	attrs  map[string]hclAttr
+	parent *hclBlock
+}
+
+// hclAttr is an attribute of a block; value is the expression as written
+type hclAttr struct {
+	line  int
+	value string
+}
+
+var (
+	hclBlockStart = regexp.MustCompile(`^([\w-]+)((?:\s+(?:"[^"]*"|[\w-]+))*)\s*\{$`)
+	hclLabel      = regexp.MustCompile(`"([^"]*)"|[\w-]+`)
+	hclAttribute  = regexp.MustCompile(`^([\w-]+)\s*=\s*(.*)$`)
+	// hclOneLine matches a block opened and closed on one line
+	hclOneLine = regexp.MustCompile(`^([\w-]+)((?:\s+(?:"[^"]*"|[\w-]+))*)\s*\{(.*)\}$`)
+)
+
+// parseTerraform reads the blocks of a Terraform file line by line. Nested
+// blocks such as ingress are returned with their parent; attributes spanning
+// several lines keep only their first line.
+func parseTerraform(content []byte) []*hclBlock {
+	var (
+		blocks  []*hclBlock
+		current *hclBlock
+		// depth counts brackets and parentheses of multi-line values
+		depth   int
+		comment bool
+	)
+	open := func(line int, kind, labels string) *hclBlock {
+		b := &hclBlock{line: line, kind: kind, attrs: make(map[string]hclAttr), parent: current}
+		for _, m := range hclLabel.FindAllStringSubmatch(labels, -1) {
+			label := m[1]
+			if label == "" {
+				label = m[0]
+			}
+			b.labels = append(b.labels, label)
+		}
+		blocks = append(blocks, b)
+		return b
+	}
+
+	lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(stripHCLComment(lines[i]))
+		if comment {
+			if end := strings.Index(line, "*/"); end >= 0 {
+				comment = false
+				line = strings.TrimSpace(line[end+2:])
+			} else {
+				continue
+			}
+		}
+		if strings.HasPrefix(line, "/*") {
+			comment = !strings.Contains(line, "*/")
+			continue
+		}
+		if line == "" {
+			continue
+		}
+
+		if depth > 0 {
+			depth += strings.Count(line, "[") + strings.Count(line, "(") + strings.Count(line, "{") -
+				strings.Count(line, "]") - strings.Count(line, ")") - strings.Count(line, "}")
+			if depth < 0 {
+				depth = 0
+			}
+			continue
+		}
+
+		switch {
+		case line == "}":
+			if current != nil {
+				current = current.parent
+			}
+		case hclOneLine.MatchString(line) && !hclAttribute.MatchString(line):
+			m := hclOneLine.FindStringSubmatch(line)
+			b := open(i+1, m[1], m[2])
+			if a := hclAttribute.FindStringSubmatch(strings.TrimSpace(m[3])); a != nil {
+				b.attrs[a[1]] = hclAttr{line: i + 1, value: strings.TrimSpace(a[2])}
+			}
+		case hclBlockStart.MatchString(line):
+			m := hclBlockStart.FindStringSubmatch(line)
+			current = open(i+1, m[1], m[2])
+		default:
+			m := hclAttribute.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			value := strings.TrimSpace(m[2])
+			if current != nil {
+				current.attrs[m[1]] = hclAttr{line: i + 1, value: value}
+			}
+			depth = strings.Count(value, "[") + strings.Count(value, "(") + strings.Count(value, "{") -
+				strings.Count(value, "]") - strings.Count(value, ")") - strings.Count(value, "}")
+			if strings.HasPrefix(value, "<<") {
+				depth = 0
+				// Skip the heredoc up to its delimiter
+				delimiter := strings.TrimLeft(value, "<-")
+				for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != delimiter {
+					i++
+				}
+			}
+			if depth < 0 {
+				depth = 0
+			}
+		}
+	}
+	return blocks
+}
+
+// stripHCLComment removes a # or // comment that is not inside a string
+func stripHCLComment(line string) string {
+	inString := false
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '"' && (i == 0 || line[i-1] != '\\'):
+			inString = !inString
+		case inString:
+		case line[i] == '#', line[i] == '/' && i+1 < len(line) && line[i+1] == '/':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// resource returns the resource type of the block or of the resource it is
+// nested in, or ""
+func (b *hclBlock) resource() string {
+	for ; b != nil; b = b.parent {
+		if b.kind == "resource" && len(b.labels) > 0 {
+			return b.labels[0]
+		}
+	}
+	return ""
+}
+
+// str returns the value of a string attribute without its quotes, and
+// whether it is a plain string literal
+func (b *hclBlock) str(key string) (string, bool) {
+	v, ok := b.attrs[key]
+	if !ok || len(v.value) < 2 || !strings.HasPrefix(v.value, `"`) || !strings.HasSuffix(v.value, `"`) {
+		return "", false
+	}
+	s := v.value[1 : len(v.value)-1]
+	return s, !strings.Contains(s, "${")
+}
+
+// is reports whether a boolean attribute is set to value
+func (b *hclBlock) is(key string, value bool) bool {
+	v, ok := b.attrs[key]
+	return ok && v.value == strconv.FormatBool(value)
+}
+
+// publicACLs are the canned ACLs that open a bucket to everyone
+var publicACLs = map[string]bool{"public-read": true, "public-read-write": true, "website": true}
+
+// analyzeTerraform checks the resources and variables of a Terraform file
+func analyzeTerraform(content []byte) []finding {
+	c := &iacChecker{}
+	for _, b := range parseTerraform(content) {
+		resource := b.resource()
+		name := strings.Join(b.labels, ".")
+
+		switch {
+		case resource == "aws_s3_bucket" || resource == "aws_s3_bucket_acl":
+			if acl, _ := b.str("acl"); publicACLs[acl] {
+				c.report(b.attrs["acl"].line, security, api.SeverityError, "Public Bucket",
+					"S3 bucket "+name+" uses the "+acl+" ACL, which lets anyone on the internet read its objects",
+					"Use a private ACL and serve public content through CloudFront with origin access control")
+			}
+		case resource == "aws_s3_bucket_public_access_block":
+			for _, key := range []string{"block_public_acls", "block_public_policy", "ignore_public_acls", "restrict_public_buckets"} {
+				if b.is(key, false) {
+					c.report(b.attrs[key].line, security, api.SeverityWarning, "Public Bucket",
+						name+" turns off "+key+", allowing the bucket to be made public",
+						"Set all four public access block settings to true")
+				}
+			}
+		case resource == "google_storage_bucket_iam_member" || resource == "google_storage_bucket_iam_binding":
+			for _, key := range []string{"member", "members"} {
+				if v, ok := b.attrs[key]; ok && (strings.Contains(v.value, `"allUsers"`) || strings.Contains(v.value, `"allAuthenticatedUsers"`)) {
+					c.report(v.line, security, api.SeverityError, "Public Bucket",
+						name+" grants access to the bucket to allUsers or allAuthenticatedUsers",
+						"Grant access to specific service accounts or groups instead")
+				}
+			}
+		case resource == "azurerm_storage_container":
+			if access, _ := b.str("container_access_type"); access == "blob" || access == "container" {
+				c.report(b.attrs["container_access_type"].line, security, api.SeverityError, "Public Bucket",
+					"Storage container "+name+" allows anonymous "+access+" access",
+					"Set container_access_type to private")
+			}
+		case resource == "aws_db_instance" || resource == "aws_rds_cluster_instance":
+			if b.is("publicly_accessible", true) {
+				c.report(b.attrs["publicly_accessible"].line, security, api.SeverityError, "Public Database",
+					"Database "+name+" is reachable from the internet",
+					"Set publicly_accessible = false and connect through the VPC")
+			}
+			if b.is("storage_encrypted", false) {
+				c.report(b.attrs["storage_encrypted"].line, security, api.SeverityWarning, "Unencrypted Storage",
+					"Database "+name+" stores its data unencrypted",
+					"Set storage_encrypted = true")
+			}
+		case resource == "aws_ebs_volume" && b.is("encrypted", false):
+			c.report(b.attrs["encrypted"].line, security, api.SeverityWarning, "Unencrypted Storage",
+				"EBS volume "+name+" is not encrypted",
+				"Set encrypted = true")
+		}
+
+		if b.kind == "ingress" || resource == "aws_security_group_rule" || resource == "aws_vpc_security_group_ingress_rule" {
+			c.checkIngress(b)
+		}
+
+		for key, attr := range b.attrs {
+			if b.kind == "variable" || !secretEnvName.MatchString(key) {
+				continue
+			}
+			if s, literal := b.str(key); literal && !placeholderValue.MatchString(s) {
+				c.report(attr.line, security, api.SeverityError, "Plaintext Secret",
+					key+" is set to a plaintext secret that is committed with the configuration",
+					"Pass the secret in a sensitive variable or read it from a secret manager data source")
+			}
+		}
+		// Variables may default to a secret too
+		if b.kind == "variable" && len(b.labels) > 0 && secretEnvName.MatchString(b.labels[0]) {
+			if s, literal := b.str("default"); literal && !placeholderValue.MatchString(s) {
+				c.report(b.attrs["default"].line, security, api.SeverityError, "Plaintext Secret",
+					"Variable "+b.labels[0]+" defaults to a plaintext secret that is committed with the configuration",
+					"Remove the default and set the variable at deploy time, marked sensitive = true")
+			}
+		}
+	}
+	return c.findings
+}
+
+// checkIngress reports ingress rules open to the whole internet
+func (c *iacChecker) checkIngress(b *hclBlock) {
+	if b.resource() == "aws_security_group_rule" {
+		if t, _ := b.str("type"); t != "ingress" {
+			return
+		}
+	}
+	open := ""
+	for _, key := range []string{"cidr_blocks", "ipv6_cidr_blocks", "cidr_ipv4", "cidr_ipv6"} {
+		if v, ok := b.attrs[key]; ok && (strings.Contains(v.value, `"0.0.0.0/0"`) || strings.Contains(v.value, `"::/0"`)) {
+			open = key
+			break
+		}
+	}
+	if open == "" {
+		return
+	}
+
+	from, _ := strconv.Atoi(b.attrs["from_port"].value)
+	to, _ := strconv.Atoi(b.attrs["to_port"].value)
+	severity, description := api.SeverityWarning, "An ingress rule accepts traffic from the whole internet"
+	for _, port := range []int{22, 3389} {
+		if from <= port && port <= to || from == 0 && to == 0 {
+			severity = api.SeverityError
+			description = "An ingress rule opens remote administration ports to the whole internet"
+			break
+		}
+	}
+	c.report(b.attrs[open].line, security, severity, "Open Ingress", description,
+		"Restrict the source CIDR blocks to known networks, or reach hosts through a bastion or VPN")
+}
@@ -0,0 +1,128 @@
+package sbom
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	"raincheck/internal/deps"
+)
+
+// cdxRootRef is the bom-ref of the project itself
+const cdxRootRef = "root"
+
+type cdxBOM struct {
+	BOMFormat    string          `json:"bomFormat"`
+	SpecVersion  string          `json:"specVersion"`
+	SerialNumber string          `json:"serialNumber"`
+	Version      int             `json:"version"`
+	Metadata     cdxMetadata     `json:"metadata"`
+	Components   []cdxComponent  `json:"components"`
+	Dependencies []cdxDependency `json:"dependencies"`
+}
+
+type cdxMetadata struct {
+	Timestamp string       `json:"timestamp"`
+	Tools     cdxTools     `json:"tools"`
+	Component cdxComponent `json:"component"`
+}
+
+type cdxTools struct {
+	Components []cdxComponent `json:"components"`
+}
+
+type cdxComponent struct {
+	Type       string        `json:"type"`
+	BOMRef     string        `json:"bom-ref,omitempty"`
+	Group      string        `json:"group,omitempty"`
+	Name       string        `json:"name"`
+	Version    string        `json:"version,omitempty"`
+	Scope      string        `json:"scope,omitempty"`
+	Hashes     []cdxHash     `json:"hashes,omitempty"`
+	PURL       string        `json:"purl,omitempty"`
+	Properties []cdxProperty `json:"properties,omitempty"`
+}
+
+type cdxHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+type cdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cdxDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// writeCycloneDX renders the document as CycloneDX 1.5 JSON. Components are
+// referenced by purl, and development dependencies are marked as excluded
+// from the runtime.
+func writeCycloneDX(w io.Writer, doc *Document) error {
+	serial, err := newUUID()
+	if err != nil {
+		return err
+	}
+	g := buildGraph(doc)
+
+	bom := cdxBOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + serial,
+		Version:      1,
+		Metadata: cdxMetadata{
+			Timestamp: doc.Created.UTC().Format("2006-01-02T15:04:05Z"),
+			Tools:     cdxTools{Components: []cdxComponent{{Type: "application", Name: toolName}}},
+			Component: cdxComponent{Type: "application", BOMRef: cdxRootRef, Name: doc.Name},
+		},
+		Components:   make([]cdxComponent, 0, len(g.components)),
+		Dependencies: []cdxDependency{{Ref: cdxRootRef, DependsOn: nonNil(g.direct)}},
+	}
+
+	for _, c := range g.components {
+		comp := cdxComponent{
+			Type:    "library",
+			BOMRef:  c.purl,
+			Name:    c.Name,
+			Version: c.Version,
+			Scope:   "required",
+			PURL:    c.purl,
+		}
+		if c.Ecosystem == deps.EcosystemMaven {
+			if group, artifact, ok := strings.Cut(c.Name, ":"); ok {
+				comp.Group, comp.Name = group, artifact
+			}
+		}
+		if c.Dev {
+			comp.Scope = "excluded"
+		}
+		for _, h := range c.Hashes {
+			comp.Hashes = append(comp.Hashes, cdxHash{Alg: h.Algorithm, Content: h.Value})
+		}
+		for _, m := range c.manifests {
+			comp.Properties = append(comp.Properties, cdxProperty{Name: "raincheck:manifest", Value: m})
+		}
+		bom.Components = append(bom.Components, comp)
+
+		if len(c.dependsOn) > 0 {
+			bom.Dependencies = append(bom.Dependencies, cdxDependency{Ref: c.purl, DependsOn: c.dependsOn})
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(bom)
+}
+
+// nonNil returns list, or an empty list instead of nil so that it encodes
+// as []
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
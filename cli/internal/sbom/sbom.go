@@ -0,0 +1,178 @@
+package sbom
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"raincheck/internal/deps"
+)
+
+// Supported output formats
+const (
+	FormatCycloneDX = "cyclonedx-json"
+	FormatSPDX      = "spdx-json"
+)
+
+// Formats lists every supported output format
+var Formats = []string{FormatCycloneDX, FormatSPDX}
+
+// toolName identifies raincheck as the creator of a document
+const toolName = "raincheck"
+
+// Document describes the dependencies of a project
+type Document struct {
+	// Name is the name of the project, usually its directory
+	Name      string
+	Created   time.Time
+	Manifests []Manifest
+}
+
+// Manifest is a manifest or lockfile and the packages it pins
+type Manifest struct {
+	// Path is relative to the project directory
+	Path     string
+	Packages []deps.Package
+}
+
+// component is a package merged across every manifest that pins it
+type component struct {
+	deps.Package
+	purl      string
+	manifests []string
+	// dependsOn are the purls of the components this one requires
+	dependsOn []string
+}
+
+// graph is the merged components of a document together with the purls of
+// the project's direct dependencies
+type graph struct {
+	components []*component
+	direct     []string
+}
+
+// ValidFormat reports whether format is a supported output format
+func ValidFormat(format string) bool {
+	for _, f := range Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// Write renders the document in the given format
+func Write(w io.Writer, format string, doc *Document) error {
+	switch format {
+	case FormatCycloneDX:
+		return writeCycloneDX(w, doc)
+	case FormatSPDX:
+		return writeSPDX(w, doc)
+	}
+	return fmt.Errorf("unknown format %q (supported: %s)", format, strings.Join(Formats, ", "))
+}
+
+// WriteFile renders the document to path, or to stdout when path is "-"
+func WriteFile(path, format string, doc *Document) error {
+	if path == "" || path == "-" {
+		return Write(os.Stdout, format, doc)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer file.Close()
+
+	if err := Write(file, format, doc); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// buildGraph merges the packages of every manifest by purl. A package is
+// only a development dependency if every manifest says so, and is direct if
+// any manifest declares it.
+func buildGraph(doc *Document) graph {
+	var (
+		g      graph
+		byPURL = make(map[string]*component)
+		direct = make(map[string]bool)
+	)
+	for _, m := range doc.Manifests {
+		for _, p := range m.Packages {
+			purl := p.PURL()
+			c, ok := byPURL[purl]
+			if !ok {
+				c = &component{Package: p, purl: purl}
+				c.Hashes, c.Dependencies = nil, nil
+				byPURL[purl] = c
+				g.components = append(g.components, c)
+			}
+			c.Direct = c.Direct || p.Direct
+			c.Dev = c.Dev && p.Dev
+			c.Hashes = appendUnique(c.Hashes, p.Hashes...)
+			if !slices.Contains(c.manifests, m.Path) {
+				c.manifests = append(c.manifests, m.Path)
+			}
+			for _, id := range p.Dependencies {
+				c.dependsOn = appendUnique(c.dependsOn, dependencyPURL(p.Ecosystem, id))
+			}
+			if p.Direct && !direct[purl] {
+				direct[purl] = true
+				g.direct = append(g.direct, purl)
+			}
+		}
+	}
+
+	// Lockfiles may name packages that were skipped, such as git or path
+	// dependencies
+	for _, c := range g.components {
+		kept := c.dependsOn[:0]
+		for _, purl := range c.dependsOn {
+			if byPURL[purl] != nil {
+				kept = append(kept, purl)
+			}
+		}
+		c.dependsOn = kept
+	}
+
+	sort.SliceStable(g.components, func(a, b int) bool { return g.components[a].purl < g.components[b].purl })
+	sort.Strings(g.direct)
+	return g
+}
+
+// dependencyPURL returns the purl of the package with the given ID
+func dependencyPURL(ecosystem, id string) string {
+	// npm scopes start with "@", so the version follows the last one
+	i := strings.LastIndex(id, "@")
+	if i <= 0 {
+		return ""
+	}
+	return deps.Package{Ecosystem: ecosystem, Name: id[:i], Version: id[i+1:]}.PURL()
+}
+
+func appendUnique[T comparable](list []T, items ...T) []T {
+	for _, item := range items {
+		if !slices.Contains(list, item) {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// newUUID returns a random version 4 UUID
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate UUID: %w", err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
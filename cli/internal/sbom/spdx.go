@@ -0,0 +1,162 @@
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"raincheck/internal/deps"
+)
+
+// SPDX identifiers of the document and the project
+const (
+	spdxDocumentID = "SPDXRef-DOCUMENT"
+	spdxRootID     = "SPDXRef-Root"
+	spdxNone       = "NOASSERTION"
+)
+
+// spdxAlgorithms maps hash algorithms to their SPDX names
+var spdxAlgorithms = map[string]string{
+	deps.HashSHA1:   "SHA1",
+	deps.HashSHA256: "SHA256",
+	deps.HashSHA384: "SHA384",
+	deps.HashSHA512: "SHA512",
+}
+
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	SPDXID                string            `json:"SPDXID"`
+	Name                  string            `json:"name"`
+	VersionInfo           string            `json:"versionInfo,omitempty"`
+	DownloadLocation      string            `json:"downloadLocation"`
+	FilesAnalyzed         bool              `json:"filesAnalyzed"`
+	LicenseConcluded      string            `json:"licenseConcluded"`
+	LicenseDeclared       string            `json:"licenseDeclared"`
+	CopyrightText         string            `json:"copyrightText"`
+	Checksums             []spdxChecksum    `json:"checksums,omitempty"`
+	ExternalRefs          []spdxExternalRef `json:"externalRefs,omitempty"`
+	PrimaryPackagePurpose string            `json:"primaryPackagePurpose"`
+}
+
+type spdxChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+// writeSPDX renders the document as SPDX 2.3 JSON. The project is described
+// by the document and depends on its direct dependencies, or is the target
+// of DEV_DEPENDENCY_OF for development ones.
+func writeSPDX(w io.Writer, doc *Document) error {
+	id, err := newUUID()
+	if err != nil {
+		return err
+	}
+	g := buildGraph(doc)
+
+	out := spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            spdxDocumentID,
+		Name:              doc.Name,
+		DocumentNamespace: "https://spdx.org/spdxdocs/" + toolName + "/" + url.PathEscape(doc.Name) + "-" + id,
+		CreationInfo: spdxCreationInfo{
+			Created:  doc.Created.UTC().Format("2006-01-02T15:04:05Z"),
+			Creators: []string{"Tool: " + toolName},
+		},
+		Packages: []spdxPackage{{
+			SPDXID:                spdxRootID,
+			Name:                  doc.Name,
+			DownloadLocation:      spdxNone,
+			LicenseConcluded:      spdxNone,
+			LicenseDeclared:       spdxNone,
+			CopyrightText:         spdxNone,
+			PrimaryPackagePurpose: "APPLICATION",
+		}},
+		Relationships: []spdxRelationship{{
+			SPDXElementID:      spdxDocumentID,
+			RelationshipType:   "DESCRIBES",
+			RelatedSPDXElement: spdxRootID,
+		}},
+	}
+
+	ids := make(map[string]string, len(g.components))
+	dev := make(map[string]bool)
+	for i, c := range g.components {
+		ids[c.purl] = fmt.Sprintf("SPDXRef-Package-%d", i+1)
+		dev[c.purl] = c.Dev
+	}
+
+	for _, c := range g.components {
+		pkg := spdxPackage{
+			SPDXID:                ids[c.purl],
+			Name:                  c.Name,
+			VersionInfo:           c.Version,
+			DownloadLocation:      spdxNone,
+			LicenseConcluded:      spdxNone,
+			LicenseDeclared:       spdxNone,
+			CopyrightText:         spdxNone,
+			PrimaryPackagePurpose: "LIBRARY",
+			ExternalRefs: []spdxExternalRef{{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  c.purl,
+			}},
+		}
+		for _, h := range c.Hashes {
+			if alg := spdxAlgorithms[h.Algorithm]; alg != "" {
+				pkg.Checksums = append(pkg.Checksums, spdxChecksum{Algorithm: alg, ChecksumValue: strings.ToLower(h.Value)})
+			}
+		}
+		out.Packages = append(out.Packages, pkg)
+
+		for _, dep := range c.dependsOn {
+			out.Relationships = append(out.Relationships, spdxRelationship{
+				SPDXElementID:      ids[c.purl],
+				RelationshipType:   "DEPENDS_ON",
+				RelatedSPDXElement: ids[dep],
+			})
+		}
+	}
+
+	for _, purl := range g.direct {
+		rel := spdxRelationship{SPDXElementID: spdxRootID, RelationshipType: "DEPENDS_ON", RelatedSPDXElement: ids[purl]}
+		if dev[purl] {
+			rel = spdxRelationship{SPDXElementID: ids[purl], RelationshipType: "DEV_DEPENDENCY_OF", RelatedSPDXElement: spdxRootID}
+		}
+		out.Relationships = append(out.Relationships, rel)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(out)
+}
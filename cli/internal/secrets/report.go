@@ -0,0 +1,44 @@
+package secrets
+
+import (
+	"fmt"
+
+	"raincheck/internal/api"
+)
+
+const rotateSuggestion = "Revoke and rotate the credential, remove it from the repository and load it from the environment or a secret manager"
+
+// Category turns findings into the Secrets report category
+func Category(findings []Finding) *api.Category {
+	issues := make([]api.Issue, 0, len(findings))
+	for _, f := range findings {
+		issues = append(issues, api.Issue{
+			Severity:    f.Severity,
+			Type:        f.Kind,
+			Description: fmt.Sprintf("%s found: %s", f.Kind, f.Secret),
+			Line:        f.Line,
+			Suggestion:  rotateSuggestion,
+		})
+	}
+	return api.NewCategory(issues)
+}
+
+// Analysis returns the report entry of a file scanned for secrets
+func Analysis(findings []Finding) *api.AnalysisResponse {
+	return api.ScanAnalysis(api.CategorySecrets, Category(findings))
+}
+
+// RedactAnalysis masks credentials quoted in the descriptions and
+// suggestions of an analysis, such as a backend echoing the key it flagged
+func RedactAnalysis(resp *api.AnalysisResponse) {
+	for _, c := range resp.Categories() {
+		for i := range c.Category.Issues {
+			issue := &c.Category.Issues[i]
+			issue.Description = RedactText(issue.Description)
+			issue.Suggestion = RedactText(issue.Suggestion)
+		}
+	}
+	for i, s := range resp.Suggestions {
+		resp.Suggestions[i] = RedactText(s)
+	}
+}
@@ -0,0 +1,362 @@
+package secrets
+
+import (
+	"bytes"
+	"math"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"raincheck/internal/api"
+)
+
+// pattern detects one kind of credential. The secret is the submatch group
+// of regex, the whole match when group is 0, or the first participating
+// group when group is -1.
+type pattern struct {
+	name     string
+	severity string
+	regex    *regexp.Regexp
+	group    int
+	// requires is matched against the whole file before the pattern applies
+	requires *regexp.Regexp
+	// generic patterns also match placeholders, which are skipped
+	generic bool
+}
+
+var privateKey = regexp.MustCompile(`-----BEGIN ((RSA|DSA|EC|OPENSSH|PGP|ENCRYPTED) )?PRIVATE KEY( BLOCK)?-----`)
+
+var patterns = []pattern{
+	{
+		name:     "AWS Access Key ID",
+		severity: api.SeverityError,
+		regex:    regexp.MustCompile(`\b((?:AKIA|ASIA|AGPA|AIDA|AROA|AIPA|ANPA|ANVA)[A-Z0-9]{16})\b`),
+		group:    1,
+	},
+	{
+		name:     "AWS Secret Access Key",
+		severity: api.SeverityError,
+		regex:    regexp.MustCompile(`(?i)aws.{0,20}?(?:secret|private)?.{0,20}?['"]?\s*(?:[:=]|=>)\s*['"]?([A-Za-z0-9/+=]{40})(?:[^A-Za-z0-9/+=]|$)`),
+		group:    1,
+	},
+	{
+		name:     "GitHub Token",
+		severity: api.SeverityError,
+		regex:    regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{22,255})\b`),
+		group:    1,
+	},
+	{
+		// Before Private Key so the whole inline key is the secret
+		name:     "Firebase Service Account Key",
+		severity: api.SeverityError,
+		regex:    regexp.MustCompile(`"private_key(?:_id)?"\s*:\s*"([^"]{16,})"`),
+		group:    1,
+		requires: regexp.MustCompile(`"type"\s*:\s*"service_account"`),
+	},
+	{
+		name:     "Private Key",
+		severity: api.SeverityError,
+		regex:    privateKey,
+	},
+	{
+		name:     "Firebase Cloud Messaging Server Key",
+		severity: api.SeverityError,
+		regex:    regexp.MustCompile(`\b(AAAA[A-Za-z0-9_-]{7}:[A-Za-z0-9_-]{140})\b`),
+		group:    1,
+	},
+	{
+		name:     "Google API Key",
+		severity: api.SeverityWarning,
+		regex:    regexp.MustCompile(`\b(AIza[0-9A-Za-z_-]{35})\b`),
+		group:    1,
+	},
+	{
+		name:     "DigitalOcean Token",
+		severity: api.SeverityError,
+		regex:    regexp.MustCompile(`\b(do[por]_v1_[a-f0-9]{64})\b`),
+		group:    1,
+	},
+	{
+		name:     "Slack Token",
+		severity: api.SeverityError,
+		regex:    regexp.MustCompile(`\b(xox[baprs]-[0-9A-Za-z-]{10,})\b`),
+		group:    1,
+	},
+	{
+		name:     "Stripe Secret Key",
+		severity: api.SeverityError,
+		regex:    regexp.MustCompile(`\b([rs]k_live_[0-9A-Za-z]{24,})\b`),
+		group:    1,
+	},
+	{
+		name:     "Password Assignment",
+		severity: api.SeverityWarning,
+		group:    -1,
+		generic:  true,
+		regex:    regexp.MustCompile(`(?i)[\w.-]*(?:password|passwd|pwd|secret|api[_-]?key|access[_-]?token|auth[_-]?token)["']?\s*(?::=|=>|[:=])\s*(?:"([^"\s]{6,})"|'([^'\s]{6,})'|([^\s"'#;,()\[\]{}$<>]{6,}))`),
+	},
+}
+
+// placeholder matches values that are obviously not real credentials
+var placeholder = regexp.MustCompile(`(?i)^(\*+|x+|\.+|changeme|change_me|password|secret|example|dummy|test|sample|placeholder|redacted|null|none|true|false|undefined|your[_-].*|<.*>|\{\{.*\}\}|\$\{.*\}|%\(.*\)s?|env\(.*)$`)
+
+// entropyCandidate matches long quoted or assigned tokens worth an entropy
+// check
+var entropyCandidate = regexp.MustCompile(`(?:["'\x60]|[:=]\s*)([A-Za-z0-9+/_=-]{20,})`)
+
+var (
+	hexString = regexp.MustCompile(`^[a-fA-F0-9]+$`)
+	uuid      = regexp.MustCompile(`^[a-fA-F0-9]{8}-[a-fA-F0-9]{4}-[a-fA-F0-9]{4}-[a-fA-F0-9]{4}-[a-fA-F0-9]{12}$`)
+)
+
+// Entropy thresholds in bits per character. Random base64 approaches 6 and
+// random hex 4, while identifiers and prose stay well below.
+const (
+	base64Entropy = 4.5
+	hexEntropy    = 3.0
+	minHexLength  = 32
+)
+
+// noEntropyFiles hold checksums that look random by design
+var noEntropyFiles = map[string]bool{
+	"go.sum":            true,
+	"package-lock.json": true,
+	"yarn.lock":         true,
+	"pnpm-lock.yaml":    true,
+	"Cargo.lock":        true,
+	"poetry.lock":       true,
+	"Gemfile.lock":      true,
+	"composer.lock":     true,
+}
+
+// Finding is a credential found on a line. Secret is already redacted.
+type Finding struct {
+	Line   int
+	Kind   string
+	Secret string
+	// Severity is ERROR for known credential formats and WARNING for
+	// generic assignments and high entropy strings
+	Severity string
+}
+
+// Scan finds credentials in the content of the file at path
+func Scan(path string, content []byte) []Finding {
+	active := activePatterns(content)
+	entropy := !noEntropyFiles[filepath.Base(path)]
+
+	var findings []Finding
+	lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		for _, f := range scanLine(line, active, entropy) {
+			f.Line = i + 1
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+// activePatterns returns the patterns whose file requirement content meets
+func activePatterns(content []byte) []pattern {
+	var active []pattern
+	for _, p := range patterns {
+		if p.requires == nil || p.requires.Match(content) {
+			active = append(active, p)
+		}
+	}
+	return active
+}
+
+// ScanLine finds credentials in a single line, as when scanning history
+// where whole files are not available. File-level patterns are skipped.
+func ScanLine(path, line string) []Finding {
+	var active []pattern
+	for _, p := range patterns {
+		if p.requires == nil {
+			active = append(active, p)
+		}
+	}
+	return scanLine(line, active, !noEntropyFiles[filepath.Base(path)])
+}
+
+func scanLine(line string, active []pattern, entropy bool) []Finding {
+	var findings []Finding
+	for _, m := range matchLine(line, active, entropy) {
+		findings = append(findings, Finding{Kind: m.kind, Secret: Redact(line[m.start:m.end]), Severity: m.severity})
+	}
+	return findings
+}
+
+// match is the byte range of a secret in a line
+type match struct {
+	start, end int
+	kind       string
+	severity   string
+}
+
+// matchLine applies the patterns and, when entropy is set, the entropy check
+// to line. A secret found by a pattern is not reported again by a later one.
+func matchLine(line string, active []pattern, entropy bool) []match {
+	var matches []match
+	overlaps := func(start, end int) bool {
+		for _, m := range matches {
+			if start < m.end && end > m.start {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, p := range active {
+		for _, loc := range p.regex.FindAllStringSubmatchIndex(line, -1) {
+			start, end := secretSpan(p, loc)
+			if start < 0 || overlaps(start, end) || (p.generic && (isPlaceholder(line[start:end]) || isCode(line, start, end))) {
+				continue
+			}
+			matches = append(matches, match{start: start, end: end, kind: p.name, severity: p.severity})
+		}
+	}
+
+	if entropy {
+		for _, loc := range entropyCandidate.FindAllStringSubmatchIndex(line, -1) {
+			start, end := loc[2], loc[3]
+			if !overlaps(start, end) && highEntropy(line[start:end]) {
+				matches = append(matches, match{start: start, end: end, kind: "High Entropy String", severity: api.SeverityWarning})
+			}
+		}
+	}
+	return matches
+}
+
+// secretSpan returns the byte range of the secret in a match: the given
+// group, the first participating group when group is -1, or the whole match
+func secretSpan(p pattern, loc []int) (int, int) {
+	if p.group < 0 {
+		for g := 2; g < len(loc); g += 2 {
+			if loc[g] >= 0 {
+				return loc[g], loc[g+1]
+			}
+		}
+		return -1, -1
+	}
+	return loc[2*p.group], loc[2*p.group+1]
+}
+
+// envName matches environment variable names such as DB_PASSWORD
+var envName = regexp.MustCompile(`^[A-Z][A-Z0-9]*(_[A-Z0-9]+)+$`)
+
+// isPlaceholder reports whether a generic assignment holds a dummy value,
+// the name of an environment variable or a short plain word rather than a
+// credential
+func isPlaceholder(value string) bool {
+	if placeholder.MatchString(value) || envName.MatchString(value) {
+		return true
+	}
+	return !strings.ContainsAny(value, "0123456789!@#%^&*-_+=/.~") && len(value) < 12
+}
+
+// dottedIdent matches references such as settings.DB_PASSWORD
+var dottedIdent = regexp.MustCompile(`^[A-Za-z_]\w*(\.[A-Za-z_]\w*)+$`)
+
+// isCode reports whether an unquoted value at line[start:end] is an
+// expression such as a call or field access rather than a literal
+func isCode(line string, start, end int) bool {
+	if start > 0 && (line[start-1] == '"' || line[start-1] == '\'') {
+		return false
+	}
+	if end < len(line) && strings.ContainsRune("([{.,", rune(line[end])) {
+		return true
+	}
+	return dottedIdent.MatchString(line[start:end])
+}
+
+// highEntropy reports whether s looks like random key material rather than
+// an identifier, path or checksum-free text
+func highEntropy(s string) bool {
+	if uuid.MatchString(s) {
+		return false
+	}
+	if hexString.MatchString(s) {
+		return len(s) >= minHexLength && shannon(s) >= hexEntropy
+	}
+	// Random tokens mix letter cases and digits
+	if !strings.ContainsAny(s, "0123456789") || strings.ToLower(s) == s || strings.ToUpper(s) == s {
+		return false
+	}
+	return shannon(s) >= base64Entropy
+}
+
+// shannon returns the Shannon entropy of s in bits per character
+func shannon(s string) float64 {
+	counts := make(map[rune]int)
+	for _, r := range s {
+		counts[r]++
+	}
+	n := float64(len(s))
+	h := 0.0
+	for _, c := range counts {
+		p := float64(c) / n
+		h -= p * math.Log2(p)
+	}
+	return h
+}
+
+// Redact masks a secret, keeping a short prefix and suffix so findings can
+// still be told apart
+func Redact(secret string) string {
+	r := []rune(secret)
+	keep := len(r) / 5
+	if keep > 4 {
+		keep = 4
+	}
+	if len(r) < 8 {
+		keep = 0
+	}
+	return string(r[:keep]) + strings.Repeat("*", len(r)-2*keep) + string(r[len(r)-keep:])
+}
+
+// pemEnd ends the body of a private key block
+var pemEnd = regexp.MustCompile(`-----END [A-Z ]*PRIVATE KEY( BLOCK)?-----`)
+
+// RedactContent masks every credential found in content, for example
+// before source is shown next to findings. The body of private key blocks
+// is masked entirely.
+func RedactContent(path string, content []byte) []byte {
+	active := activePatterns(content)
+	entropy := !noEntropyFiles[filepath.Base(path)]
+
+	inKey := false
+	lines := bytes.Split(content, []byte("\n"))
+	for i, line := range lines {
+		text := string(line)
+		if inKey {
+			if pemEnd.MatchString(text) {
+				inKey = false
+			} else {
+				lines[i] = bytes.Repeat([]byte("*"), len(bytes.TrimRight(line, "\r")))
+			}
+			continue
+		}
+		if privateKey.MatchString(text) && !pemEnd.MatchString(text) {
+			inKey = true
+		}
+
+		lines[i] = []byte(redactLine(text, active, entropy))
+	}
+	return bytes.Join(lines, []byte("\n"))
+}
+
+// RedactText masks every credential found in a line of text
+func RedactText(text string) string {
+	return redactLine(text, patterns, true)
+}
+
+func redactLine(text string, active []pattern, entropy bool) string {
+	matches := matchLine(text, active, entropy)
+	// Replace from the end so earlier offsets stay valid
+	sort.Slice(matches, func(a, b int) bool { return matches[a].start > matches[b].start })
+	for _, m := range matches {
+		text = text[:m.start] + Redact(text[m.start:m.end]) + text[m.end:]
+	}
+	return text
+}
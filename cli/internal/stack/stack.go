@@ -0,0 +1,197 @@
+package stack
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+
+	"raincheck/internal/api"
+)
+
+// framework is a framework together with the dependency that reveals it
+type framework struct {
+	dependency string
+	name       string
+}
+
+var (
+	pythonFrameworks = []framework{
+		{"django", "django"},
+		{"flask", "flask"},
+		{"fastapi", "fastapi"},
+		{"tornado", "tornado"},
+		{"pyramid", "pyramid"},
+	}
+	javaFrameworks = []framework{
+		{"org.springframework.boot", "spring-boot"},
+		{"io.quarkus", "quarkus"},
+		{"io.micronaut", "micronaut"},
+	}
+)
+
+// frameworks lists, by manifest file name, the frameworks a project
+// declaring the dependency is built on
+var frameworks = map[string][]framework{
+	"package.json": {
+		{"next", "next.js"},
+		{"react", "react"},
+		{"vue", "vue"},
+		{"@angular/core", "angular"},
+		{"svelte", "svelte"},
+		{"express", "express"},
+		{"koa", "koa"},
+		{"fastify", "fastify"},
+		{"@nestjs/core", "nestjs"},
+		{"@hapi/hapi", "hapi"},
+	},
+	"go.mod": {
+		{"github.com/gin-gonic/gin", "gin"},
+		{"github.com/labstack/echo", "echo"},
+		{"github.com/gofiber/fiber", "fiber"},
+		{"github.com/go-chi/chi", "chi"},
+		{"github.com/gorilla/mux", "gorilla/mux"},
+		{"github.com/spf13/cobra", "cobra"},
+		{"google.golang.org/grpc", "grpc"},
+	},
+	"requirements.txt": pythonFrameworks,
+	"pyproject.toml":   pythonFrameworks,
+	"Pipfile":          pythonFrameworks,
+	"Cargo.toml": {
+		{"actix-web", "actix-web"},
+		{"axum", "axum"},
+		{"rocket", "rocket"},
+		{"warp", "warp"},
+	},
+	"pom.xml":          javaFrameworks,
+	"build.gradle":     javaFrameworks,
+	"build.gradle.kts": javaFrameworks,
+	"Gemfile": {
+		{"rails", "rails"},
+		{"sinatra", "sinatra"},
+	},
+	"composer.json": {
+		{"laravel/framework", "laravel"},
+		{"symfony/framework-bundle", "symfony"},
+	},
+}
+
+// dependencyPatterns match each framework's dependency as a whole name in
+// the text of a manifest, by dependency
+var dependencyPatterns = make(map[string]*regexp.Regexp)
+
+func init() {
+	for _, list := range frameworks {
+		for _, f := range list {
+			dependencyPatterns[f.dependency] = regexp.MustCompile(`(?i)(^|[^\w.@/-])` + regexp.QuoteMeta(f.dependency) + `($|[^\w.-])`)
+		}
+	}
+}
+
+var goModule = regexp.MustCompile(`(?m)^module\s+"?([^\s"]+)`)
+
+// Detector finds the project context of files from the manifests in their
+// directory and the directories above it, up to the repository root. It is
+// safe for concurrent use and reads each directory once.
+type Detector struct {
+	mu   sync.Mutex
+	dirs map[string]*api.ProjectContext
+}
+
+// NewDetector returns a detector with an empty cache
+func NewDetector() *Detector {
+	return &Detector{dirs: make(map[string]*api.ProjectContext)}
+}
+
+// Context returns the context of the project the file at path belongs to,
+// or nil when no manifest reveals anything about it
+func (d *Detector) Context(path string) *api.ProjectContext {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	ctx := d.dir(filepath.Dir(abs))
+	if ctx.GoModule == "" && len(ctx.Frameworks) == 0 {
+		return nil
+	}
+	return ctx
+}
+
+// dir returns the context of dir, merging its manifests into the context
+// of its parent. The nearest go.mod wins.
+func (d *Detector) dir(dir string) *api.ProjectContext {
+	if ctx, ok := d.dirs[dir]; ok {
+		return ctx
+	}
+
+	ctx := &api.ProjectContext{}
+	parent := filepath.Dir(dir)
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil && parent != dir {
+		inherited := d.dir(parent)
+		ctx.GoModule = inherited.GoModule
+		ctx.Frameworks = append(ctx.Frameworks, inherited.Frameworks...)
+	}
+
+	found := make(map[string]bool)
+	for _, name := range ctx.Frameworks {
+		found[name] = true
+	}
+	for manifest, list := range frameworks {
+		content, err := os.ReadFile(filepath.Join(dir, manifest))
+		if err != nil {
+			continue
+		}
+		if manifest == "go.mod" {
+			if m := goModule.FindSubmatch(content); m != nil {
+				ctx.GoModule = string(m[1])
+			}
+		}
+		// Names in JSON manifests are looked up among the declared
+		// dependencies, since any word may turn up in a description
+		var declared map[string]bool
+		if filepath.Ext(manifest) == ".json" {
+			declared = jsonDependencies(content)
+		}
+		for _, f := range list {
+			var uses bool
+			if declared != nil {
+				uses = declared[f.dependency]
+			} else {
+				uses = dependencyPatterns[f.dependency].Match(content)
+			}
+			if !found[f.name] && uses {
+				found[f.name] = true
+				ctx.Frameworks = append(ctx.Frameworks, f.name)
+			}
+		}
+	}
+	sort.Strings(ctx.Frameworks)
+
+	d.dirs[dir] = ctx
+	return ctx
+}
+
+// jsonDependencies returns the names of the packages a package.json or
+// composer.json declares
+func jsonDependencies(content []byte) map[string]bool {
+	var manifest map[string]json.RawMessage
+	names := make(map[string]bool)
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return names
+	}
+	for _, key := range []string{"dependencies", "devDependencies", "peerDependencies", "require", "require-dev"} {
+		var section map[string]json.RawMessage
+		if json.Unmarshal(manifest[key], &section) != nil {
+			continue
+		}
+		for name := range section {
+			names[name] = true
+		}
+	}
+	return names
+}
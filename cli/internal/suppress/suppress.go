@@ -0,0 +1,176 @@
+package suppress
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"raincheck/internal/api"
+	"raincheck/internal/report"
+)
+
+// Directives recognized after the raincheck: prefix
+const (
+	// Ignore suppresses findings on the line of the comment
+	Ignore = "ignore"
+	// IgnoreNextLine suppresses findings on the line after the comment
+	IgnoreNextLine = "ignore-next-line"
+	// IgnoreFile suppresses findings anywhere in the file
+	IgnoreFile = "ignore-file"
+)
+
+// commentMarkers lists the comment prefixes of each language. Files with
+// other extensions accept any of the known markers.
+var commentMarkers = map[string][]string{
+	".go":    {"//", "/*"},
+	".js":    {"//", "/*"},
+	".jsx":   {"//", "/*"},
+	".ts":    {"//", "/*"},
+	".tsx":   {"//", "/*"},
+	".java":  {"//", "/*"},
+	".cpp":   {"//", "/*"},
+	".c":     {"//", "/*"},
+	".h":     {"//", "/*"},
+	".hpp":   {"//", "/*"},
+	".swift": {"//", "/*"},
+	".kt":    {"//", "/*"},
+	".rs":    {"//", "/*"},
+	".php":   {"//", "/*", "#"},
+	".py":    {"#"},
+	".rb":    {"#"},
+}
+
+var allMarkers = []string{"//", "/*", "#", "--", ";"}
+
+var (
+	directive = regexp.MustCompile(`raincheck:(ignore-next-line|ignore-file|ignore)\b`)
+	reason    = regexp.MustCompile(`reason\s*=\s*(?:"([^"]*)"|'([^']*)'|(\S+))`)
+)
+
+// suppression is a parsed comment together with the line it applies to
+type suppression struct {
+	report.Suppression
+	target  int // suppressed line, 0 for the whole file
+	matched int
+}
+
+// parse finds the raincheck:ignore comments in a file. Each comment may list
+// comma-separated categories or issue types to suppress, followed by an
+// optional reason="..."; without a list it suppresses every finding.
+func parse(path string, content []byte) []*suppression {
+	markers, ok := commentMarkers[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		markers = allMarkers
+	}
+
+	var result []*suppression
+	lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		loc := directive.FindStringSubmatchIndex(line)
+		if loc == nil || !afterMarker(line[:loc[0]], markers) {
+			continue
+		}
+
+		s := &suppression{Suppression: report.Suppression{
+			Line:      i + 1,
+			Directive: line[loc[2]:loc[3]],
+		}}
+		switch s.Directive {
+		case Ignore:
+			s.target = i + 1
+		case IgnoreNextLine:
+			s.target = i + 2
+		}
+
+		rest := line[loc[1]:]
+		for _, end := range []string{"*/", "-->"} {
+			if j := strings.Index(rest, end); j >= 0 {
+				rest = rest[:j]
+			}
+		}
+		if m := reason.FindStringSubmatchIndex(rest); m != nil {
+			for g := 2; g < len(m); g += 2 {
+				if m[g] >= 0 {
+					s.Reason = rest[m[g]:m[g+1]]
+					break
+				}
+			}
+			rest = rest[:m[0]] + rest[m[1]:]
+		}
+		for _, sel := range strings.Split(rest, ",") {
+			if sel = strings.TrimSpace(sel); sel != "" {
+				s.Selectors = append(s.Selectors, sel)
+			}
+		}
+
+		result = append(result, s)
+	}
+	return result
+}
+
+// afterMarker reports whether prefix ends with a comment marker, so that
+// directives inside string literals or ordinary text are not picked up
+func afterMarker(prefix string, markers []string) bool {
+	prefix = strings.TrimRight(prefix, " \t")
+	for _, m := range markers {
+		if strings.HasSuffix(prefix, m) {
+			return true
+		}
+		// Continuation lines of block comments start with "*"
+		if m == "/*" && strings.TrimSpace(prefix) == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply moves the findings of f that are covered by an inline suppression in
+// content into f.Suppressed and records suppressions that matched nothing in
+// f.StaleSuppressions
+func Apply(f *report.File, content []byte) {
+	sups := parse(f.Path, content)
+	if len(sups) == 0 {
+		return
+	}
+
+	f.Analysis.FilterIssues(func(category string, issue api.Issue) bool {
+		for _, s := range sups {
+			if (s.target == 0 || s.target == issue.Line) && s.matches(category, issue) {
+				s.matched++
+				f.Suppressed = append(f.Suppressed, report.SuppressedFinding{
+					Finding:     report.Finding{Category: category, Issue: issue},
+					Suppression: s.Suppression,
+				})
+				return false
+			}
+		}
+		return true
+	})
+
+	for _, s := range sups {
+		if s.matched == 0 {
+			f.StaleSuppressions = append(f.StaleSuppressions, s.Suppression)
+		}
+	}
+}
+
+// matches reports whether a finding is selected by the suppression's
+// categories or issue types
+func (s *suppression) matches(category string, issue api.Issue) bool {
+	if len(s.Selectors) == 0 {
+		return true
+	}
+	for _, sel := range s.Selectors {
+		sel = normalize(sel)
+		if sel == "all" || sel == "*" || sel == normalize(category) || sel == normalize(issue.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalize makes "Code Quality", "code_quality" and "code-quality" equal
+func normalize(s string) string {
+	s = strings.NewReplacer("_", " ", "-", " ").Replace(strings.ToLower(s))
+	return strings.Join(strings.Fields(s), " ")
+}
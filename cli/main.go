@@ -1,18 +1,43 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"slices"
 	"strings"
 	"syscall"
 	"time"
 
+	"raincheck/internal/advisory"
 	"raincheck/internal/api"
+	"raincheck/internal/baseline"
+	"raincheck/internal/cache"
 	"raincheck/internal/config"
+	"raincheck/internal/dashboard"
+	"raincheck/internal/deps"
+	"raincheck/internal/gitdiff"
+	"raincheck/internal/ignore"
+	"raincheck/internal/lastrun"
+	"raincheck/internal/license"
+	"raincheck/internal/patch"
+	"raincheck/internal/policy"
+	"raincheck/internal/project"
+	"raincheck/internal/report"
+	"raincheck/internal/review"
+	"raincheck/internal/rules"
+	"raincheck/internal/sbom"
+	"raincheck/internal/secrets"
+	"raincheck/internal/stack"
+	"raincheck/internal/suppress"
 
 	"github.com/spf13/cobra"
 )
@@ -20,53 +45,448 @@ import (
 var rootCmd = &cobra.Command{
 	Use:   "raincheck",
 	Short: "Raincheck is a code review tool",
-	Long:  `A command line tool for managing and automating code reviews.`,
+	// Errors from a run are not usage errors, so do not print usage for them
+	SilenceUsage: true,
+	Long: `A command line tool for managing and automating code reviews.
+
+Exit codes:
+  0  success
+  1  usage or unexpected error
+  2  policy violated (--fail-on, --min-score, --min-<category>)
+  3  one or more files could not be analyzed
+  4  authentication failed or no API key configured
+
+Environment:
+  RAINCHECK_SERVER      backend URL, overriding the profile and project settings
+  RAINCHECK_PROFILE     credential profile to use instead of the current one
+  RAINCHECK_API_KEY     API key to use instead of the credential store (for CI)
+  RAINCHECK_PASSPHRASE  passphrase of a protected credential store`,
 }
 
+// Environment variables that select the backend
+const (
+	envServer  = "RAINCHECK_SERVER"
+	envProfile = "RAINCHECK_PROFILE"
+)
+
 var loginCmd = &cobra.Command{
 	Use:   "login [apikey]",
 	Short: "Store API key for authentication",
-	Args:  cobra.ExactArgs(1),
+	Long: `Store an API key in a named profile (default: "default"). With --server the
+profile also remembers the backend URL, e.g.
+
+  raincheck login --profile staging --server https://staging.example.com <key>`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		apiKey := args[0]
-		if err := config.SaveAPIKey(apiKey); err != nil {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load credentials: %w", err)
+		}
+
+		if cmd.Flags().Changed("passphrase") {
+			protect, _ := cmd.Flags().GetBool("passphrase")
+			passphrase := ""
+			if protect {
+				if passphrase, err = config.NewPassphrase(); err != nil {
+					return err
+				}
+			}
+			cfg.SetPassphrase(passphrase)
+		}
+
+		name := cfg.ActiveName(profileFlag(cmd))
+		profile := config.Profile{APIKey: args[0]}
+		if cmd.Flags().Changed("server") {
+			profile.Server, _ = cmd.Flags().GetString("server")
+		} else if existing, ok := cfg.Profiles[name]; ok {
+			profile.Server = existing.Server
+		}
+		cfg.SetProfile(name, profile)
+
+		if err := cfg.Save(); err != nil {
 			return fmt.Errorf("failed to save API key: %w", err)
 		}
-		log.Printf("API key stored successfully")
+		log.Printf("API key stored successfully in profile %q", name)
+		return nil
+	},
+}
+
+var logoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Remove stored API keys",
+	Long: `Remove the API key of the selected profile (default: the current profile).
+With --all the whole credential store is deleted.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if all, _ := cmd.Flags().GetBool("all"); all {
+			if err := config.Remove(); err != nil {
+				return err
+			}
+			fmt.Println("✓ Removed all stored credentials")
+			return nil
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load credentials: %w", err)
+		}
+
+		name := cfg.ActiveName(profileFlag(cmd))
+		if !cfg.DeleteProfile(name) {
+			return fmt.Errorf("profile %q does not exist", name)
+		}
+
+		if len(cfg.Profiles) == 0 {
+			err = config.Remove()
+		} else {
+			err = cfg.Save()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to save credentials: %w", err)
+		}
+
+		fmt.Printf("✓ Logged out of profile %q\n", name)
+		return nil
+	},
+}
+
+var whoamiCmd = &cobra.Command{
+	Use:   "whoami",
+	Short: "Show the active credentials and check them against the backend",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		proj, err := loadProject(cmd)
+		if err != nil {
+			return err
+		}
+
+		name, profile, err := credentials(cmd)
+		if err != nil {
+			return err
+		}
+		client, err := newClient(cmd, proj)
+		if err != nil {
+			return err
+		}
+
+		if name == config.EnvAPIKey {
+			fmt.Printf("Profile: none (key from %s)\n", config.EnvAPIKey)
+		} else {
+			fmt.Printf("Profile: %s\n", name)
+		}
+		fmt.Printf("Server:  %s\n", client.BaseURL())
+		fmt.Printf("API key: %s\n", maskKey(profile.APIKey))
+
+		if err := client.VerifyKey(cmd.Context()); err != nil {
+			if api.IsAuthError(err) {
+				return &exitError{code: exitAuthError, err: fmt.Errorf("the backend rejected the API key: %w", err)}
+			}
+			return fmt.Errorf("failed to verify API key: %w", err)
+		}
+
+		fmt.Println("✓ API key is valid")
+		return nil
+	},
+}
+
+// maskKey hides all but the ends of an API key
+func maskKey(key string) string {
+	if len(key) <= 8 {
+		return strings.Repeat("*", len(key))
+	}
+	return key[:4] + strings.Repeat("*", len(key)-8) + key[len(key)-4:]
+}
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage named backend profiles",
+}
+
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List stored profiles",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load credentials: %w", err)
+		}
+		if len(cfg.Profiles) == 0 {
+			fmt.Println("No profiles stored. Run 'raincheck login <apikey>' to create one.")
+			return nil
+		}
+
+		active := cfg.ActiveName(profileFlag(cmd))
+		for _, name := range cfg.ProfileNames() {
+			marker := " "
+			if name == active {
+				marker = "*"
+			}
+			server := cfg.Profiles[name].Server
+			if server == "" {
+				server = "(default server)"
+			}
+			fmt.Printf("%s %-20s %s\n", marker, name, server)
+		}
+		return nil
+	},
+}
+
+var profileUseCmd = &cobra.Command{
+	Use:   "use [profile]",
+	Short: "Make a profile the current one",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load credentials: %w", err)
+		}
+
+		name := args[0]
+		if _, ok := cfg.Profiles[name]; !ok {
+			return fmt.Errorf("profile %q does not exist (run 'raincheck login --profile %s <apikey>' to create it)", name, name)
+		}
+		cfg.Current = name
+		if err := cfg.Save(); err != nil {
+			return fmt.Errorf("failed to save credentials: %w", err)
+		}
+
+		fmt.Printf("✓ Now using profile %q\n", name)
 		return nil
 	},
 }
 
+// profileFlag returns the profile selected by --profile or RAINCHECK_PROFILE,
+// empty when the current profile should be used
+func profileFlag(cmd *cobra.Command) string {
+	if cmd.Flags().Changed("profile") {
+		name, _ := cmd.Flags().GetString("profile")
+		return name
+	}
+	return os.Getenv(envProfile)
+}
+
 var reviewCmd = &cobra.Command{
 	Use:   "review",
 	Short: "Review code files",
 }
 
-func printCategory(name string, category api.Category) {
-	fmt.Printf("\n%s (Score: %.1f/10)\n", strings.ToUpper(name), category.Score)
-	fmt.Println(strings.Repeat("-", len(name)+15))
+// outputOptions holds the --format and --output flags shared by review commands
+type outputOptions struct {
+	format string
+	output string
+}
+
+func addOutputFlags(cmd *cobra.Command, defaultFormat string) {
+	cmd.Flags().StringP("format", "f", defaultFormat, "Output format: "+strings.Join(report.Formats, "|"))
+	cmd.Flags().StringP("output", "o", "", "Write the report to this path ('-' for stdout)")
+}
+
+// getOutputOptions reads the output flags, falling back to the project's
+// default format and defaulting the destination for the markdown format to
+// defaultMarkdownPath and everything else to stdout
+func getOutputOptions(cmd *cobra.Command, proj *project.Config, defaultMarkdownPath string) (outputOptions, error) {
+	format, _ := cmd.Flags().GetString("format")
+	output, _ := cmd.Flags().GetString("output")
 
-	if len(category.Issues) == 0 {
-		fmt.Println("✓ No issues found")
-		return
+	if !cmd.Flags().Changed("format") && proj.Format != "" {
+		format = proj.Format
+	}
+
+	format = strings.ToLower(format)
+	if !report.ValidFormat(format) {
+		return outputOptions{}, fmt.Errorf("unknown format %q (supported: %s)", format, strings.Join(report.Formats, ", "))
+	}
+
+	if output == "" {
+		output = "-"
+		if format == report.FormatMarkdown && defaultMarkdownPath != "" {
+			output = defaultMarkdownPath
+		}
+	}
+
+	return outputOptions{format: format, output: output}, nil
+}
+
+func (o outputOptions) toStdout() bool {
+	return o.output == "-"
+}
+
+// loadProject finds the project configuration for the working directory
+// (or loads the file given by --config) and applies flag overrides to it
+func loadProject(cmd *cobra.Command) (*project.Config, error) {
+	var (
+		proj *project.Config
+		err  error
+	)
+
+	if path, _ := cmd.Flags().GetString("config"); path != "" {
+		proj, err = project.Load(path)
+	} else {
+		dir, wdErr := os.Getwd()
+		if wdErr != nil {
+			return nil, fmt.Errorf("failed to get current directory: %w", wdErr)
+		}
+		proj, err = project.Find(dir)
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	for _, issue := range category.Issues {
-		severityColor := "🔵" // INFO
-		if issue.Severity == "WARNING" {
-			severityColor = "🟡"
-		} else if issue.Severity == "ERROR" {
-			severityColor = "🔴"
+	flags := cmd.Flags()
+	if flags.Changed("timeout") {
+		proj.Timeout, _ = flags.GetDuration("timeout")
+	}
+	if flags.Changed("retries") {
+		retries, _ := flags.GetInt("retries")
+		proj.Retries = &retries
+	}
+	if flags.Changed("include") {
+		proj.Include, _ = flags.GetStringSlice("include")
+	}
+	if flags.Changed("exclude") {
+		proj.Exclude, _ = flags.GetStringSlice("exclude")
+	}
+	if flags.Changed("ext") {
+		exts, _ := flags.GetStringSlice("ext")
+		proj.Extensions = nil
+		proj.AddExtensions(exts)
+	}
+	if flags.Changed("max-file-size") {
+		value, _ := flags.GetString("max-file-size")
+		if proj.MaxFileSize, err = project.ParseSize(value); err != nil {
+			return nil, fmt.Errorf("invalid --max-file-size: %w", err)
 		}
+	}
+	if flags.Changed("min-severity") {
+		proj.MinSeverity, _ = flags.GetString("min-severity")
+	}
+	if flags.Changed("engine") {
+		proj.Engine, _ = flags.GetString("engine")
+	}
+	if local, _ := flags.GetBool("local"); local {
+		proj.Engine = project.EngineLocal
+	}
 
-		fmt.Printf("%s [%s] %s\n", severityColor, issue.Type, issue.Description)
-		if issue.Line > 0 {
-			fmt.Printf("   Line: %d\n", issue.Line)
+	if flags.Changed("fail-on") {
+		proj.FailOn, _ = flags.GetString("fail-on")
+	}
+	if flags.Changed("min-score") {
+		proj.MinScore, _ = flags.GetFloat64("min-score")
+	}
+	for _, key := range policy.CategoryKeys() {
+		name := "min-" + strings.ReplaceAll(key, "_", "-")
+		if flags.Changed(name) {
+			if proj.MinScores == nil {
+				proj.MinScores = make(map[string]float64)
+			}
+			proj.MinScores[key], _ = flags.GetFloat64(name)
 		}
-		if issue.Suggestion != "" {
-			fmt.Printf("   💡 Suggestion: %s\n", issue.Suggestion)
+	}
+
+	if proj.Timeout < 0 {
+		return nil, fmt.Errorf("invalid timeout %s (must not be negative)", proj.Timeout)
+	}
+	if proj.Retries != nil && *proj.Retries < 0 {
+		return nil, fmt.Errorf("invalid retries %d (must not be negative)", *proj.Retries)
+	}
+	if !project.ValidEngine(proj.Engine) {
+		return nil, fmt.Errorf("invalid engine %q (expected remote, local or hybrid)", proj.Engine)
+	}
+	if proj.MinSeverity != "" && !api.ValidSeverity(proj.MinSeverity) {
+		return nil, fmt.Errorf("invalid minimum severity %q (expected INFO, WARNING or ERROR)", proj.MinSeverity)
+	}
+	if proj.Licenses.Unknown != "" && !api.ValidSeverity(proj.Licenses.Unknown) {
+		return nil, fmt.Errorf("invalid unknown license severity %q (expected INFO, WARNING or ERROR)", proj.Licenses.Unknown)
+	}
+	if err := reviewPolicy(proj).Validate(); err != nil {
+		return nil, err
+	}
+
+	return proj, nil
+}
+
+// credentials returns the name and contents of the profile to use.
+// RAINCHECK_API_KEY takes precedence over the credential store so CI jobs
+// need neither a login nor a passphrase.
+func credentials(cmd *cobra.Command) (string, config.Profile, error) {
+	if key := os.Getenv(config.EnvAPIKey); key != "" {
+		return config.EnvAPIKey, config.Profile{APIKey: key}, nil
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return "", config.Profile{}, &exitError{code: exitAuthError, err: fmt.Errorf("failed to load credentials: %w", err)}
+	}
+	name := cfg.ActiveName(profileFlag(cmd))
+	profile, err := cfg.Profile(name)
+	if err != nil {
+		return "", config.Profile{}, &exitError{code: exitAuthError, err: fmt.Errorf("authentication required: %w", err)}
+	}
+	return name, profile, nil
+}
+
+// newClient creates an API client using the selected credentials. The
+// server is taken from --server, RAINCHECK_SERVER, the profile, the project
+// settings or the default backend, in that order.
+func newClient(cmd *cobra.Command, proj *project.Config) (*api.Client, error) {
+	_, profile, err := credentials(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	server := proj.Server
+	if profile.Server != "" {
+		server = profile.Server
+	}
+	if env := os.Getenv(envServer); env != "" {
+		server = env
+	}
+	if cmd.Flags().Changed("server") {
+		server, _ = cmd.Flags().GetString("server")
+	}
+
+	client := api.NewClient(server, profile.APIKey)
+	if proj.Timeout > 0 {
+		client.Timeout = proj.Timeout
+	}
+	if proj.Retries != nil {
+		client.Retry.MaxAttempts = *proj.Retries + 1
+	}
+	// Only the review commands have --jobs
+	client.Jobs, _ = cmd.Flags().GetBool("jobs")
+	return client, nil
+}
+
+// filterSeverity drops issues below the project's minimum severity from
+// the new and suppressed issues of f
+func filterSeverity(proj *project.Config, f *report.File) {
+	if proj.MinSeverity == "" {
+		return
+	}
+	min := api.SeverityRank(proj.MinSeverity)
+	f.Analysis.FilterIssues(func(_ string, issue api.Issue) bool {
+		return api.SeverityRank(issue.Severity) >= min
+	})
+
+	kept := f.Suppressed[:0]
+	for _, s := range f.Suppressed {
+		if api.SeverityRank(s.Issue.Severity) >= min {
+			kept = append(kept, s)
 		}
-		fmt.Println()
+	}
+	f.Suppressed = kept
+}
+
+// applyFindingFilters applies inline suppressions, the minimum severity and
+// the baseline, when given, to the findings of f. Suppressions are applied
+// first so that comments silencing low severity issues are not reported as
+// stale.
+func applyFindingFilters(proj *project.Config, bl *baseline.Baseline, f *report.File, absPath string, content []byte) {
+	suppress.Apply(f, content)
+	filterSeverity(proj, f)
+	if bl != nil {
+		bl.Apply(f, proj.Rel(absPath), content)
 	}
 }
 
@@ -77,53 +497,54 @@ var reviewFileCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		filename := args[0]
 
+		proj, err := loadProject(cmd)
+		if err != nil {
+			return err
+		}
+
+		out, err := getOutputOptions(cmd, proj, "")
+		if err != nil {
+			return err
+		}
+
 		// Read file content
 		content, err := os.ReadFile(filename)
 		if err != nil {
 			return fmt.Errorf("failed to read file: %w", err)
 		}
 
-		// Get API key
-		apiKey, err := config.GetAPIKey()
+		analyze, err := newAnalyzer(cmd, proj)
 		if err != nil {
-			return fmt.Errorf("authentication required: %w", err)
+			return err
 		}
 
-		// Create API client
-		client := api.NewClient(apiKey)
-
 		// Analyze code
-		resp, err := client.AnalyzeCode(string(content))
+		resp, err := analyze(cmd.Context(), review.Target{Path: filename, AbsPath: filename}, content)
 		if err != nil {
-			return fmt.Errorf("failed to analyze code: %w", err)
+			return analysisError(err)
 		}
 
-		// Print analysis results
-		fmt.Printf("\n📊 Code Analysis Report for %s\n", filename)
-		fmt.Println(strings.Repeat("=", 50))
+		bl, blPath, err := loadBaseline(cmd, proj)
+		if err != nil {
+			return err
+		}
 
-		// Overall Score
-		fmt.Printf("\n🏆 Overall Score: %.1f/10\n", resp.OverallScore)
-		fmt.Println(strings.Repeat("-", 30))
+		file := report.File{Path: filename, Analysis: resp}
+		applyFindingFilters(proj, bl, &file, filename, content)
 
-		// Print each category
-		printCategory("Security", resp.Security)
-		printCategory("Performance", resp.Performance)
-		printCategory("Code Quality", resp.CodeQuality)
-		printCategory("Maintainability", resp.Maintainability)
-		printCategory("Best Practices", resp.BestPractices)
+		rep := report.New()
+		rep.Baseline = blPath
+		rep.Dir, _ = os.Getwd()
+		rep.AddFile(file)
 
-		// Print suggestions
-		if len(resp.Suggestions) > 0 {
-			fmt.Printf("\n💡 General Suggestions\n")
-			fmt.Println(strings.Repeat("-", 20))
-			for _, suggestion := range resp.Suggestions {
-				fmt.Printf("• %s\n", suggestion)
-			}
+		if err := report.WriteFile(out.output, out.format, rep); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
 		}
-
-		fmt.Println("\n" + strings.Repeat("=", 50))
-		return nil
+		if !out.toStdout() {
+			fmt.Printf("\n📝 Report has been saved to %s\n", out.output)
+		}
+		saveRun(proj, rep, progressWriter(out))
+		return enforcePolicy(proj, rep, nil, progressWriter(out))
 	},
 }
 
@@ -163,103 +584,249 @@ func shouldSkipDir(path string) bool {
 	return skipDirs[filepath.Base(path)]
 }
 
-func writeMarkdownReport(reports []struct {
-	path     string
-	response *api.AnalysisResponse
-}, stats struct {
-	totalFiles      int
-	filesWithIssues int
-	totalIssues     int
-	overallScore    float64
-}) error {
-	file, err := os.Create("SCAN.md")
-	if err != nil {
-		return fmt.Errorf("failed to create SCAN.md: %w", err)
+// isReviewable reports whether a file is selected for review by the built-in
+// code extensions and the project's include, exclude and size settings
+func isReviewable(proj *project.Config, path string, size int64) bool {
+	if !isCodeFile(path) && !proj.HasExtension(path) {
+		return false
 	}
-	defer file.Close()
+	return proj.Included(path) && !proj.Excluded(path) && !proj.TooLarge(size)
+}
 
-	// Write header
-	fmt.Fprintf(file, "# Code Review Report\n\n")
-	fmt.Fprintf(file, "Generated on: %s\n\n", time.Now().Format("2006-01-02 15:04:05"))
+// collectTargets walks dir and returns every reviewable code file in walk
+// order, skipping paths matched by ignored when it is non-nil
+func collectTargets(dir string, proj *project.Config, ignored *ignore.Matcher) ([]review.Target, error) {
+	return walkFiles(dir, proj, ignored, func(path string, size int64) bool {
+		return isReviewable(proj, path, size)
+	})
+}
 
-	// Write summary
-	fmt.Fprintf(file, "## Summary\n\n")
-	fmt.Fprintf(file, "- Total Files Scanned: %d\n", stats.totalFiles)
-	fmt.Fprintf(file, "- Files with Issues: %d\n", stats.filesWithIssues)
-	fmt.Fprintf(file, "- Total Issues Found: %d\n", stats.totalIssues)
-	if stats.totalFiles > 0 {
-		fmt.Fprintf(file, "- Average Score: %.1f/10\n\n", stats.overallScore/float64(stats.totalFiles))
-	}
+// walkFiles walks dir and returns the files selected by keep in walk order,
+// skipping the same directories and ignored paths as reviews
+func walkFiles(dir string, proj *project.Config, ignored *ignore.Matcher, keep func(path string, size int64) bool) ([]review.Target, error) {
+	var targets []review.Target
 
-	// Write detailed reports
-	fmt.Fprintf(file, "## Detailed Reports\n\n")
-	for _, report := range reports {
-		fmt.Fprintf(file, "### %s\n\n", report.path)
-		fmt.Fprintf(file, "**Overall Score:** %.1f/10\n\n", report.response.OverallScore)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
 
-		// Write each category
-		categories := map[string]api.Category{
-			"Security":        report.response.Security,
-			"Performance":     report.response.Performance,
-			"Code Quality":    report.response.CodeQuality,
-			"Maintainability": report.response.Maintainability,
-			"Best Practices":  report.response.BestPractices,
+		// Skip directories
+		if info.IsDir() {
+			if path != dir && (shouldSkipDir(path) || proj.Excluded(path) || (ignored != nil && ignored.Ignored(path, true))) {
+				return filepath.SkipDir
+			}
+			return nil
 		}
 
-		for name, category := range categories {
-			fmt.Fprintf(file, "#### %s (Score: %.1f/10)\n\n", name, category.Score)
+		if ignored != nil && ignored.Ignored(path, false) {
+			return nil
+		}
 
-			if len(category.Issues) == 0 {
-				fmt.Fprintf(file, "✓ No issues found\n\n")
-				continue
+		if !keep(path, info.Size()) {
+			return nil
+		}
+
+		// Get relative path
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		targets = append(targets, review.Target{Path: relPath, AbsPath: path})
+		return nil
+	})
+
+	return targets, err
+}
+
+// ignoreMatcher returns the matcher for .gitignore and .raincheckignore files,
+// rooted at the git work tree or else at the project root, or nil when
+// --no-ignore is set
+func ignoreMatcher(cmd *cobra.Command, proj *project.Config) *ignore.Matcher {
+	if noIgnore, _ := cmd.Flags().GetBool("no-ignore"); noIgnore {
+		return nil
+	}
+
+	root, err := gitdiff.RepoRoot(cmd.Context(), proj.Root)
+	if err != nil {
+		root = proj.Root
+	}
+	return ignore.New(root, ignore.GitIgnore, ignore.RaincheckIgnore)
+}
+
+// newAnalyzer returns the analyzer for the project's engine. Credentials
+// are only required when the backend is used.
+func newAnalyzer(cmd *cobra.Command, proj *project.Config) (review.Analyzer, error) {
+	if proj.Engine == project.EngineLocal {
+		return engineAnalyzer(proj, nil), nil
+	}
+
+	client, err := newClient(cmd, proj)
+	if err != nil {
+		return nil, err
+	}
+	return engineAnalyzer(proj, remoteAnalyzer(proj, client, openCache(cmd))), nil
+}
+
+// engineAnalyzer combines the remote analyzer with the built-in rules as the
+// project's engine requires. Every engine also reports committed secrets and
+// redacts credentials quoted in the findings.
+func engineAnalyzer(proj *project.Config, remote review.Analyzer) review.Analyzer {
+	analyze := remote
+	switch proj.Engine {
+	case project.EngineLocal:
+		analyze = func(_ context.Context, target review.Target, content []byte) (*api.AnalysisResponse, error) {
+			return rules.Analyze(target.Path, content), nil
+		}
+	case project.EngineHybrid:
+		analyze = func(ctx context.Context, target review.Target, content []byte) (*api.AnalysisResponse, error) {
+			resp, err := remote(ctx, target, content)
+			if err != nil {
+				return nil, err
 			}
+			return rules.Merge(resp, rules.Analyze(target.Path, content)), nil
+		}
+	}
 
-			for _, issue := range category.Issues {
-				severity := "🔵 INFO"
-				if issue.Severity == "WARNING" {
-					severity = "🟡 WARNING"
-				} else if issue.Severity == "ERROR" {
-					severity = "🔴 ERROR"
-				}
+	return func(ctx context.Context, target review.Target, content []byte) (*api.AnalysisResponse, error) {
+		resp, err := analyze(ctx, target, content)
+		if err != nil {
+			return nil, err
+		}
+		secrets.RedactAnalysis(resp)
+		if found := secrets.Scan(target.Path, content); len(found) > 0 {
+			resp.Secrets = secrets.Category(found)
+		}
+		return resp, nil
+	}
+}
 
-				fmt.Fprintf(file, "- %s [%s] %s\n", severity, issue.Type, issue.Description)
-				if issue.Line > 0 {
-					fmt.Fprintf(file, "  - Line: %d\n", issue.Line)
-				}
-				if issue.Suggestion != "" {
-					fmt.Fprintf(file, "  - Suggestion: %s\n", issue.Suggestion)
+// remoteAnalyzer analyzes files with the backend API, telling it the
+// language, path and project of each file
+func remoteAnalyzer(proj *project.Config, client *api.Client, results *cache.Cache) review.Analyzer {
+	projects := stack.NewDetector()
+	return func(ctx context.Context, target review.Target, content []byte) (*api.AnalysisResponse, error) {
+		path := proj.Rel(target.AbsPath)
+		if path == "" {
+			path = filepath.ToSlash(target.Path)
+		}
+		req := api.CodeRequest{
+			Code:     string(content),
+			Filename: filepath.Base(target.Path),
+			Language: rules.Language(target.Path, content),
+			Path:     path,
+			Context:  projects.Context(target.AbsPath),
+		}
+
+		// Results are cached per backend analysis version, so a new prompt
+		// or model on the backend invalidates them. Without a version the
+		// file is analyzed uncached.
+		var key, version string
+		if results != nil {
+			var err error
+			if version, err = client.AnalysisVersion(ctx); err == nil {
+				key = cache.Key(client.BaseURL(), version, req)
+				if resp, ok := results.Get(key); ok {
+					return resp, nil
 				}
-				fmt.Fprintf(file, "\n")
 			}
 		}
 
-		// Write suggestions
-		if len(report.response.Suggestions) > 0 {
-			fmt.Fprintf(file, "#### General Suggestions\n\n")
-			for _, suggestion := range report.response.Suggestions {
-				fmt.Fprintf(file, "- %s\n", suggestion)
-			}
-			fmt.Fprintf(file, "\n")
+		resp, err := client.AnalyzeCode(ctx, req)
+		if err != nil {
+			return nil, err
 		}
 
-		fmt.Fprintf(file, "---\n\n")
+		// The cache is best effort; a failed write only costs a later request.
+		// Partial results are not cached so the next run tries again.
+		if key != "" && !resp.Partial {
+			results.Put(key, client.BaseURL(), version, resp)
+		}
+		return resp, nil
 	}
+}
 
-	return nil
+// openCache returns the result cache unless --no-cache is set. Caching is
+// disabled with a warning when the cache directory is unusable.
+func openCache(cmd *cobra.Command) *cache.Cache {
+	if noCache, _ := cmd.Flags().GetBool("no-cache"); noCache {
+		return nil
+	}
+
+	dir, err := cache.DefaultDir()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Result cache disabled: %v\n", err)
+		return nil
+	}
+	results, err := cache.Open(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Result cache disabled: %v\n", err)
+		return nil
+	}
+	return results
+}
+
+// fileProcessor adjusts a file's result, with access to its content, before
+// the file is reported
+type fileProcessor func(f *report.File, res review.Result)
+
+// reviewTargets analyzes targets with a bounded worker pool, printing each
+// file's report to progress in target order, and returns the collected report
+// along with the targets that could not be analyzed
+func reviewTargets(ctx context.Context, analyze review.Analyzer, targets []review.Target, concurrency int, progress io.Writer, process fileProcessor) (*report.Report, []review.Result, error) {
+	rep := report.New()
+	var failures []review.Result
+
+	_, err := review.Run(ctx, targets, concurrency, analyze, func(res review.Result) {
+		if res.Err != nil {
+			fmt.Fprintf(progress, "⚠️  Failed to analyze %s: %v\n", res.Path, res.Err)
+			failures = append(failures, res)
+			rep.AddFailure(res.Path, res.Err)
+			return
+		}
+
+		file := report.File{Path: res.Path, Analysis: res.Analysis}
+		if process != nil {
+			process(&file, res)
+		}
+		rep.AddFile(file)
+
+		// Print report for this file
+		report.PrintFile(progress, file)
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("review interrupted: %w", err)
+	}
+
+	return rep, failures, nil
 }
 
 var reviewAllCmd = &cobra.Command{
 	Use:   "all",
 	Short: "Review all files",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		// Get API key
-		apiKey, err := config.GetAPIKey()
+		proj, err := loadProject(cmd)
 		if err != nil {
-			return fmt.Errorf("authentication required: %w", err)
+			return err
 		}
 
-		// Create API client
-		client := api.NewClient(apiKey)
+		out, err := getOutputOptions(cmd, proj, "SCAN.md")
+		if err != nil {
+			return err
+		}
+
+		concurrency, _ := cmd.Flags().GetInt("concurrency")
+		if concurrency < 1 {
+			return fmt.Errorf("--concurrency must be at least 1")
+		}
+
+		progress := progressWriter(out)
+
+		analyze, err := newAnalyzer(cmd, proj)
+		if err != nil {
+			return err
+		}
 
 		// Get current directory
 		dir, err := os.Getwd()
@@ -267,193 +834,1881 @@ var reviewAllCmd = &cobra.Command{
 			return fmt.Errorf("failed to get current directory: %w", err)
 		}
 
-		// Track statistics
-		var (
-			totalFiles      int
-			filesWithIssues int
-			totalIssues     int
-			overallScore    float64
-		)
+		fmt.Fprintf(progress, "\n🔍 Starting code review for all files in %s\n", dir)
+		fmt.Fprintln(progress, strings.Repeat("=", 80))
 
-		// Store reports for markdown file
-		var reports []struct {
-			path     string
-			response *api.AnalysisResponse
+		targets, err := collectTargets(dir, proj, ignoreMatcher(cmd, proj))
+		if err != nil {
+			return fmt.Errorf("error walking through files: %w", err)
 		}
 
-		fmt.Printf("\n🔍 Starting code review for all files in %s\n", dir)
-		fmt.Println(strings.Repeat("=", 80))
+		bl, blPath, err := loadBaseline(cmd, proj)
+		if err != nil {
+			return err
+		}
 
-		// Walk through all files
-		err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
+		rep, failures, err := reviewTargets(cmd.Context(), analyze, targets, concurrency, progress, findingsProcessor(proj, bl))
+		if err != nil {
+			return err
+		}
+		rep.Baseline = blPath
+		rep.Dir = dir
 
-			// Skip directories
-			if info.IsDir() {
-				if shouldSkipDir(path) {
-					return filepath.SkipDir
-				}
-				return nil
+		if err := finishReview(proj, rep, out, progress); err != nil {
+			return err
+		}
+		return enforcePolicy(proj, rep, failures, progress)
+	},
+}
+
+// finishReview prints the run summary, writes the report to its destination
+// and records the run for the dashboard
+func finishReview(proj *project.Config, rep *report.Report, out outputOptions, progress io.Writer) error {
+	// Print summary
+	report.PrintSummary(progress, rep.Summary())
+	report.PrintFailures(progress, rep.Failures)
+
+	if err := report.WriteFile(out.output, out.format, rep); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+
+	if !out.toStdout() {
+		fmt.Fprintf(progress, "\n📝 Report has been saved to %s\n", out.output)
+	}
+
+	saveRun(proj, rep, progress)
+	return nil
+}
+
+// saveRun records rep as the last run of the project so raincheck dashboard
+// can show it. Failing to save only warns since the review itself succeeded.
+func saveRun(proj *project.Config, rep *report.Report, progress io.Writer) {
+	if err := lastrun.Save(proj.Root, rep); err != nil {
+		fmt.Fprintf(progress, "⚠️  Failed to save run for the dashboard: %v\n", err)
+	}
+}
+
+// loadBaseline loads the baseline selected by --baseline, the project
+// settings or the default file at the project root. It returns a nil
+// baseline when none exists or --no-baseline is set.
+func loadBaseline(cmd *cobra.Command, proj *project.Config) (*baseline.Baseline, string, error) {
+	if disabled, _ := cmd.Flags().GetBool("no-baseline"); disabled {
+		return nil, "", nil
+	}
+
+	path, _ := cmd.Flags().GetString("baseline")
+	explicit := path != ""
+	if !explicit && proj.Baseline != "" {
+		path, explicit = filepath.Join(proj.Root, proj.Baseline), true
+	}
+	if !explicit {
+		path = filepath.Join(proj.Root, baseline.FileName)
+		if _, err := os.Stat(path); err != nil {
+			return nil, "", nil
+		}
+	}
+
+	bl, err := baseline.Load(path)
+	if err != nil {
+		return nil, "", err
+	}
+	return bl, path, nil
+}
+
+// findingsProcessor applies inline suppressions, the minimum severity and
+// the baseline to each file's new issues
+func findingsProcessor(proj *project.Config, bl *baseline.Baseline) fileProcessor {
+	return func(f *report.File, res review.Result) {
+		applyFindingFilters(proj, bl, f, res.AbsPath, res.Content)
+	}
+}
+
+// reviewPolicy builds the pass/fail policy from the project settings
+func reviewPolicy(proj *project.Config) policy.Policy {
+	return policy.Policy{
+		FailOn:    proj.FailOn,
+		MinScore:  proj.MinScore,
+		MinScores: proj.MinScores,
+	}
+}
+
+// enforcePolicy turns analysis failures and policy violations into errors
+// carrying the matching exit code. Authentication failures take precedence
+// over analysis failures, which take precedence over policy violations,
+// since an incomplete review cannot be judged.
+func enforcePolicy(proj *project.Config, rep *report.Report, failures []review.Result, progress io.Writer) error {
+	if len(failures) > 0 {
+		for _, f := range failures {
+			if api.IsAuthError(f.Err) {
+				return &exitError{code: exitAuthError, err: fmt.Errorf("authentication failed: %w", f.Err)}
+			}
+		}
+		return &exitError{code: exitAnalysisError, err: fmt.Errorf("%d file(s) could not be analyzed", len(failures))}
+	}
+
+	violations := reviewPolicy(proj).Evaluate(rep)
+	if len(violations) == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(progress, "\n❌ Policy Violations\n")
+	fmt.Fprintln(progress, strings.Repeat("=", 80))
+	for _, v := range violations {
+		fmt.Fprintf(progress, "• %s\n", v)
+	}
+	fmt.Fprintln(progress, strings.Repeat("=", 80))
+
+	return &exitError{code: exitPolicyViolation, err: fmt.Errorf("%d policy violation(s) found", len(violations))}
+}
+
+// analysisError wraps an error from the backend with the matching exit code
+func analysisError(err error) error {
+	if api.IsAuthError(err) {
+		return &exitError{code: exitAuthError, err: fmt.Errorf("authentication failed: %w", err)}
+	}
+	return &exitError{code: exitAnalysisError, err: fmt.Errorf("failed to analyze code: %w", err)}
+}
+
+// progressWriter returns where per-file progress is printed, keeping stdout
+// clean for the report when it is written there
+func progressWriter(out outputOptions) io.Writer {
+	if out.toStdout() {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+var reviewDiffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Review files changed in git",
+	Long: `Review only the files changed in git and report only the findings on changed lines.
+
+By default the working tree, including untracked files that are not
+ignored, is compared to HEAD. Use --staged to review the
+index, --base to compare against the merge base with another branch, or
+--range to review the changes between two commits. With --staged and --range
+the files are reviewed as they are in the index or the later commit, not as
+they are in the working tree.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		proj, err := loadProject(cmd)
+		if err != nil {
+			return err
+		}
+
+		out, err := getOutputOptions(cmd, proj, "SCAN.md")
+		if err != nil {
+			return err
+		}
+
+		concurrency, _ := cmd.Flags().GetInt("concurrency")
+		if concurrency < 1 {
+			return fmt.Errorf("--concurrency must be at least 1")
+		}
+
+		var opts gitdiff.Options
+		opts.Staged, _ = cmd.Flags().GetBool("staged")
+		opts.Base, _ = cmd.Flags().GetString("base")
+		opts.Range, _ = cmd.Flags().GetString("range")
+
+		progress := progressWriter(out)
+
+		remote, err := newAnalyzer(cmd, proj)
+		if err != nil {
+			return err
+		}
+
+		// Get current directory
+		dir, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+
+		root, err := gitdiff.RepoRoot(cmd.Context(), dir)
+		if err != nil {
+			return fmt.Errorf("not a git repository: %w", err)
+		}
+
+		changes, err := gitdiff.Changes(cmd.Context(), root, opts)
+		if err != nil {
+			return fmt.Errorf("failed to diff changes: %w", err)
+		}
+
+		// Select changed code files outside skipped directories and ignored paths
+		var targets []review.Target
+		changed := make(map[string]gitdiff.FileChange)
+		ignored := ignoreMatcher(cmd, proj)
+		for _, change := range changes {
+			absPath := filepath.Join(root, filepath.FromSlash(change.Path))
+			// Size is checked once the file is read
+			if inSkippedDir(change.Path) || !isReviewable(proj, absPath, 0) || (ignored != nil && ignored.Ignored(absPath, false)) {
+				continue
+			}
+
+			// With --staged and --range the changed lines refer to the index
+			// or a commit, which the working tree may have moved on from
+			target := review.Target{AbsPath: absPath}
+			var size int64
+			if opts.FromGit() {
+				content, err := gitdiff.Content(cmd.Context(), root, opts, change.Path)
+				if err != nil {
+					return fmt.Errorf("failed to read %s: %w", change.Path, err)
+				}
+				size = int64(len(content))
+				target.Load = func(context.Context) ([]byte, error) { return content, nil }
+			} else {
+				info, err := os.Stat(absPath)
+				if err != nil {
+					continue
+				}
+				size = info.Size()
+			}
+			if proj.TooLarge(size) {
+				continue
+			}
+
+			relPath, err := filepath.Rel(dir, absPath)
+			if err != nil {
+				return err
+			}
+
+			target.Path = relPath
+			targets = append(targets, target)
+			changed[relPath] = change
+		}
+
+		fmt.Fprintf(progress, "\n🔍 Reviewing %d changed file(s) in %s\n", len(targets), root)
+		fmt.Fprintln(progress, strings.Repeat("=", 80))
+
+		// Drop findings outside the changed lines; findings without a line
+		// number apply to the whole file and are kept
+		analyze := func(ctx context.Context, target review.Target, content []byte) (*api.AnalysisResponse, error) {
+			resp, err := remote(ctx, target, content)
+			if err != nil {
+				return nil, err
 			}
 
-			// Check if it's a code file
-			if !isCodeFile(path) {
-				return nil
-			}
+			change := changed[target.Path]
+			resp.FilterIssues(func(_ string, issue api.Issue) bool {
+				return issue.Line <= 0 || change.Contains(issue.Line)
+			})
+			return resp, nil
+		}
+
+		bl, blPath, err := loadBaseline(cmd, proj)
+		if err != nil {
+			return err
+		}
+
+		// Suppressions on unchanged lines match nothing once findings outside
+		// the diff are dropped, so only report those touched by the change
+		process := func(f *report.File, res review.Result) {
+			applyFindingFilters(proj, bl, f, res.AbsPath, res.Content)
+			change := changed[res.Path]
+			stale := f.StaleSuppressions[:0]
+			for _, s := range f.StaleSuppressions {
+				if change.Contains(s.Line) {
+					stale = append(stale, s)
+				}
+			}
+			f.StaleSuppressions = stale
+		}
+
+		rep, failures, err := reviewTargets(cmd.Context(), analyze, targets, concurrency, progress, process)
+		if err != nil {
+			return err
+		}
+		rep.Baseline = blPath
+		rep.Dir = dir
+
+		if err := finishReview(proj, rep, out, progress); err != nil {
+			return err
+		}
+		return enforcePolicy(proj, rep, failures, progress)
+	},
+}
+
+// inSkippedDir reports whether any directory in the slash-separated path
+// would be skipped by shouldSkipDir
+func inSkippedDir(path string) bool {
+	parts := strings.Split(path, "/")
+	for _, dir := range parts[:len(parts)-1] {
+		if shouldSkipDir(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// maxSecretScanSize skips files larger than this when scanning for secrets,
+// unless the project sets a lower limit
+const maxSecretScanSize = 10 << 20
+
+var secretsCmd = &cobra.Command{
+	Use:   "secrets [dir]",
+	Short: "Scan for committed secrets and credentials",
+	Long: `Scan every text file in dir (default: the current directory) for credentials
+such as AWS keys, GitHub and DigitalOcean tokens, private keys, Firebase
+service-account keys and password assignments, plus strings with high entropy.
+With --history, lines added by any commit in the git history are scanned too.
+
+Secrets are redacted in all output. The scan fails with a policy violation when
+a finding at or above the fail-on severity (default: WARNING) is found.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		proj, err := loadProject(cmd)
+		if err != nil {
+			return err
+		}
+		if proj.FailOn == "" {
+			proj.FailOn = api.SeverityWarning
+		}
+
+		out, err := getOutputOptions(cmd, proj, "")
+		if err != nil {
+			return err
+		}
+		progress := progressWriter(out)
+
+		dir := "."
+		if len(args) > 0 {
+			dir = args[0]
+		}
+		if dir, err = filepath.Abs(dir); err != nil {
+			return fmt.Errorf("failed to resolve directory: %w", err)
+		}
+
+		fmt.Fprintf(progress, "\n🔑 Scanning %s for secrets\n", dir)
+		fmt.Fprintln(progress, strings.Repeat("=", 80))
+
+		ignored := ignoreMatcher(cmd, proj)
+		targets, err := walkFiles(dir, proj, ignored, func(path string, size int64) bool {
+			return proj.Included(path) && !proj.Excluded(path) && !proj.TooLarge(size) && size <= maxSecretScanSize
+		})
+		if err != nil {
+			return fmt.Errorf("error walking through files: %w", err)
+		}
+
+		rep := report.New()
+		rep.Dir = dir
+		scanned := 0
+		var failures []review.Result
+		// current holds the secrets still present, which history skips
+		current := make(map[string]bool)
+		for _, target := range targets {
+			content, err := os.ReadFile(target.AbsPath)
+			if err != nil {
+				fmt.Fprintf(progress, "⚠️  Failed to read %s: %v\n", target.Path, err)
+				rep.AddFailure(target.Path, err)
+				failures = append(failures, review.Result{Target: target, Err: err})
+				continue
+			}
+			if isBinary(content) {
+				continue
+			}
+			scanned++
+
+			found := secrets.Scan(target.Path, content)
+			for _, f := range found {
+				current[secretKey(target.AbsPath, f)] = true
+			}
+			file := report.File{Path: target.Path, Analysis: secrets.Analysis(found)}
+			applyFindingFilters(proj, nil, &file, target.AbsPath, content)
+			addScannedFile(rep, file, progress)
+		}
+
+		if history, _ := cmd.Flags().GetBool("history"); history {
+			files, err := scanHistory(cmd.Context(), dir, proj, ignored, current)
+			if err != nil {
+				return err
+			}
+			for _, file := range files {
+				addScannedFile(rep, file, progress)
+			}
+		}
+
+		fmt.Fprintf(progress, "\nScanned %d text file(s)\n", scanned)
+		if err := finishReview(proj, rep, out, progress); err != nil {
+			return err
+		}
+		return enforcePolicy(proj, rep, failures, progress)
+	},
+}
+
+// addScannedFile adds a scanned file to the report and prints its findings
+// when it has any, keeping reports of large trees short; files without
+// findings are only counted
+func addScannedFile(rep *report.Report, file report.File, progress io.Writer) {
+	if file.Analysis.IssueCount() == 0 && len(file.Suppressed) == 0 && len(file.StaleSuppressions) == 0 {
+		rep.Clean++
+		return
+	}
+	rep.AddFile(file)
+	printFindings(file, progress)
+}
+
+// printFindings prints one line per finding of a scanned file
+func printFindings(file report.File, progress io.Writer) {
+	for _, c := range file.Analysis.Categories() {
+		for _, issue := range c.Category.Issues {
+			location := file.Path
+			if issue.Line > 0 {
+				location = fmt.Sprintf("%s:%d", file.Path, issue.Line)
+			}
+			fmt.Fprintf(progress, "%s %s %s\n", severityMark(issue.Severity), location, issue.Description)
+		}
+	}
+}
+
+// secretKey identifies a secret in a file across the working tree and
+// history, where file-level patterns may report it under another kind
+func secretKey(absPath string, f secrets.Finding) string {
+	return absPath + "\x00" + f.Secret
+}
+
+// scanHistory scans the lines added by every commit for secrets. Each
+// secret is reported once, at the commit that introduced it, in a file
+// named "<commit>:<path>". Secrets listed in current are still in the
+// working tree and already reported, so they are skipped.
+func scanHistory(ctx context.Context, dir string, proj *project.Config, ignored *ignore.Matcher, current map[string]bool) ([]report.File, error) {
+	root, err := gitdiff.RepoRoot(ctx, dir)
+	if err != nil {
+		return nil, fmt.Errorf("--history requires a git repository: %w", err)
+	}
+
+	type introduced struct {
+		commit, path string
+		finding      secrets.Finding
+	}
+	var order []string
+	first := make(map[string]introduced)
+
+	err = gitdiff.History(ctx, root, func(line gitdiff.AddedLine) {
+		absPath := filepath.Join(root, filepath.FromSlash(line.Path))
+		if inSkippedDir(line.Path) || !proj.Included(absPath) || proj.Excluded(absPath) || (ignored != nil && ignored.Ignored(absPath, false)) {
+			return
+		}
+		for _, f := range secrets.ScanLine(line.Path, line.Text) {
+			f.Line = line.Line
+			key := secretKey(absPath, f)
+			if current[key] {
+				continue
+			}
+			if _, seen := first[key]; !seen {
+				order = append(order, key)
+			}
+			// History is read newest first, so the last sighting introduced it
+			first[key] = introduced{commit: line.Commit, path: line.Path, finding: f}
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan git history: %w", err)
+	}
+
+	var (
+		files  []report.File
+		byName = make(map[string][]secrets.Finding)
+		names  []string
+	)
+	for _, key := range order {
+		in := first[key]
+		commit := in.commit
+		if len(commit) > 12 {
+			commit = commit[:12]
+		}
+		name := commit + ":" + in.path
+		if _, ok := byName[name]; !ok {
+			names = append(names, name)
+		}
+		byName[name] = append(byName[name], in.finding)
+	}
+	for _, name := range names {
+		file := report.File{Path: name, Analysis: secrets.Analysis(byName[name])}
+		filterSeverity(proj, &file)
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// isBinary reports whether content looks like a binary file
+func isBinary(content []byte) bool {
+	if len(content) > 8000 {
+		content = content[:8000]
+	}
+	return bytes.IndexByte(content, 0) >= 0
+}
+
+// severityMark returns the marker printed before a finding
+func severityMark(severity string) string {
+	switch api.NormalizeSeverity(severity) {
+	case api.SeverityError:
+		return "🔴"
+	case api.SeverityWarning:
+		return "🟡"
+	}
+	return "🔵"
+}
+
+var iacCmd = &cobra.Command{
+	Use:   "iac [dir]",
+	Short: "Scan infrastructure-as-code files for misconfigurations",
+	Long: `Check the Dockerfiles, Docker Compose files, Kubernetes manifests and Terraform
+files in dir (default: the current directory) with the built-in infrastructure
+rules: containers running as root or privileged, unpinned latest images,
+missing health checks, hostPath and Docker socket mounts, public buckets and
+databases, open ingress rules, and secrets in plaintext or passed through
+environment variables.
+
+The scan runs offline and fails with a policy violation when a finding at or
+above the fail-on severity (default: WARNING) is found.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		proj, err := loadProject(cmd)
+		if err != nil {
+			return err
+		}
+		if proj.FailOn == "" {
+			proj.FailOn = api.SeverityWarning
+		}
+
+		out, err := getOutputOptions(cmd, proj, "")
+		if err != nil {
+			return err
+		}
+		progress := progressWriter(out)
+
+		dir := "."
+		if len(args) > 0 {
+			dir = args[0]
+		}
+		if dir, err = filepath.Abs(dir); err != nil {
+			return fmt.Errorf("failed to resolve directory: %w", err)
+		}
+
+		fmt.Fprintf(progress, "\n🏗️  Scanning %s for infrastructure misconfigurations\n", dir)
+		fmt.Fprintln(progress, strings.Repeat("=", 80))
+
+		targets, err := walkFiles(dir, proj, ignoreMatcher(cmd, proj), func(path string, size int64) bool {
+			return rules.IsIaC(path) && proj.Included(path) && !proj.Excluded(path) && !proj.TooLarge(size)
+		})
+		if err != nil {
+			return fmt.Errorf("error walking through files: %w", err)
+		}
+
+		rep := report.New()
+		rep.Dir = dir
+		scanned := 0
+		var failures []review.Result
+		for _, target := range targets {
+			content, err := os.ReadFile(target.AbsPath)
+			if err != nil {
+				fmt.Fprintf(progress, "⚠️  Failed to read %s: %v\n", target.Path, err)
+				rep.AddFailure(target.Path, err)
+				failures = append(failures, review.Result{Target: target, Err: err})
+				continue
+			}
+			// YAML files that are not Kubernetes manifests are left out
+			if rules.IaCLanguage(target.Path, content) == "" {
+				continue
+			}
+			scanned++
+
+			file := report.File{Path: target.Path, Analysis: rules.Analyze(target.Path, content)}
+			applyFindingFilters(proj, nil, &file, target.AbsPath, content)
+			rep.AddFile(file)
+			printFindings(file, progress)
+		}
+
+		if scanned == 0 {
+			fmt.Fprintln(progress, "No infrastructure-as-code files found")
+		}
+		fmt.Fprintf(progress, "\nScanned %d infrastructure file(s)\n", scanned)
+		if err := finishReview(proj, rep, out, progress); err != nil {
+			return err
+		}
+		return enforcePolicy(proj, rep, failures, progress)
+	},
+}
+
+var depsCmd = &cobra.Command{
+	Use:   "deps [dir]",
+	Short: "Scan dependencies for known vulnerabilities",
+	Long: `Find the dependency manifests and lockfiles in dir (default: the current
+directory) and check every pinned package against the OSV advisory database:
+go.mod and go.sum, package.json and package-lock.json, requirements.txt and
+poetry.lock, Cargo.lock and pom.xml.
+
+Advisories are stored locally and refreshed from ` + advisory.DefaultSource + `
+when they are older than a day and the network is available (see ` + advisory.EnvSource + `).
+Use raincheck advisories import to load them from a file or directory instead.
+
+The scan fails with a policy violation when an advisory at or above the
+fail-on severity (default: WARNING) affects a dependency.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		proj, err := loadProject(cmd)
+		if err != nil {
+			return err
+		}
+		if proj.FailOn == "" {
+			proj.FailOn = api.SeverityWarning
+		}
+
+		out, err := getOutputOptions(cmd, proj, "")
+		if err != nil {
+			return err
+		}
+		progress := progressWriter(out)
+
+		dir := "."
+		if len(args) > 0 {
+			dir = args[0]
+		}
+		if dir, err = filepath.Abs(dir); err != nil {
+			return fmt.Errorf("failed to resolve directory: %w", err)
+		}
+
+		fmt.Fprintf(progress, "\n📦 Scanning %s for vulnerable dependencies\n", dir)
+		fmt.Fprintln(progress, strings.Repeat("=", 80))
+
+		targets, err := walkFiles(dir, proj, ignoreMatcher(cmd, proj), func(path string, size int64) bool {
+			return deps.IsManifest(path) && proj.Included(path) && !proj.Excluded(path)
+		})
+		if err != nil {
+			return fmt.Errorf("error walking through files: %w", err)
+		}
+		rep := report.New()
+		rep.Dir = dir
+		noDev, _ := cmd.Flags().GetBool("no-dev")
+		manifests, ecosystems, failures := parseManifests(targets, noDev, rep, progress)
+		if len(manifests) == 0 {
+			fmt.Fprintln(progress, "No dependency manifests found")
+		}
+
+		db, err := openAdvisories()
+		if err != nil {
+			return err
+		}
+		if offline, _ := cmd.Flags().GetBool("offline"); !offline {
+			refresh, _ := cmd.Flags().GetBool("refresh")
+			refreshAdvisories(cmd.Context(), db, ecosystems, refresh, progress)
+		}
+		for _, ecosystem := range ecosystems {
+			if !db.Has(ecosystem) {
+				return &exitError{code: exitAnalysisError, err: fmt.Errorf("no %s advisories are available; connect to the network or run raincheck advisories import", ecosystem)}
+			}
+		}
+
+		packages := 0
+		for _, m := range manifests {
+			matches, err := db.Match(m.packages)
+			if err != nil {
+				return err
+			}
+			packages += len(m.packages)
+
+			file := report.File{Path: m.target.Path, Analysis: advisory.Analysis(matches)}
+			applyFindingFilters(proj, nil, &file, m.target.AbsPath, m.content)
+			rep.AddFile(file)
+			printFindings(file, progress)
+		}
+
+		fmt.Fprintf(progress, "\nChecked %d package(s) in %d manifest(s)\n", packages, len(manifests))
+		if err := finishReview(proj, rep, out, progress); err != nil {
+			return err
+		}
+		return enforcePolicy(proj, rep, failures, progress)
+	},
+}
+
+var sbomCmd = &cobra.Command{
+	Use:   "sbom [dir]",
+	Short: "Generate a software bill of materials",
+	Long: `Find the dependency manifests and lockfiles in dir (default: the current
+directory) and write a software bill of materials listing every pinned
+package with its package URL, the hashes the lockfile records and the
+dependencies between packages.
+
+Manifests are found the same way as by raincheck deps, honoring
+` + ignore.GitIgnore + ` and ` + ignore.RaincheckIgnore + ` files and the project's exclude settings.
+When a lockfile cannot be parsed the manifest next to it is listed instead,
+and the command exits with an error once the document is written.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		proj, err := loadProject(cmd)
+		if err != nil {
+			return err
+		}
+
+		format, _ := cmd.Flags().GetString("format")
+		format = strings.ToLower(format)
+		if !sbom.ValidFormat(format) {
+			return fmt.Errorf("unknown format %q (supported: %s)", format, strings.Join(sbom.Formats, ", "))
+		}
+		output, _ := cmd.Flags().GetString("output")
+		if output == "" {
+			output = "-"
+		}
+		progress := progressWriter(outputOptions{format: format, output: output})
+
+		dir := "."
+		if len(args) > 0 {
+			dir = args[0]
+		}
+		if dir, err = filepath.Abs(dir); err != nil {
+			return fmt.Errorf("failed to resolve directory: %w", err)
+		}
+
+		targets, err := walkFiles(dir, proj, ignoreMatcher(cmd, proj), func(path string, size int64) bool {
+			return deps.IsManifest(path) && proj.Included(path) && !proj.Excluded(path)
+		})
+		if err != nil {
+			return fmt.Errorf("error walking through files: %w", err)
+		}
+		noDev, _ := cmd.Flags().GetBool("no-dev")
+		manifests, _, failures := parseManifests(targets, noDev, report.New(), progress)
+
+		doc := &sbom.Document{Name: filepath.Base(dir), Created: time.Now()}
+		packages := 0
+		for _, m := range manifests {
+			// go.mod records versions only, the hashes are in go.sum
+			if name := deps.ChecksumFile(m.target.AbsPath); name != "" {
+				if sums, err := os.ReadFile(filepath.Join(filepath.Dir(m.target.AbsPath), name)); err == nil {
+					deps.AddChecksums(m.packages, sums)
+				}
+			}
+			doc.Manifests = append(doc.Manifests, sbom.Manifest{Path: m.target.Path, Packages: m.packages})
+			packages += len(m.packages)
+		}
+
+		if err := sbom.WriteFile(output, format, doc); err != nil {
+			return err
+		}
+		fmt.Fprintf(progress, "Listed %d package(s) from %d manifest(s)\n", packages, len(manifests))
+		// The document is written anyway, but it may be missing packages
+		if len(failures) > 0 {
+			return &exitError{code: exitAnalysisError, err: fmt.Errorf("%d manifest(s) could not be read or parsed", len(failures))}
+		}
+		return nil
+	},
+}
+
+// manifest is a parsed dependency manifest
+type manifest struct {
+	target   review.Target
+	content  []byte
+	packages []deps.Package
+}
+
+// splitSupersededManifests separates the manifests that add nothing when
+// their siblings are scanned, such as package.json next to
+// package-lock.json, from the rest
+func splitSupersededManifests(targets []review.Target) (kept, superseded []review.Target) {
+	names := make(map[string]map[string]bool)
+	for _, t := range targets {
+		dir := filepath.Dir(t.AbsPath)
+		if names[dir] == nil {
+			names[dir] = make(map[string]bool)
+		}
+		names[dir][filepath.Base(t.AbsPath)] = true
+	}
+
+	for _, t := range targets {
+		if deps.Superseded(t.AbsPath, names[filepath.Dir(t.AbsPath)]) {
+			superseded = append(superseded, t)
+		} else {
+			kept = append(kept, t)
+		}
+	}
+	return kept, superseded
+}
+
+// parseManifests reads and parses each manifest, recording files that fail
+// as report failures, and returns the ecosystems of the packages found and
+// the manifests that failed. Manifests superseded by a lockfile are skipped
+// unless the lockfile fails, so its packages are still listed.
+func parseManifests(targets []review.Target, noDev bool, rep *report.Report, progress io.Writer) ([]manifest, []string, []review.Result) {
+	var (
+		manifests  []manifest
+		ecosystems []string
+		failures   []review.Result
+		seen       = make(map[string]bool)
+	)
+	queue, superseded := splitSupersededManifests(targets)
+	for len(queue) > 0 {
+		target := queue[0]
+		queue = queue[1:]
+
+		content, err := os.ReadFile(target.AbsPath)
+		var pkgs []deps.Package
+		if err != nil {
+			fmt.Fprintf(progress, "⚠️  Failed to read %s: %v\n", target.Path, err)
+		} else if pkgs, err = deps.Parse(target.AbsPath, content); err != nil {
+			fmt.Fprintf(progress, "⚠️  Failed to parse %s: %v\n", target.Path, err)
+		}
+		if err != nil {
+			rep.AddFailure(target.Path, err)
+			failures = append(failures, review.Result{Target: target, Err: err})
+			for _, t := range superseded {
+				if filepath.Dir(t.AbsPath) == filepath.Dir(target.AbsPath) && deps.Superseded(t.AbsPath, map[string]bool{filepath.Base(target.AbsPath): true}) {
+					fmt.Fprintf(progress, "   Using %s instead\n", t.Path)
+					queue = append(queue, t)
+				}
+			}
+			continue
+		}
+
+		m := manifest{target: target, content: content}
+		for _, p := range pkgs {
+			if noDev && p.Dev {
+				continue
+			}
+			m.packages = append(m.packages, p)
+			if !seen[p.Ecosystem] {
+				seen[p.Ecosystem] = true
+				ecosystems = append(ecosystems, p.Ecosystem)
+			}
+		}
+		manifests = append(manifests, m)
+	}
+	return manifests, ecosystems, failures
+}
+
+var licensesCmd = &cobra.Command{
+	Use:   "licenses [dir]",
+	Short: "Check dependency and source licenses against the license policy",
+	Long: `Identify the licenses of the dependencies, vendored packages and source files
+in dir (default: the current directory) and check them against the licenses
+section of ` + project.FileName + `:
+
+  licenses:
+    allow: [MIT, Apache-2.0, "BSD-*"]
+    deny: ["GPL-*", "AGPL-*"]
+    unknown: WARNING
+
+Dependency licenses come from the lockfile when it records them, as
+package-lock.json does, or else from the installed package in the Go module
+cache, the Cargo registry, node_modules or the local Maven repository.
+Packages copied into vendor, node_modules and third_party directories are
+identified by their manifest or by matching their LICENSE files against a
+bundled set of license texts, as are the project's own LICENSE files.
+Source files are checked for SPDX-License-Identifier headers.
+
+A denied license is an ERROR, a license missing from a non-empty allow list
+is a WARNING, and a license that cannot be identified is reported with the
+unknown severity (default: INFO). The check fails with a policy violation
+when a finding is at or above the fail-on severity (default: WARNING).`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		proj, err := loadProject(cmd)
+		if err != nil {
+			return err
+		}
+		if proj.FailOn == "" {
+			proj.FailOn = api.SeverityWarning
+		}
+
+		out, err := getOutputOptions(cmd, proj, "")
+		if err != nil {
+			return err
+		}
+		progress := progressWriter(out)
+
+		dir := "."
+		if len(args) > 0 {
+			dir = args[0]
+		}
+		if dir, err = filepath.Abs(dir); err != nil {
+			return fmt.Errorf("failed to resolve directory: %w", err)
+		}
+
+		fmt.Fprintf(progress, "\n⚖️  Checking licenses in %s\n", dir)
+		fmt.Fprintln(progress, strings.Repeat("=", 80))
+
+		ignored := ignoreMatcher(cmd, proj)
+		vendorDirs, err := findVendorDirs(dir, proj, ignored)
+		if err != nil {
+			return fmt.Errorf("error walking through files: %w", err)
+		}
+		targets, err := walkFiles(dir, proj, ignored, func(path string, size int64) bool {
+			if !proj.Included(path) || proj.Excluded(path) {
+				return false
+			}
+			// Vendored packages are checked as a whole below
+			for _, d := range vendorDirs {
+				if strings.HasPrefix(path, d+string(filepath.Separator)) {
+					return false
+				}
+			}
+			return deps.IsManifest(path) || license.IsLicenseFile(path) || isReviewable(proj, path, size)
+		})
+		if err != nil {
+			return fmt.Errorf("error walking through files: %w", err)
+		}
+
+		var manifestTargets, licenseFiles, sources []review.Target
+		for _, t := range targets {
+			switch {
+			case deps.IsManifest(t.AbsPath):
+				manifestTargets = append(manifestTargets, t)
+			case license.IsLicenseFile(t.AbsPath):
+				licenseFiles = append(licenseFiles, t)
+			default:
+				sources = append(sources, t)
+			}
+		}
+
+		rep := report.New()
+		rep.Dir = dir
+		check := newLicenseCheck(proj)
+
+		// Packages are checked once, where they are first declared
+		seen := make(map[string]bool)
+		noDev, _ := cmd.Flags().GetBool("no-dev")
+		manifests, _, failures := parseManifests(manifestTargets, noDev, rep, progress)
+		// checked holds every file looked at, for the summary
+		checked := make(map[string]bool)
+		dependencies := 0
+		for _, m := range manifests {
+			checked[m.target.AbsPath] = true
+			for _, p := range m.packages {
+				if seen[p.ID()] {
+					continue
+				}
+				seen[p.ID()] = true
+				dependencies++
+
+				e := license.Parse(p.License)
+				if e == nil {
+					if v, ok := license.Installed(filepath.Dir(m.target.AbsPath), p); ok {
+						if e, _, _, err = v.License(); err != nil {
+							fmt.Fprintf(progress, "⚠️  Failed to read the license of %s: %v\n", p.ID(), err)
+							rep.AddFailure(m.target.Path, err)
+							failures = append(failures, review.Result{Target: m.target, Err: err})
+							continue
+						}
+					}
+				}
+				check.add(m.target.AbsPath, "dependency "+p.ID(), e, p.Line)
+			}
+		}
+
+		vendored := 0
+		for _, vendorDir := range vendorDirs {
+			pkgs, err := license.FindVendored(vendorDir)
+			if err != nil {
+				return err
+			}
+			for _, v := range pkgs {
+				id := v.Name
+				if v.Version != "" {
+					id += "@" + v.Version
+				}
+				if seen[id] {
+					continue
+				}
+				seen[id] = true
+				vendored++
+
+				e, path, line, err := v.License()
+				if err != nil {
+					fmt.Fprintf(progress, "⚠️  Failed to read the license of %s: %v\n", id, err)
+					rep.AddFailure(displayPath(dir, v.Dir), err)
+					failures = append(failures, review.Result{Target: review.Target{Path: displayPath(dir, v.Dir), AbsPath: v.Dir}, Err: err})
+					continue
+				}
+				check.add(path, "vendored package "+id, e, line)
+			}
+		}
+
+		// License files in the same directory cover the same code
+		var licenseDirs []string
+		byDir := make(map[string][]string)
+		for _, t := range licenseFiles {
+			checked[t.AbsPath] = true
+			d := filepath.Dir(t.AbsPath)
+			if _, ok := byDir[d]; !ok {
+				licenseDirs = append(licenseDirs, d)
+			}
+			byDir[d] = append(byDir[d], t.AbsPath)
+		}
+		for _, d := range licenseDirs {
+			files, err := license.IdentifyFiles(byDir[d])
+			if err != nil {
+				fmt.Fprintf(progress, "⚠️  %v\n", err)
+				rep.AddFailure(displayPath(dir, d), err)
+				failures = append(failures, review.Result{Target: review.Target{Path: displayPath(dir, d), AbsPath: d}, Err: err})
+				continue
+			}
+			subject := "the project"
+			if d != dir {
+				subject = "the code in " + displayPath(dir, d)
+			}
+			for _, f := range files {
+				// The GPL text next to the LGPL is not a license of its own
+				if f.Recognized && len(f.IDs) == 0 {
+					continue
+				}
+				check.add(f.Path, subject, license.Combine("AND", f.IDs), 0)
+			}
+		}
+
+		scanned := 0
+		for _, t := range sources {
+			content, err := os.ReadFile(t.AbsPath)
+			if err != nil {
+				fmt.Fprintf(progress, "⚠️  Failed to read %s: %v\n", t.Path, err)
+				rep.AddFailure(t.Path, err)
+				failures = append(failures, review.Result{Target: t, Err: err})
+				continue
+			}
+			if isBinary(content) {
+				continue
+			}
+			scanned++
+			checked[t.AbsPath] = true
+			for _, h := range license.FindHeaders(content) {
+				check.add(t.AbsPath, "the file", license.Parse(h.Expression), h.Line)
+			}
+		}
+
+		for _, path := range check.order {
+			// Findings in a manifest can be suppressed with inline comments
+			content, _ := os.ReadFile(path)
+			file := report.File{Path: displayPath(dir, path), Analysis: license.Analysis(check.findings[path])}
+			applyFindingFilters(proj, nil, &file, path, content)
+			addScannedFile(rep, file, progress)
+		}
+		for path := range checked {
+			if _, ok := check.findings[path]; !ok {
+				rep.Clean++
+			}
+		}
+
+		check.printInventory(progress)
+		fmt.Fprintf(progress, "Checked %d dependencies, %d vendored package(s), %d license file(s) and %d source file(s)\n",
+			dependencies, vendored, len(licenseFiles), scanned)
+		if err := finishReview(proj, rep, out, progress); err != nil {
+			return err
+		}
+		return enforcePolicy(proj, rep, failures, progress)
+	},
+}
+
+// findVendorDirs returns the directories holding copies of third-party
+// packages, such as vendor and node_modules, honoring the project's exclude
+// settings and ignore files
+func findVendorDirs(dir string, proj *project.Config, ignored *ignore.Matcher) ([]string, error) {
+	var dirs []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() || path == dir {
+			return nil
+		}
+		if proj.Excluded(path) || (ignored != nil && ignored.Ignored(path, true)) {
+			return filepath.SkipDir
+		}
+		if license.VendorDirs[info.Name()] {
+			dirs = append(dirs, path)
+			return filepath.SkipDir
+		}
+		if shouldSkipDir(path) {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return dirs, err
+}
+
+// licenseCheck collects the license findings of a run, keyed by the file
+// they are reported in, and counts the licenses seen
+type licenseCheck struct {
+	policy   license.Policy
+	unknown  string
+	findings map[string][]license.Finding
+	order    []string
+	counts   map[string]int
+}
+
+// newLicenseCheck returns an empty check of the project's license policy
+func newLicenseCheck(proj *project.Config) *licenseCheck {
+	unknown := proj.Licenses.Unknown
+	if unknown == "" {
+		unknown = api.SeverityInfo
+	}
+	return &licenseCheck{
+		policy:   license.Policy{Allow: proj.Licenses.Allow, Deny: proj.Licenses.Deny},
+		unknown:  unknown,
+		findings: make(map[string][]license.Finding),
+		counts:   make(map[string]int),
+	}
+}
+
+// add checks the license of subject, reporting a finding at line of path
+// when the policy does not permit it
+func (c *licenseCheck) add(path, subject string, e *license.Expr, line int) {
+	if e != nil {
+		c.counts[e.String()]++
+	} else {
+		c.counts["unknown"]++
+	}
+
+	f := c.policy.Check(subject, e, line, c.unknown)
+	if f == nil {
+		return
+	}
+	if _, ok := c.findings[path]; !ok {
+		c.order = append(c.order, path)
+	}
+	c.findings[path] = append(c.findings[path], *f)
+}
+
+// printInventory prints how often each license was seen, most common first
+func (c *licenseCheck) printInventory(progress io.Writer) {
+	if len(c.counts) == 0 {
+		return
+	}
+	names := make([]string, 0, len(c.counts))
+	for name := range c.counts {
+		names = append(names, name)
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		if c.counts[a] != c.counts[b] {
+			return c.counts[b] - c.counts[a]
+		}
+		return strings.Compare(a, b)
+	})
+
+	fmt.Fprintln(progress, "\nLicenses:")
+	for _, name := range names {
+		fmt.Fprintf(progress, "  %-40s %d\n", name, c.counts[name])
+	}
+	fmt.Fprintln(progress)
+}
+
+// openAdvisories opens the advisory database in its default location
+func openAdvisories() (*advisory.DB, error) {
+	dir, err := advisory.DefaultDir()
+	if err != nil {
+		return nil, err
+	}
+	return advisory.Open(dir)
+}
+
+// advisorySource returns where advisories are downloaded from
+func advisorySource() string {
+	if source := os.Getenv(advisory.EnvSource); source != "" {
+		return source
+	}
+	return advisory.DefaultSource
+}
+
+// advisoryClient returns the HTTP client for advisory downloads. Connecting
+// gives up quickly so scans without a network fall back to the local
+// database without a long wait.
+func advisoryClient() *http.Client {
+	return &http.Client{
+		Timeout: 10 * time.Minute,
+		Transport: &http.Transport{
+			Proxy:               http.ProxyFromEnvironment,
+			DialContext:         (&net.Dialer{Timeout: 10 * time.Second}).DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+		},
+	}
+}
+
+// refreshAdvisories downloads the advisories of ecosystems that are older
+// than advisory.MaxAge, or all of them when force is set. Failures only
+// warn since the local database can still be used.
+func refreshAdvisories(ctx context.Context, db *advisory.DB, ecosystems []string, force bool, progress io.Writer) {
+	client := advisoryClient()
+	for _, ecosystem := range ecosystems {
+		if !force && time.Since(db.Downloaded(ecosystem)) < advisory.MaxAge {
+			continue
+		}
+		fmt.Fprintf(progress, "⬇️  Updating %s advisories\n", ecosystem)
+		if err := db.Update(ctx, client, advisorySource(), ecosystem); err != nil {
+			fmt.Fprintf(progress, "⚠️  Using the local advisory database: %v\n", err)
+			// Without a network the other downloads would fail the same way
+			return
+		}
+	}
+}
+
+var advisoriesCmd = &cobra.Command{
+	Use:   "advisories",
+	Short: "Manage the local vulnerability advisory database",
+	Long: `raincheck deps matches dependencies against a local database of OSV
+advisories. It is refreshed automatically when a network is available, and can
+be loaded from OSV files for offline use.`,
+}
+
+var advisoriesImportCmd = &cobra.Command{
+	Use:   "import <path>...",
+	Short: "Import OSV advisories from JSON files, zip archives or directories",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openAdvisories()
+		if err != nil {
+			return err
+		}
 
-			// Get relative path
-			relPath, err := filepath.Rel(dir, path)
+		for _, path := range args {
+			counts, err := db.Import(path)
 			if err != nil {
 				return err
 			}
+			if len(counts) == 0 {
+				fmt.Printf("⚠️  No advisories for supported ecosystems found in %s\n", path)
+				continue
+			}
+			for _, ecosystem := range advisory.Ecosystems {
+				if n, ok := counts[ecosystem]; ok {
+					fmt.Printf("✅ Imported %d %s advisory(ies) from %s\n", n, ecosystem, path)
+				}
+			}
+		}
+		return nil
+	},
+}
 
-			// Read file content
-			content, err := os.ReadFile(path)
-			if err != nil {
-				fmt.Printf("⚠️  Failed to read %s: %v\n", relPath, err)
-				return nil
+var advisoriesUpdateCmd = &cobra.Command{
+	Use:   "update [ecosystem...]",
+	Short: "Download the latest advisories (default: every supported ecosystem)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ecosystems := advisory.Ecosystems
+		if len(args) > 0 {
+			ecosystems = args
+		}
+		for _, ecosystem := range ecosystems {
+			if !slices.Contains(advisory.Ecosystems, ecosystem) {
+				return fmt.Errorf("unsupported ecosystem %q (supported: %s)", ecosystem, strings.Join(advisory.Ecosystems, ", "))
 			}
+		}
 
-			// Analyze code
-			resp, err := client.AnalyzeCode(string(content))
-			if err != nil {
-				fmt.Printf("⚠️  Failed to analyze %s: %v\n", relPath, err)
-				return nil
+		db, err := openAdvisories()
+		if err != nil {
+			return err
+		}
+		client := advisoryClient()
+		for _, ecosystem := range ecosystems {
+			fmt.Printf("⬇️  Updating %s advisories\n", ecosystem)
+			if err := db.Update(cmd.Context(), client, advisorySource(), ecosystem); err != nil {
+				return err
 			}
+		}
+		fmt.Println("✅ Advisories are up to date")
+		return nil
+	},
+}
 
-			// Update statistics
-			totalFiles++
-			overallScore += resp.OverallScore
+var advisoriesStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the advisories stored locally",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openAdvisories()
+		if err != nil {
+			return err
+		}
+		statuses, err := db.Status()
+		if err != nil {
+			return err
+		}
 
-			// Count issues
-			fileIssues := 0
-			fileIssues += len(resp.Security.Issues)
-			fileIssues += len(resp.Performance.Issues)
-			fileIssues += len(resp.CodeQuality.Issues)
-			fileIssues += len(resp.Maintainability.Issues)
-			fileIssues += len(resp.BestPractices.Issues)
+		fmt.Printf("Advisory directory: %s\n", db.Dir())
+		if len(statuses) == 0 {
+			fmt.Println("No advisories stored; run raincheck advisories update or import")
+			return nil
+		}
+		for _, s := range statuses {
+			fmt.Printf("%-10s %-9s %6d advisory(ies) for %d package(s), updated %s\n",
+				s.Ecosystem, s.Source, s.Advisories, s.Packages, s.Updated.Local().Format(time.RFC1123))
+		}
+		return nil
+	},
+}
 
-			if fileIssues > 0 {
-				filesWithIssues++
-				totalIssues += fileIssues
-			}
+var baselineCmd = &cobra.Command{
+	Use:   "baseline",
+	Short: "Manage the baseline of known findings",
+}
 
-			// Store report
-			reports = append(reports, struct {
-				path     string
-				response *api.AnalysisResponse
-			}{
-				path:     relPath,
-				response: resp,
-			})
+var baselineCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Record all current findings in a baseline file",
+	Long: `Review every file in the project and record the findings in a baseline file.
 
-			// Print report for this file
-			fmt.Printf("\n📊 Code Analysis Report for %s\n", relPath)
-			fmt.Println(strings.Repeat("=", 80))
+Later reviews report findings recorded in the baseline as baselined instead of
+new, and baselined findings do not count towards --fail-on or other gates.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		proj, err := loadProject(cmd)
+		if err != nil {
+			return err
+		}
 
-			// Overall Score
-			fmt.Printf("\n🏆 Overall Score: %.1f/10\n", resp.OverallScore)
-			fmt.Println(strings.Repeat("-", 30))
+		concurrency, _ := cmd.Flags().GetInt("concurrency")
+		if concurrency < 1 {
+			return fmt.Errorf("--concurrency must be at least 1")
+		}
 
-			// Print each category
-			printCategory("Security", resp.Security)
-			printCategory("Performance", resp.Performance)
-			printCategory("Code Quality", resp.CodeQuality)
-			printCategory("Maintainability", resp.Maintainability)
-			printCategory("Best Practices", resp.BestPractices)
-
-			// Print suggestions
-			if len(resp.Suggestions) > 0 {
-				fmt.Printf("\n💡 General Suggestions\n")
-				fmt.Println(strings.Repeat("-", 20))
-				for _, suggestion := range resp.Suggestions {
-					fmt.Printf("• %s\n", suggestion)
-				}
+		output, _ := cmd.Flags().GetString("output")
+		if output == "" {
+			output = filepath.Join(proj.Root, baseline.FileName)
+			if proj.Baseline != "" {
+				output = filepath.Join(proj.Root, proj.Baseline)
 			}
+		}
 
-			fmt.Println(strings.Repeat("=", 80))
-			return nil
-		})
+		analyze, err := newAnalyzer(cmd, proj)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("\n🔍 Creating baseline for all files in %s\n", proj.Root)
+		fmt.Println(strings.Repeat("=", 80))
 
+		targets, err := collectTargets(proj.Root, proj, ignoreMatcher(cmd, proj))
 		if err != nil {
 			return fmt.Errorf("error walking through files: %w", err)
 		}
 
-		// Print summary
-		fmt.Printf("\n📈 Review Summary\n")
-		fmt.Println(strings.Repeat("=", 80))
-		fmt.Printf("Total Files Scanned: %d\n", totalFiles)
-		fmt.Printf("Files with Issues: %d\n", filesWithIssues)
-		fmt.Printf("Total Issues Found: %d\n", totalIssues)
-		if totalFiles > 0 {
-			fmt.Printf("Average Score: %.1f/10\n", overallScore/float64(totalFiles))
+		bl := baseline.New()
+		rep, failures, err := reviewTargets(cmd.Context(), analyze, targets, concurrency, io.Discard, func(f *report.File, res review.Result) {
+			applyFindingFilters(proj, nil, f, res.AbsPath, res.Content)
+			bl.Add(proj.Rel(res.AbsPath), f.Analysis, res.Content)
+			fmt.Printf("✓ %s (%d issue(s))\n", res.Path, f.Analysis.IssueCount())
+		})
+		if err != nil {
+			return err
 		}
-		fmt.Println(strings.Repeat("=", 80))
 
-		// Write markdown report
-		stats := struct {
-			totalFiles      int
-			filesWithIssues int
-			totalIssues     int
-			overallScore    float64
-		}{
-			totalFiles:      totalFiles,
-			filesWithIssues: filesWithIssues,
-			totalIssues:     totalIssues,
-			overallScore:    overallScore,
+		if len(failures) > 0 {
+			for _, f := range failures {
+				fmt.Printf("⚠️  Failed to analyze %s: %v\n", f.Path, f.Err)
+			}
+			if err := enforcePolicy(proj, rep, failures, os.Stdout); err != nil {
+				return fmt.Errorf("baseline not written: %w", err)
+			}
 		}
 
-		if err := writeMarkdownReport(reports, stats); err != nil {
-			return fmt.Errorf("failed to write markdown report: %w", err)
+		if err := bl.Save(output); err != nil {
+			return err
 		}
 
-		fmt.Printf("\n📝 Report has been saved to SCAN.md\n")
+		fmt.Printf("\n📝 Baseline with %d finding(s) from %d file(s) saved to %s\n", len(bl.Entries), len(rep.Files), output)
 		return nil
 	},
 }
 
 var dashboardCmd = &cobra.Command{
 	Use:   "dashboard",
-	Short: "Open the dashboard",
+	Short: "Browse the last review run in a local dashboard",
+	Long: `Start a local web server with an interactive dashboard of the last review
+run in this project, or of a JSON report given with --report.
+
+The dashboard runs until interrupted.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		var (
+			rep *report.Report
+			err error
+		)
+		if reportPath, _ := cmd.Flags().GetString("report"); reportPath != "" {
+			rep, err = report.ReadJSON(reportPath)
+			if err == nil && rep.Dir == "" {
+				rep.Dir = filepath.Dir(reportPath)
+			}
+		} else {
+			var proj *project.Config
+			if proj, err = loadProject(cmd); err != nil {
+				return err
+			}
+			rep, err = lastrun.Load(proj.Root)
+		}
+		if err != nil {
+			return err
+		}
+
+		addr, _ := cmd.Flags().GetString("addr")
+		ln, url, err := dashboard.Listen(addr)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("\n📊 Dashboard for %d file(s) running at %s\n", len(rep.Files), url)
+		fmt.Println("Press Ctrl+C to stop")
+
 		open, _ := cmd.Flags().GetBool("open")
 		if open {
-			// TODO: Implement dashboard opening logic
-			log.Printf("Opening dashboard")
+			if err := dashboard.Open(url); err != nil {
+				fmt.Printf("⚠️  %v\n", err)
+			}
+		}
+
+		return dashboard.New(rep).Serve(cmd.Context(), ln)
+	},
+}
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the local cache of analysis results",
+	Long: `Analysis results are cached by file content, backend URL and the analysis
+version the backend reports, which changes with its prompt and model,
+so unchanged files are not sent to the backend again.`,
+}
+
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show the size of the result cache",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		results, err := defaultCache()
+		if err != nil {
+			return err
+		}
+		stats, err := results.Stats()
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Cache directory: %s\n", stats.Dir)
+		fmt.Printf("Cached results: %d\n", stats.Entries)
+		fmt.Printf("Size: %s\n", formatBytes(stats.Size))
+		if stats.Entries > 0 {
+			fmt.Printf("Least recently used: %s\n", stats.Oldest.Format(time.RFC1123))
+			fmt.Printf("Most recently used: %s\n", stats.Newest.Format(time.RFC1123))
+		}
+		return nil
+	},
+}
+
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove old results from the cache",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		maxAge, _ := cmd.Flags().GetDuration("older-than")
+		if all, _ := cmd.Flags().GetBool("all"); all {
+			maxAge = 0
+		} else if maxAge <= 0 {
+			return fmt.Errorf("--older-than must be positive (use --all to clear the cache)")
+		}
+
+		results, err := defaultCache()
+		if err != nil {
+			return err
+		}
+		removed, freed, err := results.Prune(maxAge)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("🧹 Removed %d cached result(s), freeing %s\n", removed, formatBytes(freed))
+		return nil
+	},
+}
+
+// defaultCache opens the result cache in its default location
+func defaultCache() (*cache.Cache, error) {
+	dir, err := cache.DefaultDir()
+	if err != nil {
+		return nil, err
+	}
+	return cache.Open(dir)
+}
+
+// formatBytes renders a byte count with a binary unit
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// defaultPatchFile is where raincheck fix saves the proposed change
+const defaultPatchFile = "raincheck.patch"
+
+var fixCmd = &cobra.Command{
+	Use:   "fix [filename]",
+	Short: "Request a fix for an issue in a file",
+	Long: `Request a fix for one of the issues found in a file and save it as a patch.
+
+Without --issue the file's issues are listed with their numbers. With --issue
+the backend proposes a fix for that issue; the diff is shown and saved so it can
+be applied with 'raincheck apply'. Use --report to pick the issue from a JSON
+report written by 'raincheck review --format json' instead of analyzing the
+file again.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filename := args[0]
+		issueNumber, _ := cmd.Flags().GetInt("issue")
+		reportPath, _ := cmd.Flags().GetString("report")
+		patchPath, _ := cmd.Flags().GetString("patch")
+
+		proj, err := loadProject(cmd)
+		if err != nil {
+			return err
+		}
+
+		// Read file content
+		content, err := os.ReadFile(filename)
+		if err != nil {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+
+		// Create API client
+		client, err := newClient(cmd, proj)
+		if err != nil {
+			return err
+		}
+
+		// Find the issues of the file
+		var analysis *api.AnalysisResponse
+		if reportPath != "" {
+			rep, err := report.ReadJSON(reportPath)
+			if err != nil {
+				return err
+			}
+			for _, f := range rep.Files {
+				if filepath.Clean(f.Path) == filepath.Clean(filename) {
+					analysis = f.Analysis
+					break
+				}
+			}
+			if analysis == nil {
+				return fmt.Errorf("%s is not part of report %s", filename, reportPath)
+			}
+		} else {
+			analysis, err = engineAnalyzer(proj, remoteAnalyzer(proj, client, openCache(cmd)))(cmd.Context(), review.Target{Path: filename, AbsPath: filename}, content)
+			if err != nil {
+				return analysisError(err)
+			}
+			applyFindingFilters(proj, nil, &report.File{Path: filename, Analysis: analysis}, filename, content)
+		}
+
+		findings := report.Findings(analysis)
+		if issueNumber == 0 {
+			if len(findings) == 0 {
+				fmt.Printf("✓ No issues found in %s\n", filename)
+				return nil
+			}
+			fmt.Printf("\nIssues in %s\n", filename)
+			fmt.Println(strings.Repeat("-", 30))
+			for i, f := range findings {
+				fmt.Printf("%3d. [%s] [%s] %s", i+1, api.NormalizeSeverity(f.Issue.Severity), f.Issue.Type, f.Issue.Description)
+				if f.Issue.Line > 0 {
+					fmt.Printf(" (line %d)", f.Issue.Line)
+				}
+				fmt.Println()
+			}
+			fmt.Printf("\nRun 'raincheck fix %s --issue <n>' to request a fix\n", filename)
+			return nil
 		}
+		if issueNumber < 1 || issueNumber > len(findings) {
+			return fmt.Errorf("issue %d does not exist (%s has %d issue(s))", issueNumber, filename, len(findings))
+		}
+
+		finding := findings[issueNumber-1]
+		problem := fmt.Sprintf("[%s] %s: %s", finding.Category, finding.Issue.Type, finding.Issue.Description)
+		if finding.Issue.Line > 0 {
+			problem += fmt.Sprintf(" (line %d)", finding.Issue.Line)
+		}
+		suggestion := finding.Issue.Suggestion
+		if suggestion == "" {
+			suggestion = "Fix the problem"
+		}
+
+		fmt.Printf("\n🔧 Requesting a fix for issue %d: [%s] %s\n", issueNumber, finding.Issue.Type, finding.Issue.Description)
+
+		fix, err := client.FixIssue(cmd.Context(), string(content), suggestion, problem)
+		if err != nil {
+			return analysisError(err)
+		}
+		if !fix.Success || strings.TrimSpace(fix.Diff) == "" {
+			return &exitError{code: exitAnalysisError, err: fmt.Errorf("no fix could be generated: %s", fix.Explanation)}
+		}
+
+		// Attach the diff to the file and anchor its hunks in the current content
+		patches, err := patch.Parse(fix.Diff)
+		if err != nil {
+			return fmt.Errorf("the proposed fix is not a valid diff: %w", err)
+		}
+		if len(patches) != 1 {
+			return fmt.Errorf("the proposed fix touches %d files; expected only %s", len(patches), filename)
+		}
+		fp := patches[0]
+		fp.OldPath = filepath.ToSlash(filepath.Clean(filename))
+		fp.NewPath = fp.OldPath
+		if err := fp.Anchor(content); err != nil {
+			return fmt.Errorf("the proposed fix does not match %s: %w", filename, err)
+		}
+
+		fmt.Printf("\n📝 Explanation\n%s\n", fix.Explanation)
+		if fix.Changelog != "" {
+			fmt.Printf("\n📋 Changelog\n%s\n", fix.Changelog)
+		}
+		fmt.Printf("\n🎯 Confidence: %d/10\n", fix.Confidence)
+		fmt.Printf("\n%s\n", fp.String())
+
+		if err := os.WriteFile(patchPath, []byte(fp.String()), 0644); err != nil {
+			return fmt.Errorf("failed to save patch: %w", err)
+		}
+		fmt.Printf("💾 Patch saved to %s\n", patchPath)
+		fmt.Printf("Run 'raincheck apply %s' to apply it\n", patchPath)
 		return nil
 	},
 }
 
 var applyCmd = &cobra.Command{
-	Use:   "apply [changes]",
-	Short: "Apply changes",
-	Args:  cobra.ExactArgs(1),
+	Use:   "apply [patch]",
+	Short: "Apply a patch produced by raincheck fix",
+	Long: `Apply a unified diff, such as one saved by 'raincheck fix', to the working tree.
+
+Every hunk is checked before anything is written, so a conflicting patch leaves
+all files untouched. Unless --no-backup is given, each modified file is copied
+to <file>` + patch.BackupSuffix + ` first, and 'raincheck apply --revert' restores those backups.`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		changes := args[0]
-		// TODO: Implement apply changes logic
-		log.Printf("Applying changes: %s", changes)
+		patchFile := args[0]
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		noBackup, _ := cmd.Flags().GetBool("no-backup")
+		revert, _ := cmd.Flags().GetBool("revert")
+
+		data, err := os.ReadFile(patchFile)
+		if err != nil {
+			return fmt.Errorf("failed to read patch: %w", err)
+		}
+
+		patches, err := patch.Parse(string(data))
+		if err != nil {
+			return fmt.Errorf("failed to parse patch: %w", err)
+		}
+
+		dir, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+
+		if revert {
+			restored, err := patch.Revert(dir, patches)
+			for _, path := range restored {
+				fmt.Printf("↩️  Reverted %s\n", displayPath(dir, path))
+			}
+			if err != nil {
+				return fmt.Errorf("failed to revert: %w", err)
+			}
+			return nil
+		}
+
+		changes, err := patch.Prepare(dir, patches)
+		if err != nil {
+			return fmt.Errorf("patch does not apply cleanly, no files were changed:\n%w", err)
+		}
+
+		if dryRun {
+			for _, c := range changes {
+				fmt.Printf("✓ %s: %d hunk(s) apply cleanly\n", displayPath(dir, c.Path), c.Hunks)
+			}
+			fmt.Println("Dry run: no files were changed")
+			return nil
+		}
+
+		if err := patch.Write(changes, !noBackup); err != nil {
+			return err
+		}
+
+		for _, c := range changes {
+			fmt.Printf("✅ Patched %s (%d hunk(s))\n", displayPath(dir, c.Path), c.Hunks)
+		}
+		if !noBackup {
+			fmt.Printf("Run 'raincheck apply --revert %s' to undo\n", patchFile)
+		}
 		return nil
 	},
 }
 
+// displayPath shortens path to be relative to dir when possible
+func displayPath(dir, path string) string {
+	if rel, err := filepath.Rel(dir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
+
 func init() {
+	rootCmd.PersistentFlags().String("server", "", "Backend URL (overrides "+envServer+", the profile and "+project.FileName+")")
+	rootCmd.PersistentFlags().String("profile", "", "Credential profile to use (default: the current profile, or "+envProfile+")")
+	rootCmd.PersistentFlags().Duration("timeout", api.DefaultTimeout, "Give up on an analysis request after this long, including retries")
+	rootCmd.PersistentFlags().Int("retries", api.DefaultRetryPolicy.MaxAttempts-1, "Retry failed analysis requests this many times")
+
 	rootCmd.AddCommand(loginCmd)
+	rootCmd.AddCommand(logoutCmd)
+	rootCmd.AddCommand(whoamiCmd)
+	rootCmd.AddCommand(profileCmd)
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileUseCmd)
 	rootCmd.AddCommand(reviewCmd)
 	reviewCmd.AddCommand(reviewFileCmd)
 	reviewCmd.AddCommand(reviewAllCmd)
+	reviewCmd.AddCommand(reviewDiffCmd)
+	rootCmd.AddCommand(secretsCmd)
+	rootCmd.AddCommand(iacCmd)
+	rootCmd.AddCommand(depsCmd)
+	rootCmd.AddCommand(sbomCmd)
+	rootCmd.AddCommand(licensesCmd)
+	rootCmd.AddCommand(advisoriesCmd)
+	advisoriesCmd.AddCommand(advisoriesImportCmd)
+	advisoriesCmd.AddCommand(advisoriesUpdateCmd)
+	advisoriesCmd.AddCommand(advisoriesStatusCmd)
+	rootCmd.AddCommand(baselineCmd)
+	baselineCmd.AddCommand(baselineCreateCmd)
 	rootCmd.AddCommand(dashboardCmd)
+	rootCmd.AddCommand(fixCmd)
 	rootCmd.AddCommand(applyCmd)
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheStatsCmd)
+	cacheCmd.AddCommand(cachePruneCmd)
+
+	loginCmd.Flags().Bool("passphrase", false, "Protect stored keys with a passphrase (--passphrase=false removes it)")
+	logoutCmd.Flags().Bool("all", false, "Remove every profile and the credential store")
+
+	reviewCmd.PersistentFlags().String("config", "", "Path to the project config file (default: nearest "+project.FileName+")")
+	reviewCmd.PersistentFlags().StringSlice("include", nil, "Only review files matching these globs")
+	reviewCmd.PersistentFlags().StringSlice("exclude", nil, "Skip files and directories matching these globs")
+	reviewCmd.PersistentFlags().StringSlice("ext", nil, "Extra file extensions to review (e.g. .scala,.lua)")
+	reviewCmd.PersistentFlags().String("max-file-size", "", "Skip files larger than this size (e.g. 512KB, 1MB)")
+	reviewCmd.PersistentFlags().String("min-severity", "", "Hide issues below this severity: INFO|WARNING|ERROR")
+	reviewCmd.PersistentFlags().String("fail-on", "", "Exit with a policy violation if any issue is at or above this severity: INFO|WARNING|ERROR")
+	reviewCmd.PersistentFlags().Float64("min-score", 0, "Exit with a policy violation if any file's overall score is below this value")
+	for _, key := range policy.CategoryKeys() {
+		name := strings.ReplaceAll(key, "_", "-")
+		reviewCmd.PersistentFlags().Float64("min-"+name, 0, "Exit with a policy violation if any file's "+strings.ReplaceAll(key, "_", " ")+" score is below this value")
+	}
+
+	reviewCmd.PersistentFlags().String("baseline", "", "Baseline file of known findings (default: "+baseline.FileName+" at the project root)")
+	reviewCmd.PersistentFlags().Bool("no-baseline", false, "Report all findings as new, ignoring any baseline")
+	reviewCmd.PersistentFlags().Bool("no-ignore", false, "Review files matched by "+ignore.GitIgnore+" and "+ignore.RaincheckIgnore+" files")
+	reviewCmd.PersistentFlags().Bool("no-cache", false, "Analyze every file again instead of reusing cached results")
+	reviewCmd.PersistentFlags().String("engine", "", "Analysis engine: remote|local|hybrid (default: remote)")
+	reviewCmd.PersistentFlags().Bool("local", false, "Analyze offline with the built-in rules only (same as --engine local)")
+
+	addOutputFlags(reviewFileCmd, report.FormatText)
+	reviewFileCmd.Flags().Bool("jobs", false, "Queue the file as a job on the backend even if it is small")
+	addOutputFlags(reviewAllCmd, report.FormatMarkdown)
+	reviewAllCmd.Flags().IntP("concurrency", "j", 4, "Number of files to analyze in parallel")
+	reviewAllCmd.Flags().Bool("jobs", false, "Queue every file as a job on the backend instead of only large files")
+
+	addOutputFlags(reviewDiffCmd, report.FormatMarkdown)
+	reviewDiffCmd.Flags().IntP("concurrency", "j", 4, "Number of files to analyze in parallel")
+	reviewDiffCmd.Flags().Bool("jobs", false, "Queue every file as a job on the backend instead of only large files")
+	reviewDiffCmd.Flags().Bool("staged", false, "Review staged changes")
+	reviewDiffCmd.Flags().String("base", "", "Review changes since the merge base with this ref (e.g. main)")
+	reviewDiffCmd.Flags().String("range", "", "Review changes in a commit range (e.g. a..b)")
+	reviewDiffCmd.MarkFlagsMutuallyExclusive("staged", "base", "range")
+
+	addOutputFlags(secretsCmd, report.FormatText)
+	secretsCmd.Flags().String("config", "", "Path to the project config file (default: nearest "+project.FileName+")")
+	secretsCmd.Flags().Bool("history", false, "Also scan the lines added by every commit in the git history")
+	secretsCmd.Flags().Bool("no-ignore", false, "Scan files matched by "+ignore.GitIgnore+" and "+ignore.RaincheckIgnore+" files")
+	secretsCmd.Flags().StringSlice("exclude", nil, "Skip files and directories matching these globs")
+	secretsCmd.Flags().String("min-severity", "", "Hide findings below this severity: INFO|WARNING|ERROR")
+	secretsCmd.Flags().String("fail-on", "", "Exit with a policy violation if any finding is at or above this severity (default: WARNING)")
+
+	addOutputFlags(iacCmd, report.FormatText)
+	iacCmd.Flags().String("config", "", "Path to the project config file (default: nearest "+project.FileName+")")
+	iacCmd.Flags().Bool("no-ignore", false, "Scan files matched by "+ignore.GitIgnore+" and "+ignore.RaincheckIgnore+" files")
+	iacCmd.Flags().StringSlice("exclude", nil, "Skip files and directories matching these globs")
+	iacCmd.Flags().String("min-severity", "", "Hide findings below this severity: INFO|WARNING|ERROR")
+	iacCmd.Flags().String("fail-on", "", "Exit with a policy violation if any finding is at or above this severity (default: WARNING)")
+
+	addOutputFlags(depsCmd, report.FormatText)
+	depsCmd.Flags().String("config", "", "Path to the project config file (default: nearest "+project.FileName+")")
+	depsCmd.Flags().Bool("offline", false, "Only use the local advisory database")
+	depsCmd.Flags().Bool("refresh", false, "Download the latest advisories even if the local ones are recent")
+	depsCmd.MarkFlagsMutuallyExclusive("offline", "refresh")
+	depsCmd.Flags().Bool("no-dev", false, "Skip development and test dependencies")
+	depsCmd.Flags().Bool("no-ignore", false, "Scan manifests matched by "+ignore.GitIgnore+" and "+ignore.RaincheckIgnore+" files")
+	depsCmd.Flags().StringSlice("exclude", nil, "Skip files and directories matching these globs")
+	depsCmd.Flags().String("min-severity", "", "Hide findings below this severity: INFO|WARNING|ERROR")
+	depsCmd.Flags().String("fail-on", "", "Exit with a policy violation if any finding is at or above this severity (default: WARNING)")
+
+	sbomCmd.Flags().StringP("format", "f", sbom.FormatCycloneDX, "Output format: "+strings.Join(sbom.Formats, "|"))
+	sbomCmd.Flags().StringP("output", "o", "", "Write the SBOM to this path ('-' for stdout)")
+	sbomCmd.Flags().String("config", "", "Path to the project config file (default: nearest "+project.FileName+")")
+	sbomCmd.Flags().Bool("no-dev", false, "Leave out development and test dependencies")
+	sbomCmd.Flags().Bool("no-ignore", false, "Include manifests matched by "+ignore.GitIgnore+" and "+ignore.RaincheckIgnore+" files")
+	sbomCmd.Flags().StringSlice("exclude", nil, "Skip files and directories matching these globs")
+
+	addOutputFlags(licensesCmd, report.FormatText)
+	licensesCmd.Flags().String("config", "", "Path to the project config file (default: nearest "+project.FileName+")")
+	licensesCmd.Flags().Bool("no-dev", false, "Skip development and test dependencies")
+	licensesCmd.Flags().Bool("no-ignore", false, "Check files matched by "+ignore.GitIgnore+" and "+ignore.RaincheckIgnore+" files")
+	licensesCmd.Flags().StringSlice("exclude", nil, "Skip files and directories matching these globs")
+	licensesCmd.Flags().String("min-severity", "", "Hide findings below this severity: INFO|WARNING|ERROR")
+	licensesCmd.Flags().String("fail-on", "", "Exit with a policy violation if any finding is at or above this severity (default: WARNING)")
+
+	baselineCreateCmd.Flags().StringP("output", "o", "", "Write the baseline to this path (default: "+baseline.FileName+" at the project root)")
+	baselineCreateCmd.Flags().IntP("concurrency", "j", 4, "Number of files to analyze in parallel")
+	baselineCreateCmd.Flags().Bool("no-ignore", false, "Include files matched by "+ignore.GitIgnore+" and "+ignore.RaincheckIgnore+" files")
+	baselineCreateCmd.Flags().Bool("no-cache", false, "Analyze every file again instead of reusing cached results")
+	baselineCreateCmd.Flags().String("engine", "", "Analysis engine: remote|local|hybrid (default: remote)")
+	baselineCreateCmd.Flags().Bool("local", false, "Analyze offline with the built-in rules only (same as --engine local)")
+
+	fixCmd.Flags().IntP("issue", "i", 0, "Number of the issue to fix, as listed without this flag")
+	fixCmd.Flags().String("report", "", "Pick the issue from this JSON report instead of analyzing the file")
+	fixCmd.Flags().StringP("patch", "p", defaultPatchFile, "Save the proposed patch to this path")
+	fixCmd.Flags().Bool("no-cache", false, "Analyze the file again instead of reusing a cached result")
+	fixCmd.Flags().String("engine", "", "Engine that finds the issues: remote|local|hybrid (default: remote)")
+
+	applyCmd.Flags().Bool("dry-run", false, "Check that the patch applies without changing any files")
+	applyCmd.Flags().Bool("no-backup", false, "Do not keep a backup of modified files")
+	applyCmd.Flags().Bool("revert", false, "Restore the backups made when the patch was applied")
+
+	cachePruneCmd.Flags().Duration("older-than", 30*24*time.Hour, "Remove results not used within this duration")
+	cachePruneCmd.Flags().Bool("all", false, "Remove every cached result")
 
 	dashboardCmd.Flags().BoolP("open", "o", false, "Open dashboard in browser")
+	dashboardCmd.Flags().String("addr", "127.0.0.1:7420", "Address to serve the dashboard on")
+	dashboardCmd.Flags().String("report", "", "Show this JSON report instead of the last review run")
+	dashboardCmd.Flags().String("config", "", "Path to the project config file (default: nearest "+project.FileName+")")
+}
+
+// Exit codes reported to the calling shell
+const (
+	exitGenericError    = 1
+	exitPolicyViolation = 2
+	exitAnalysisError   = 3
+	exitAuthError       = 4
+)
+
+// exitError attaches a process exit code to an error
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
 }
 
 func main() {
 	if err := exec(); err != nil {
 		log.Printf("Error executing application: %v", err)
-		os.Exit(1)
+
+		code := exitGenericError
+		var exitErr *exitError
+		if errors.As(err, &exitErr) {
+			code = exitErr.code
+		}
+		os.Exit(code)
 	}
 }
 
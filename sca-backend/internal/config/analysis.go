@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Analysis holds the settings for splitting large files into chunks that
+// are analyzed separately and for repairing invalid model output
+type Analysis struct {
+	// ChunkSize is the most characters of code sent in one prompt
+	ChunkSize int
+	// ChunkOverlap is the number of lines a chunk repeats from the end of
+	// the one before it, so code near a split is seen whole
+	ChunkOverlap int
+	// Concurrency bounds the chunks of one file analyzed at once
+	Concurrency int
+	// MaxRepairs is the number of times the model is asked to correct an
+	// analysis that does not match the schema
+	MaxRepairs int
+}
+
+// LoadAnalysis reads the analysis settings from ANALYSIS_CHUNK_SIZE,
+// ANALYSIS_CHUNK_OVERLAP, ANALYSIS_CONCURRENCY and ANALYSIS_MAX_REPAIRS
+func LoadAnalysis() (Analysis, error) {
+	cfg := Analysis{ChunkSize: 12000, ChunkOverlap: 20, Concurrency: 4, MaxRepairs: 2}
+	for _, setting := range []struct {
+		env   string
+		value *int
+		min   int
+	}{
+		{"ANALYSIS_CHUNK_SIZE", &cfg.ChunkSize, 1000},
+		{"ANALYSIS_CHUNK_OVERLAP", &cfg.ChunkOverlap, 0},
+		{"ANALYSIS_CONCURRENCY", &cfg.Concurrency, 1},
+		{"ANALYSIS_MAX_REPAIRS", &cfg.MaxRepairs, 0},
+	} {
+		v := os.Getenv(setting.env)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < setting.min {
+			return Analysis{}, fmt.Errorf("invalid %s %q: expected a number of at least %d", setting.env, v, setting.min)
+		}
+		*setting.value = n
+	}
+	return cfg, nil
+}
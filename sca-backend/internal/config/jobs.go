@@ -0,0 +1,36 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Jobs holds the settings of the asynchronous analysis queue
+type Jobs struct {
+	// Workers is the number of jobs analyzed at once
+	Workers int
+	// TTL is how long a job and its result are kept after its last update
+	TTL time.Duration
+}
+
+// LoadJobs reads the job queue settings from JOB_WORKERS and JOB_TTL
+func LoadJobs() (Jobs, error) {
+	cfg := Jobs{Workers: 4, TTL: 24 * time.Hour}
+	if v := os.Getenv("JOB_WORKERS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return Jobs{}, fmt.Errorf("invalid JOB_WORKERS %q: expected a number of at least 1", v)
+		}
+		cfg.Workers = n
+	}
+	if v := os.Getenv("JOB_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return Jobs{}, fmt.Errorf("invalid JOB_TTL %q: expected a duration such as 24h", v)
+		}
+		cfg.TTL = d
+	}
+	return cfg, nil
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// LLM providers the backend can use
+const (
+	ProviderDigitalOcean = "digitalocean"
+	ProviderOpenAI       = "openai"
+	ProviderOllama       = "ollama"
+	ProviderLlamaCpp     = "llamacpp"
+)
+
+// Default DigitalOcean agents for analysis and fixes
+const (
+	DefaultAnalyzeAgentURL = "https://ipwpfibhdjn5nc34bk25sueu.agents.do-ai.run/api/v1/chat/completions"
+	DefaultFixAgentURL     = "https://z4b7rluk7f3moqgmpducstst.agents.do-ai.run/api/v1/chat/completions"
+)
+
+// LLM holds the settings of the language model provider
+type LLM struct {
+	// Provider is one of the Provider constants
+	Provider string
+	// BaseURL is the API root of OpenAI-compatible and local servers, such
+	// as https://api.openai.com/v1 or http://localhost:11434
+	BaseURL string
+	APIKey  string
+	Model   string
+	Timeout time.Duration
+
+	// DigitalOcean agents, one for analysis and one for fixes, each with
+	// its own key
+	AnalyzeAgentURL string
+	AnalyzeAgentKey string
+	FixAgentURL     string
+	FixAgentKey     string
+}
+
+// LoadLLM reads the provider settings from the environment. LLM_PROVIDER
+// selects the provider (default: digitalocean); LLM_BASE_URL, LLM_API_KEY,
+// LLM_MODEL and LLM_TIMEOUT configure it.
+func LoadLLM() (LLM, error) {
+	cfg := LLM{
+		Provider:        strings.ToLower(strings.TrimSpace(os.Getenv("LLM_PROVIDER"))),
+		BaseURL:         strings.TrimSuffix(os.Getenv("LLM_BASE_URL"), "/"),
+		APIKey:          os.Getenv("LLM_API_KEY"),
+		Model:           os.Getenv("LLM_MODEL"),
+		Timeout:         60 * time.Second,
+		AnalyzeAgentURL: envOr("DIGITALOCEAN_AGENT_URL", DefaultAnalyzeAgentURL),
+		AnalyzeAgentKey: os.Getenv("DIGITALOCEAN_API_KEY"),
+		FixAgentURL:     envOr("DIGITALOCEAN_FIX_AGENT_URL", DefaultFixAgentURL),
+		FixAgentKey:     os.Getenv("DIGITALOCEAN_FIX_API_KEY"),
+	}
+	if cfg.Provider == "" {
+		cfg.Provider = ProviderDigitalOcean
+	}
+	if timeout := os.Getenv("LLM_TIMEOUT"); timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil || d <= 0 {
+			return LLM{}, fmt.Errorf("invalid LLM_TIMEOUT %q: expected a duration such as 90s", timeout)
+		}
+		cfg.Timeout = d
+	}
+
+	switch cfg.Provider {
+	case ProviderDigitalOcean:
+		if cfg.AnalyzeAgentKey == "" {
+			return LLM{}, fmt.Errorf("DIGITALOCEAN_API_KEY environment variable not set")
+		}
+	case ProviderOpenAI:
+		if cfg.BaseURL == "" {
+			cfg.BaseURL = "https://api.openai.com/v1"
+		}
+		if cfg.Model == "" {
+			return LLM{}, fmt.Errorf("LLM_MODEL environment variable not set")
+		}
+	case ProviderOllama:
+		if cfg.BaseURL == "" {
+			cfg.BaseURL = "http://localhost:11434"
+		}
+		if cfg.Model == "" {
+			return LLM{}, fmt.Errorf("LLM_MODEL environment variable not set")
+		}
+	case ProviderLlamaCpp:
+		// llama.cpp serves the model it was started with
+		if cfg.BaseURL == "" {
+			cfg.BaseURL = "http://localhost:8080/v1"
+		}
+	default:
+		return LLM{}, fmt.Errorf("unknown LLM_PROVIDER %q (expected %s, %s, %s or %s)",
+			cfg.Provider, ProviderDigitalOcean, ProviderOpenAI, ProviderOllama, ProviderLlamaCpp)
+	}
+	return cfg, nil
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -10,6 +11,8 @@ import (
 	"strings"
 	"time"
 
+	"sca-backend/internal/config"
+	"sca-backend/internal/jobs"
 	"sca-backend/internal/models"
 	"sca-backend/internal/services"
 
@@ -57,6 +60,16 @@ func HealthHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// VersionHandler responds with the version of the analyses this backend
+// makes, which clients use to tell stale cached results apart
+func (h *Handler) VersionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{
+		"analysis_version": h.Version,
+	})
+}
+
 // StatsHandler handles stats-related requests
 func StatsHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -113,11 +126,22 @@ func StatsHandler(w http.ResponseWriter, r *http.Request) {
 
 type Handler struct {
 	FirebaseClient *firestore.Client // or your specific Firebase client type
+	// Provider is the language model that analyzes and fixes code
+	Provider services.Provider
+	// Analysis controls how large files are split for analysis
+	Analysis config.Analysis
+	// Jobs queues analyses submitted with POST /api/jobs
+	Jobs *jobs.Queue
+	// Version identifies the prompt, model and settings analyses are made
+	// with, as reported by GET /api/version
+	Version string
 }
 
-func NewHandler(firestoreClient *firestore.Client) *Handler {
+func NewHandler(firestoreClient *firestore.Client, provider services.Provider, analysis config.Analysis) *Handler {
 	return &Handler{
 		FirebaseClient: firestoreClient,
+		Provider:       provider,
+		Analysis:       analysis,
 	}
 }
 
@@ -128,39 +152,89 @@ func (h *Handler) AnalyzeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	req, ok := readCodeRequest(w, r)
+	if !ok {
+		return
+	}
+	owner, ok := h.requestOwner(w, r)
+	if !ok {
+		return
+	}
+
+	// Get analysis from service
+	analysis, err := services.AnalyzeCode(r.Context(), h.Provider, req, h.Analysis)
+	if errors.Is(err, services.ErrInvalidOutput) {
+		SendError(w, fmt.Sprintf("Analysis failed: %v", err), http.StatusBadGateway)
+		return
+	}
+	if err != nil {
+		SendError(w, fmt.Sprintf("Analysis failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+	h.recordAnalysis(owner, req, analysis)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(analysis)
+}
+
+// AnalyzeJob analyzes the code of a queued job and records the analysis
+// like AnalyzeHandler does
+func (h *Handler) AnalyzeJob(ctx context.Context, owner string, req models.CodeRequest) (*models.AnalysisResponse, error) {
+	analysis, err := services.AnalyzeCode(ctx, h.Provider, req, h.Analysis)
+	if err != nil {
+		return nil, err
+	}
+	h.recordAnalysis(owner, req, analysis)
+	return analysis, nil
+}
+
+// readCodeRequest reads and validates the code request in the body of r,
+// sending an error response and returning false when it is invalid
+func readCodeRequest(w http.ResponseWriter, r *http.Request) (models.CodeRequest, bool) {
+	var req models.CodeRequest
+
 	// Read the entire request body
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		SendError(w, "Failed to read request body", http.StatusBadRequest)
-		return
+		return req, false
 	}
 	defer r.Body.Close()
 
 	// Check if the request body is too large (10MB limit)
 	if len(body) > 10*1024*1024 {
 		SendError(w, "Request body too large", http.StatusRequestEntityTooLarge)
-		return
+		return req, false
 	}
 
-	var req models.CodeRequest
 	if err := json.Unmarshal(body, &req); err != nil {
 		SendError(w, "Invalid JSON input", http.StatusBadRequest)
-		return
+		return req, false
 	}
 
 	// Check if code is empty
 	if strings.TrimSpace(req.Code) == "" {
 		SendError(w, "Code cannot be empty", http.StatusBadRequest)
-		return
+		return req, false
 	}
+	return req, true
+}
 
-	// Get analysis from service
-	analysis, err := services.AnalyzeCode(req.Code)
-	if err != nil {
-		SendError(w, fmt.Sprintf("Analysis failed: %v", err), http.StatusInternalServerError)
-		return
+// requestOwner returns the API key the auth middleware verified for r. It
+// is only required when scans are stored in Firestore; otherwise a missing
+// key yields "".
+func (h *Handler) requestOwner(w http.ResponseWriter, r *http.Request) (string, bool) {
+	apiKeyStr, ok := r.Context().Value(ApiKeyContextKey).(string)
+	if h.FirebaseClient != nil && (!ok || apiKeyStr == "") {
+		SendError(w, "API key missing from context", http.StatusUnauthorized)
+		return "", false
 	}
+	return apiKeyStr, true
+}
 
+// recordAnalysis counts an analysis in Redis and stores the scan in
+// Firestore under the API key that requested it
+func (h *Handler) recordAnalysis(apiKeyStr string, req models.CodeRequest, analysis *models.AnalysisResponse) {
 	// Update analysis count in Redis if available
 	if rdb != nil {
 		if err := rdb.Incr(ctx, "analyses").Err(); err != nil {
@@ -168,38 +242,32 @@ func (h *Handler) AnalyzeHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if h.FirebaseClient != nil {
-		apiKeyVal := r.Context().Value(ApiKeyContextKey)
-		apiKeyStr, ok := apiKeyVal.(string)
-		if !ok || apiKeyStr == "" {
-			SendError(w, "API key missing from context", http.StatusUnauthorized)
-			return
-		}
+	if h.FirebaseClient == nil || apiKeyStr == "" {
+		return
+	}
 
-		scanID := uuid.New().String()
-		fmt.Printf("Storing scan: apiKey=%s, scanID=%s", apiKeyStr, scanID)
+	scanID := uuid.New().String()
+	fmt.Printf("Storing scan: apiKey=%s, scanID=%s", apiKeyStr, scanID)
 
-		scanData := map[string]interface{}{
-			"code":           req.Code,
-			"analysisResult": analysis,
-			"timestamp":      time.Now(),
-		}
+	scanData := map[string]interface{}{
+		"code":           req.Code,
+		"path":           req.Path,
+		"language":       req.Language,
+		"analysisResult": analysis,
+		"timestamp":      time.Now(),
+	}
 
-		_, err := h.FirebaseClient.Collection("code_scans").
-			Doc(apiKeyStr).
-			Set(ctx, map[string]interface{}{
-				scanID: scanData,
-			}, firestore.MergeAll)
+	_, err := h.FirebaseClient.Collection("code_scans").
+		Doc(apiKeyStr).
+		Set(ctx, map[string]interface{}{
+			scanID: scanData,
+		}, firestore.MergeAll)
 
-		if err != nil {
-			log.Printf("Failed to store analysis in Firestore: apiKey=%s, scanID=%s, error=%v", apiKeyStr, scanID, err)
-		} else {
-			log.Printf("Successfully stored analysis in Firestore: apiKey=%s, scanID=%s", apiKeyStr, scanID)
-		}
+	if err != nil {
+		log.Printf("Failed to store analysis in Firestore: apiKey=%s, scanID=%s, error=%v", apiKeyStr, scanID, err)
+	} else {
+		log.Printf("Successfully stored analysis in Firestore: apiKey=%s, scanID=%s", apiKeyStr, scanID)
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(analysis)
 }
 
 // FixIssuesHandler handles code fixing requests
@@ -238,7 +306,7 @@ func (h *Handler) FixIssuesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fixResp, err := services.FixIssues(req.Code, req.Suggestion, req.Problem)
+	fixResp, err := services.FixIssues(r.Context(), h.Provider, req.Code, req.Suggestion, req.Problem)
 	if err != nil {
 		SendError(w, fmt.Sprintf("FixIssues failed: %v", err), http.StatusInternalServerError)
 		return
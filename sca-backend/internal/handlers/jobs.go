@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"sca-backend/internal/jobs"
+	"sca-backend/internal/models"
+)
+
+// CreateJobHandler queues a code analysis and responds with the job, which
+// is polled with GET /api/jobs/{id}
+func (h *Handler) CreateJobHandler(w http.ResponseWriter, r *http.Request) {
+	req, ok := readCodeRequest(w, r)
+	if !ok {
+		return
+	}
+	owner, ok := h.requestOwner(w, r)
+	if !ok {
+		return
+	}
+
+	job, err := h.Jobs.Submit(r.Context(), owner, req)
+	if errors.Is(err, jobs.ErrQueueFull) {
+		w.Header().Set("Retry-After", "30")
+		SendError(w, "Too many queued analyses, please try again later", http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to queue analysis: %v", err)
+		SendError(w, "Failed to queue analysis", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Location", "/api/jobs/"+job.ID)
+	sendJob(w, job, http.StatusAccepted)
+}
+
+// GetJobHandler responds with the status of a job and, once it is done, the
+// analysis
+func (h *Handler) GetJobHandler(w http.ResponseWriter, r *http.Request) {
+	owner, ok := h.requestOwner(w, r)
+	if !ok {
+		return
+	}
+	job, err := h.Jobs.Get(r.Context(), owner, r.PathValue("id"))
+	if err != nil {
+		sendJobError(w, err)
+		return
+	}
+	sendJob(w, job, http.StatusOK)
+}
+
+// CancelJobHandler cancels a queued or running job. Jobs that have already
+// finished are left as they are and answered with 409 Conflict.
+func (h *Handler) CancelJobHandler(w http.ResponseWriter, r *http.Request) {
+	owner, ok := h.requestOwner(w, r)
+	if !ok {
+		return
+	}
+	job, err := h.Jobs.Cancel(r.Context(), owner, r.PathValue("id"))
+	if err != nil {
+		sendJobError(w, err)
+		return
+	}
+	if job.Status != models.JobCancelled {
+		SendError(w, fmt.Sprintf("Job has already finished with status %s", job.Status), http.StatusConflict)
+		return
+	}
+	sendJob(w, job, http.StatusOK)
+}
+
+// sendJob sends a job as JSON with the given status code
+func sendJob(w http.ResponseWriter, job *models.Job, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(job)
+}
+
+// sendJobError sends the error response for a failed job lookup
+func sendJobError(w http.ResponseWriter, err error) {
+	if errors.Is(err, jobs.ErrNotFound) {
+		SendError(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	log.Printf("Failed to load job: %v", err)
+	SendError(w, "Failed to load job", http.StatusInternalServerError)
+}
@@ -0,0 +1,303 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"sca-backend/internal/config"
+	"sca-backend/internal/models"
+)
+
+// AnalyzeFunc analyzes the code of a job submitted with the API key owner
+type AnalyzeFunc func(ctx context.Context, owner string, req models.CodeRequest) (*models.AnalysisResponse, error)
+
+// cancelCheckInterval is how often a running job checks whether it was
+// cancelled through another instance of the backend
+const cancelCheckInterval = 2 * time.Second
+
+// Queue runs analysis jobs on a pool of workers. Jobs are kept in Redis when
+// it is available and in memory otherwise.
+type Queue struct {
+	store   store
+	analyze AnalyzeFunc
+	workers int
+
+	mu      sync.Mutex
+	running map[string]context.CancelFunc
+}
+
+// NewQueue returns a queue backed by rdb, or by memory when rdb is nil
+func NewQueue(rdb *redis.Client, analyze AnalyzeFunc, cfg config.Jobs) *Queue {
+	var s store = newMemoryStore(cfg.TTL)
+	if rdb != nil {
+		s = &redisStore{rdb: rdb, ttl: cfg.TTL, instance: uuid.New().String()}
+	}
+	return &Queue{
+		store:   s,
+		analyze: analyze,
+		workers: cfg.Workers,
+		running: make(map[string]context.CancelFunc),
+	}
+}
+
+// Start takes a lease on the jobs this instance will run, launches the
+// workers and keeps requeuing the jobs of instances that stopped before
+// finishing them. Everything stops when ctx is cancelled.
+func (q *Queue) Start(ctx context.Context) {
+	// The lease must exist before the first job is taken, or another
+	// instance could requeue it
+	if err := q.store.heartbeat(ctx); err != nil {
+		log.Printf("Failed to take a job lease: %v", err)
+	}
+	if err := q.recover(ctx); err != nil {
+		log.Printf("Failed to requeue unfinished jobs: %v", err)
+	}
+	for i := 0; i < q.workers; i++ {
+		go q.work(ctx)
+	}
+	go q.maintain(ctx)
+}
+
+// maintain renews the lease of this instance and requeues orphaned jobs
+// until ctx is cancelled
+func (q *Queue) maintain(ctx context.Context) {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := q.store.heartbeat(ctx); err != nil {
+				log.Printf("Failed to renew job lease: %v", err)
+			}
+			if err := q.recover(ctx); err != nil {
+				log.Printf("Failed to requeue unfinished jobs: %v", err)
+			}
+		}
+	}
+}
+
+// Submit queues req for analysis on behalf of owner
+func (q *Queue) Submit(ctx context.Context, owner string, req models.CodeRequest) (*models.Job, error) {
+	now := time.Now().UTC()
+	rec := &record{
+		Job: models.Job{
+			ID:        uuid.New().String(),
+			Status:    models.JobQueued,
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		Owner:   owner,
+		Request: req,
+	}
+	if err := q.store.push(ctx, rec); err != nil {
+		return nil, err
+	}
+	return &rec.Job, nil
+}
+
+// Get returns the job with the given ID if it belongs to owner
+func (q *Queue) Get(ctx context.Context, owner, id string) (*models.Job, error) {
+	rec, err := q.load(ctx, owner, id)
+	if err != nil {
+		return nil, err
+	}
+	return &rec.Job, nil
+}
+
+// Cancel stops the job with the given ID if it belongs to owner and has not
+// finished yet, and returns it
+func (q *Queue) Cancel(ctx context.Context, owner, id string) (*models.Job, error) {
+	rec, err := q.store.update(ctx, id, func(rec *record) bool {
+		if rec.Owner != owner || finished(rec.Job.Status) {
+			return false
+		}
+		rec.Job.Status = models.JobCancelled
+		rec.Job.UpdatedAt = time.Now().UTC()
+		rec.Request = models.CodeRequest{}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if rec.Owner != owner {
+		return nil, ErrNotFound
+	}
+
+	// Jobs running on other instances notice on their next check
+	if rec.Job.Status == models.JobCancelled {
+		q.mu.Lock()
+		if cancel, ok := q.running[id]; ok {
+			cancel()
+		}
+		q.mu.Unlock()
+	}
+	return &rec.Job, nil
+}
+
+// finished reports whether a job with status can no longer change
+func finished(status string) bool {
+	return status != models.JobQueued && status != models.JobRunning
+}
+
+// load returns the record of a job, hiding jobs of other API keys
+func (q *Queue) load(ctx context.Context, owner, id string) (*record, error) {
+	rec, err := q.store.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if rec.Owner != owner {
+		return nil, ErrNotFound
+	}
+	return rec, nil
+}
+
+// work runs queued jobs one at a time until ctx is cancelled
+func (q *Queue) work(ctx context.Context) {
+	for ctx.Err() == nil {
+		id, err := q.store.pop(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Failed to take a job from the queue: %v", err)
+				time.Sleep(time.Second)
+			}
+			continue
+		}
+		if id == "" {
+			continue
+		}
+		q.run(ctx, id)
+		// A job interrupted by shutdown stays in the processing list and is
+		// queued again once the lease of this instance expires
+		if ctx.Err() == nil {
+			if err := q.store.ack(ctx, id); err != nil {
+				log.Printf("Failed to acknowledge job %s: %v", id, err)
+			}
+		}
+	}
+}
+
+// run analyzes one job and records its outcome
+func (q *Queue) run(ctx context.Context, id string) {
+	started := false
+	rec, err := q.store.update(ctx, id, func(rec *record) bool {
+		// Jobs cancelled while queued are skipped
+		if rec.Job.Status != models.JobQueued {
+			return false
+		}
+		rec.Job.Status = models.JobRunning
+		rec.Job.UpdatedAt = time.Now().UTC()
+		started = true
+		return true
+	})
+	if errors.Is(err, ErrNotFound) {
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to start job %s: %v", id, err)
+		return
+	}
+	if !started {
+		return
+	}
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	q.mu.Lock()
+	q.running[id] = cancel
+	q.mu.Unlock()
+	defer func() {
+		q.mu.Lock()
+		delete(q.running, id)
+		q.mu.Unlock()
+	}()
+	go q.watchCancel(jobCtx, id, cancel)
+
+	analysis, analyzeErr := q.analyze(jobCtx, rec.Owner, rec.Request)
+	if ctx.Err() != nil {
+		// Shutting down; the job is run again after the restart
+		return
+	}
+
+	// A cancellation that got in first wins over the outcome
+	_, err = q.store.update(ctx, id, func(rec *record) bool {
+		if finished(rec.Job.Status) {
+			return false
+		}
+		if analyzeErr != nil {
+			rec.Job.Status = models.JobFailed
+			rec.Job.Error = analyzeErr.Error()
+		} else {
+			rec.Job.Status = models.JobDone
+			rec.Job.Result = analysis
+		}
+		rec.Job.UpdatedAt = time.Now().UTC()
+		// The code is not needed any more
+		rec.Request = models.CodeRequest{}
+		return true
+	})
+	if err != nil {
+		log.Printf("Failed to save the result of job %s: %v", id, err)
+	}
+}
+
+// recover queues again the jobs taken from the queue by instances whose
+// lease expired before they finished them. Their status is reset first,
+// since workers skip jobs that are not queued.
+func (q *Queue) recover(ctx context.Context) error {
+	instances, err := q.store.orphaned(ctx)
+	if err != nil {
+		return err
+	}
+	for _, instance := range instances {
+		ids, err := q.store.processing(ctx, instance)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			_, err := q.store.update(ctx, id, func(rec *record) bool {
+				if rec.Job.Status != models.JobRunning {
+					return false
+				}
+				rec.Job.Status = models.JobQueued
+				rec.Job.UpdatedAt = time.Now().UTC()
+				return true
+			})
+			if err != nil && !errors.Is(err, ErrNotFound) {
+				return err
+			}
+		}
+		if len(ids) > 0 {
+			log.Printf("Requeuing %d unfinished job(s) of a stopped instance", len(ids))
+		}
+		if err := q.store.requeue(ctx, instance); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// watchCancel cancels a running job once its stored status says it was
+// cancelled, until ctx is done
+func (q *Queue) watchCancel(ctx context.Context, id string, cancel context.CancelFunc) {
+	ticker := time.NewTicker(cancelCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if rec, err := q.store.load(ctx, id); err == nil && rec.Job.Status == models.JobCancelled {
+				cancel()
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,336 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"sca-backend/internal/models"
+)
+
+// ErrNotFound is returned for jobs that do not exist, have expired or
+// belong to another API key
+var ErrNotFound = errors.New("job not found")
+
+// ErrQueueFull is returned when the in-memory queue cannot take more jobs
+var ErrQueueFull = errors.New("job queue is full")
+
+// record is a job as stored, with the request it analyzes and the API key
+// that submitted it
+type record struct {
+	Job     models.Job         `json:"job"`
+	Owner   string             `json:"owner"`
+	Request models.CodeRequest `json:"request"`
+}
+
+// store keeps job records and the queue of jobs waiting for a worker
+type store interface {
+	// push saves a new record and queues it
+	push(ctx context.Context, rec *record) error
+	// pop waits for the ID of the next queued job and marks it as being
+	// processed until ack is called. It returns "" when no job arrived for
+	// a while, so workers can check for shutdown.
+	pop(ctx context.Context) (string, error)
+	// ack marks a popped job as no longer being processed
+	ack(ctx context.Context, id string) error
+	// heartbeat renews this instance's lease on the jobs it is processing
+	heartbeat(ctx context.Context) error
+	// orphaned returns the instances whose lease has expired, such as
+	// backends that stopped mid-analysis
+	orphaned(ctx context.Context) ([]string, error)
+	// processing returns the IDs of the jobs instance popped but did not ack
+	processing(ctx context.Context, instance string) ([]string, error)
+	// requeue puts the jobs instance was processing back on the queue and
+	// forgets the instance
+	requeue(ctx context.Context, instance string) error
+	load(ctx context.Context, id string) (*record, error)
+	// update atomically applies fn to a record and saves it if fn returns
+	// true. It returns the record as it is afterwards.
+	update(ctx context.Context, id string, fn func(*record) bool) (*record, error)
+}
+
+// Redis keys of the queue and of the set of backend instances taking jobs
+// from it
+const (
+	redisQueueKey     = "jobs:queue"
+	redisInstancesKey = "jobs:instances"
+)
+
+func redisJobKey(id string) string {
+	return "job:" + id
+}
+
+// redisProcessingKey is the list of jobs an instance took from the queue
+func redisProcessingKey(instance string) string {
+	return "jobs:processing:" + instance
+}
+
+// redisLeaseKey exists while an instance is alive
+func redisLeaseKey(instance string) string {
+	return "jobs:lease:" + instance
+}
+
+// Jobs of an instance whose lease is not renewed for leaseTTL are queued
+// again. The lease is renewed every heartbeatInterval.
+const (
+	leaseTTL          = 30 * time.Second
+	heartbeatInterval = 10 * time.Second
+)
+
+// maxUpdateAttempts bounds the retries of an update that keeps losing the
+// race with another update of the same job
+const maxUpdateAttempts = 10
+
+// redisStore keeps jobs in Redis so that they survive restarts and can be
+// run by any instance of the backend. Each instance keeps the jobs it took
+// in its own processing list, so others only requeue them once its lease
+// expires.
+type redisStore struct {
+	rdb      *redis.Client
+	ttl      time.Duration
+	instance string
+}
+
+func (s *redisStore) push(ctx context.Context, rec *record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to serialize job: %v", err)
+	}
+	_, err = s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, redisJobKey(rec.Job.ID), data, s.ttl)
+		pipe.LPush(ctx, redisQueueKey, rec.Job.ID)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to queue job: %v", err)
+	}
+	return nil
+}
+
+func (s *redisStore) pop(ctx context.Context) (string, error) {
+	id, err := s.rdb.BLMove(ctx, redisQueueKey, redisProcessingKey(s.instance), "RIGHT", "LEFT", 5*time.Second).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return id, err
+}
+
+func (s *redisStore) ack(ctx context.Context, id string) error {
+	// A job requeued while this instance still ran it may be listed twice
+	if err := s.rdb.LRem(ctx, redisProcessingKey(s.instance), 0, id).Err(); err != nil {
+		return fmt.Errorf("failed to acknowledge job: %v", err)
+	}
+	return nil
+}
+
+func (s *redisStore) heartbeat(ctx context.Context) error {
+	_, err := s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SAdd(ctx, redisInstancesKey, s.instance)
+		pipe.Set(ctx, redisLeaseKey(s.instance), time.Now().UTC().Format(time.RFC3339), leaseTTL)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to renew job lease: %v", err)
+	}
+	return nil
+}
+
+func (s *redisStore) orphaned(ctx context.Context) ([]string, error) {
+	instances, err := s.rdb.SMembers(ctx, redisInstancesKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backend instances: %v", err)
+	}
+	var orphans []string
+	for _, instance := range instances {
+		if instance == s.instance {
+			continue
+		}
+		n, err := s.rdb.Exists(ctx, redisLeaseKey(instance)).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to check job lease: %v", err)
+		}
+		if n == 0 {
+			orphans = append(orphans, instance)
+		}
+	}
+	return orphans, nil
+}
+
+func (s *redisStore) processing(ctx context.Context, instance string) ([]string, error) {
+	ids, err := s.rdb.LRange(ctx, redisProcessingKey(instance), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs in progress: %v", err)
+	}
+	return ids, nil
+}
+
+func (s *redisStore) requeue(ctx context.Context, instance string) error {
+	for {
+		// The oldest job goes back to the end the workers take from
+		err := s.rdb.LMove(ctx, redisProcessingKey(instance), redisQueueKey, "RIGHT", "RIGHT").Err()
+		if errors.Is(err, redis.Nil) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to requeue jobs: %v", err)
+		}
+	}
+	if err := s.rdb.SRem(ctx, redisInstancesKey, instance).Err(); err != nil {
+		return fmt.Errorf("failed to forget backend instance: %v", err)
+	}
+	return nil
+}
+
+func (s *redisStore) load(ctx context.Context, id string) (*record, error) {
+	data, err := s.rdb.Get(ctx, redisJobKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load job: %v", err)
+	}
+	var rec record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("failed to parse job: %v", err)
+	}
+	return &rec, nil
+}
+
+func (s *redisStore) update(ctx context.Context, id string, fn func(*record) bool) (*record, error) {
+	key := redisJobKey(id)
+	for attempt := 0; attempt < maxUpdateAttempts; attempt++ {
+		var rec record
+		// The transaction fails if the job changes after it is read
+		err := s.rdb.Watch(ctx, func(tx *redis.Tx) error {
+			data, err := tx.Get(ctx, key).Bytes()
+			if errors.Is(err, redis.Nil) {
+				return ErrNotFound
+			}
+			if err != nil {
+				return fmt.Errorf("failed to load job: %v", err)
+			}
+			if err := json.Unmarshal(data, &rec); err != nil {
+				return fmt.Errorf("failed to parse job: %v", err)
+			}
+			if !fn(&rec) {
+				return nil
+			}
+
+			if data, err = json.Marshal(&rec); err != nil {
+				return fmt.Errorf("failed to serialize job: %v", err)
+			}
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.Set(ctx, key, data, s.ttl)
+				return nil
+			})
+			return err
+		}, key)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &rec, nil
+	}
+	return nil, fmt.Errorf("failed to save job: it kept changing")
+}
+
+// memoryQueueSize bounds the jobs waiting in the in-memory queue
+const memoryQueueSize = 1000
+
+// memoryStore keeps jobs in memory when Redis is unavailable. Jobs are lost
+// on restart, so there is nothing to requeue.
+type memoryStore struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	records map[string]record
+	queue   chan string
+}
+
+func newMemoryStore(ttl time.Duration) *memoryStore {
+	return &memoryStore{
+		ttl:     ttl,
+		records: make(map[string]record),
+		queue:   make(chan string, memoryQueueSize),
+	}
+}
+
+func (s *memoryStore) push(ctx context.Context, rec *record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Drop expired jobs here rather than in a janitor goroutine
+	now := time.Now()
+	for id, r := range s.records {
+		if now.Sub(r.Job.UpdatedAt) > s.ttl {
+			delete(s.records, id)
+		}
+	}
+
+	select {
+	case s.queue <- rec.Job.ID:
+		s.records[rec.Job.ID] = *rec
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+func (s *memoryStore) pop(ctx context.Context) (string, error) {
+	select {
+	case id := <-s.queue:
+		return id, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func (s *memoryStore) ack(ctx context.Context, id string) error {
+	return nil
+}
+
+func (s *memoryStore) heartbeat(ctx context.Context) error {
+	return nil
+}
+
+func (s *memoryStore) orphaned(ctx context.Context) ([]string, error) {
+	return nil, nil
+}
+
+func (s *memoryStore) processing(ctx context.Context, instance string) ([]string, error) {
+	return nil, nil
+}
+
+func (s *memoryStore) requeue(ctx context.Context, instance string) error {
+	return nil
+}
+
+func (s *memoryStore) load(ctx context.Context, id string) (*record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, ok := s.records[id]
+	if !ok || time.Since(rec.Job.UpdatedAt) > s.ttl {
+		return nil, ErrNotFound
+	}
+	return &rec, nil
+}
+
+func (s *memoryStore) update(ctx context.Context, id string, fn func(*record) bool) (*record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, ok := s.records[id]
+	if !ok || time.Since(rec.Job.UpdatedAt) > s.ttl {
+		return nil, ErrNotFound
+	}
+	if fn(&rec) {
+		s.records[id] = rec
+	}
+	return &rec, nil
+}
@@ -1,14 +1,29 @@
 package models
 
+import "time"
+
 // Stats maintains visitor and analysis counts
 type Stats struct {
 	Visitors int `json:"visitors"`
 	Analyses int `json:"analyses"`
 }
 
-// CodeRequest represents the request body from client
+// CodeRequest represents the request body from client. Only Code is
+// required; the rest lets the review focus on the file's language and
+// project.
 type CodeRequest struct {
-	Code string `json:"code"`
+	Code     string `json:"code"`
+	Filename string `json:"filename,omitempty"`
+	Language string `json:"language,omitempty"`
+	// Path is the file's path relative to the repository root
+	Path    string          `json:"path,omitempty"`
+	Context *ProjectContext `json:"context,omitempty"`
+}
+
+// ProjectContext describes the project a file belongs to
+type ProjectContext struct {
+	Frameworks []string `json:"frameworks,omitempty"`
+	GoModule   string   `json:"go_module,omitempty"`
 }
 
 // Issue represents a single code issue
@@ -35,23 +50,41 @@ type AnalysisResponse struct {
 	Maintainability Category `json:"maintainability"`
 	BestPractices   Category `json:"best_practices"`
 	Suggestions     []string `json:"suggestions"`
+	// Partial is set when some of the code could not be analyzed because
+	// the model never returned a valid analysis for it. Issues and scores
+	// cover only the rest of the file.
+	Partial bool `json:"partial,omitempty"`
+	// Unanalyzed lists the lines of a partial analysis that were not
+	// analyzed
+	Unanalyzed []LineRange `json:"unanalyzed,omitempty"`
 }
 
-// DigitalOceanMessage represents a message in the DigitalOcean AI API
-type DigitalOceanMessage struct {
+// LineRange is a range of lines of a file, inclusive and numbered from 1
+type LineRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// ChatMessage represents a message of a chat completion
+type ChatMessage struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
 }
 
-// DigitalOceanRequest represents the request to DigitalOcean AI API
-type DigitalOceanRequest struct {
-	Messages    []DigitalOceanMessage `json:"messages"`
-	MaxTokens   int                   `json:"max_completion_tokens,omitempty"`
-	Temperature float64               `json:"temperature,omitempty"`
+// ChatRequest represents an OpenAI-compatible chat completion request, as
+// accepted by DigitalOcean agents, OpenAI and llama.cpp
+type ChatRequest struct {
+	Model    string        `json:"model,omitempty"`
+	Messages []ChatMessage `json:"messages"`
+	// MaxCompletionTokens replaces MaxTokens in the OpenAI API, but local
+	// servers such as llama.cpp only know MaxTokens
+	MaxCompletionTokens int     `json:"max_completion_tokens,omitempty"`
+	MaxTokens           int     `json:"max_tokens,omitempty"`
+	Temperature         float64 `json:"temperature,omitempty"`
 }
 
-// DigitalOceanResponse represents the response from DigitalOcean AI API
-type DigitalOceanResponse struct {
+// ChatResponse represents an OpenAI-compatible chat completion response
+type ChatResponse struct {
 	Choices []struct {
 		Message struct {
 			Content string `json:"content"`
@@ -63,6 +96,27 @@ type DigitalOceanResponse struct {
 	} `json:"error,omitempty"`
 }
 
+// OllamaRequest represents a request to the Ollama chat API
+type OllamaRequest struct {
+	Model    string        `json:"model"`
+	Messages []ChatMessage `json:"messages"`
+	Stream   bool          `json:"stream"`
+	Format   string        `json:"format,omitempty"`
+	Options  OllamaOptions `json:"options"`
+}
+
+// OllamaOptions represents the model options of an Ollama request
+type OllamaOptions struct {
+	Temperature float64 `json:"temperature"`
+	NumPredict  int     `json:"num_predict,omitempty"`
+}
+
+// OllamaResponse represents the response from the Ollama chat API
+type OllamaResponse struct {
+	Message ChatMessage `json:"message"`
+	Error   string      `json:"error,omitempty"`
+}
+
 // ApiErrorResponse represents the error response structure
 type ApiErrorResponse struct {
 	Message string `json:"message"`
@@ -87,3 +141,23 @@ type FixIssuesResponse struct {
 	Confidence  int    `json:"confidence"`
 	Changelog   string `json:"changelog"`
 }
+
+// Job statuses
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobDone      = "done"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// Job represents an analysis queued with POST /api/jobs. Result is set once
+// the job is done, and Error once it has failed.
+type Job struct {
+	ID        string            `json:"id"`
+	Status    string            `json:"status"`
+	Result    *AnalysisResponse `json:"result,omitempty"`
+	Error     string            `json:"error,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
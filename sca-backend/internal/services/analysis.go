@@ -1,113 +1,240 @@
 package services
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
-	"os"
+	"log"
+	"sort"
 	"strings"
+	"sync"
 
+	"sca-backend/internal/config"
 	"sca-backend/internal/models"
 )
 
-const digitalOceanURL = "https://ipwpfibhdjn5nc34bk25sueu.agents.do-ai.run/api/v1/chat/completions"
-
-// AnalyzeCode performs code analysis using DigitalOcean AI
-func AnalyzeCode(code string) (*models.AnalysisResponse, error) {
-	// Prepare request for DigitalOcean AI
-	doReq := models.DigitalOceanRequest{
-		Messages: []models.DigitalOceanMessage{
-			{
-				Role:    "user",
-				Content: code,
-			},
-		},
-		MaxTokens:   2000,
-		Temperature: 0.1, // Low temperature for consistent JSON output
+// ErrInvalidOutput is returned when the model still replies with an
+// analysis that does not match the schema after every repair attempt
+var ErrInvalidOutput = errors.New("the AI model did not return a valid analysis")
+
+// AnalyzeCode performs code analysis with the configured provider, focusing
+// on the problems common in the file's language and frameworks. Files too
+// large for one prompt are split into chunks that are analyzed in parallel
+// and merged into one analysis. When the model cannot produce a valid
+// analysis for some chunks the result is marked partial; when it fails for
+// all of them ErrInvalidOutput is returned.
+func AnalyzeCode(ctx context.Context, provider Provider, req models.CodeRequest, opts config.Analysis) (*models.AnalysisResponse, error) {
+	chunks := splitCode(req.Code, normalizeLanguage(req), opts.ChunkSize, opts.ChunkOverlap)
+	total := chunks[len(chunks)-1].end()
+	if len(chunks) == 1 {
+		return analyzeChunk(ctx, provider, req, chunks[0], total, opts.MaxRepairs)
 	}
 
-	jsonBody, err := json.Marshal(doReq)
-	if err != nil {
-		return nil, fmt.Errorf("failed to serialize request: %v", err)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		invalid  error
+		sem      = make(chan struct{}, max(opts.Concurrency, 1))
+		analyses = make([]*models.AnalysisResponse, len(chunks))
+	)
+	for i, c := range chunks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if ctx.Err() != nil {
+				return
+			}
+
+			analysis, err := analyzeChunk(ctx, provider, req, c, total, opts.MaxRepairs)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case errors.Is(err, ErrInvalidOutput):
+				// The rest of the file is still worth reporting
+				invalid = err
+			case err != nil:
+				if firstErr == nil {
+					firstErr = fmt.Errorf("lines %d-%d: %w", c.start, c.end(), err)
+					// The file cannot be analyzed whole, so stop the other chunks
+					cancel()
+				}
+			default:
+				analyses[i] = analysis
+			}
+		}()
 	}
-
-	// Send request to DigitalOcean AI
-	httpReq, err := http.NewRequest("POST", digitalOceanURL, bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	merged := mergeAnalyses(chunks, analyses)
+	if merged == nil {
+		return nil, invalid
 	}
+	return merged, nil
+}
 
-	// Set required headers for DigitalOcean AI API
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", os.Getenv("DIGITALOCEAN_API_KEY")))
+// analyzeChunk analyzes one chunk of a file of total lines, returning issues
+// with line numbers in the file. A reply that does not match the schema is
+// sent back with its problems up to repairs times.
+func analyzeChunk(ctx context.Context, provider Provider, req models.CodeRequest, part chunk, total, repairs int) (*models.AnalysisResponse, error) {
+	request := analysisRequest(req, part, total)
+	prompt := request
+	var problems []string
+	for attempt := 0; attempt <= repairs; attempt++ {
+		reply, err := provider.Complete(ctx, Completion{
+			Task:        TaskAnalyze,
+			System:      analysisPrompt,
+			Prompt:      prompt,
+			MaxTokens:   2000,
+			Temperature: 0.1, // Low temperature for consistent JSON output
+		})
+		if err != nil {
+			return nil, err
+		}
 
-	client := &http.Client{Timeout: 60 * 1000000000} // 60 seconds
-	httpResp, err := client.Do(httpReq)
-	if err != nil {
-		return nil, fmt.Errorf("failed to contact DigitalOcean AI API: %v", err)
+		var analysis *models.AnalysisResponse
+		analysis, problems = parseAnalysis(reply)
+		if analysis != nil {
+			offsetLines(analysis, part)
+			return analysis, nil
+		}
+		log.Printf("Invalid analysis of lines %d-%d (attempt %d of %d): %s",
+			part.start, part.end(), attempt+1, repairs+1, strings.Join(problems, "; "))
+		prompt = repairRequest(request, reply, problems)
 	}
-	defer httpResp.Body.Close()
+	return nil, fmt.Errorf("%w: %s", ErrInvalidOutput, strings.Join(problems, "; "))
+}
 
-	respBody, err := io.ReadAll(httpResp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read AI response: %v", err)
+// offsetLines turns the line numbers of an analysis of part, which the
+// model counts from the start of the chunk, into line numbers in the file.
+// Lines outside the chunk are made up and dropped.
+func offsetLines(analysis *models.AnalysisResponse, part chunk) {
+	for _, c := range categories(analysis) {
+		for i := range c.Issues {
+			if line := c.Issues[i].Line; line < 1 || line > len(part.lines) {
+				c.Issues[i].Line = 0
+			} else {
+				c.Issues[i].Line = line + part.start - 1
+			}
+		}
 	}
+}
 
-	if httpResp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("AI service temporarily unavailable (status %d)", httpResp.StatusCode)
+// mergeAnalyses combines the analyses of the chunks of a file. Issues found
+// twice where chunks overlap are kept once. The security score is the
+// lowest of the chunks, since one hole anywhere exposes the whole file;
+// the other scores are averaged, weighted by the lines of each chunk.
+// Chunks without an analysis make the result partial, and nil is returned
+// when no chunk has one.
+func mergeAnalyses(chunks []chunk, analyses []*models.AnalysisResponse) *models.AnalysisResponse {
+	merged := &models.AnalysisResponse{Suggestions: []string{}}
+	targets := categories(merged)
+	for _, c := range targets {
+		c.Issues = []models.Issue{}
 	}
 
-	var doResponse models.DigitalOceanResponse
-	if err := json.Unmarshal(respBody, &doResponse); err != nil {
-		return nil, fmt.Errorf("failed to parse AI response: %v", err)
-	}
+	var lines float64
+	seen := make(map[string]bool)
+	suggested := make(map[string]bool)
+	for i, analysis := range analyses {
+		if analysis == nil {
+			continue
+		}
+		first := lines == 0
+		weight := float64(len(chunks[i].lines))
+		lines += weight
+		merged.OverallScore += analysis.OverallScore * weight
+
+		for j, c := range categories(analysis) {
+			target := targets[j]
+			if j == 0 {
+				if first || c.Score < target.Score {
+					target.Score = c.Score
+				}
+			} else {
+				target.Score += c.Score * weight
+			}
+			for _, issue := range c.Issues {
+				key := fmt.Sprintf("%d\x00%d\x00%s", j, issue.Line, strings.ToLower(issue.Type))
+				if issue.Line == 0 {
+					key += "\x00" + issue.Description
+				}
+				if !seen[key] {
+					seen[key] = true
+					target.Issues = append(target.Issues, issue)
+				}
+			}
+		}
 
-	// Check for API errors
-	if doResponse.Error != nil {
-		return nil, fmt.Errorf("AI API error: %s", doResponse.Error.Message)
+		for _, s := range analysis.Suggestions {
+			if !suggested[s] {
+				suggested[s] = true
+				merged.Suggestions = append(merged.Suggestions, s)
+			}
+		}
 	}
 
-	if len(doResponse.Choices) == 0 {
-		return nil, fmt.Errorf("no response from AI model")
+	if lines == 0 {
+		return nil
 	}
+	merged.Unanalyzed = unanalyzedLines(chunks, analyses)
+	merged.Partial = len(merged.Unanalyzed) > 0
 
-	// Clean the response to extract JSON
-	content := doResponse.Choices[0].Message.Content
-	start := strings.Index(content, "{")
-	end := strings.LastIndex(content, "}")
-	if start >= 0 && end >= 0 && end > start {
-		content = content[start : end+1]
+	merged.OverallScore = roundScore(merged.OverallScore / lines)
+	for j, target := range targets {
+		if j > 0 {
+			target.Score = roundScore(target.Score / lines)
+		}
+		// Issues without a line go last
+		sort.SliceStable(target.Issues, func(a, b int) bool {
+			la, lb := target.Issues[a].Line, target.Issues[b].Line
+			return la != 0 && (lb == 0 || la < lb)
+		})
 	}
+	return merged
+}
 
-	// Parse AI's JSON response
-	var analysis models.AnalysisResponse
-	if err := json.Unmarshal([]byte(content), &analysis); err != nil {
-		// Fallback: create a basic response if JSON parsing fails
-		analysis = models.AnalysisResponse{
-			OverallScore: 5.0,
-			Security: models.Category{
-				Score: 5.0,
-				Issues: []models.Issue{{
-					Severity:    "medium",
-					Type:        "Analysis Error",
-					Description: "Unable to complete full analysis due to parsing error",
-					Suggestion:  "Please try again or check code format",
-				}},
-			},
-			Performance:     models.Category{Score: 5.0, Issues: []models.Issue{}},
-			CodeQuality:     models.Category{Score: 5.0, Issues: []models.Issue{}},
-			Maintainability: models.Category{Score: 5.0, Issues: []models.Issue{}},
-			BestPractices:   models.Category{Score: 5.0, Issues: []models.Issue{}},
-			Suggestions:     []string{"Code analysis could not be completed fully"},
+// unanalyzedLines returns the ranges of lines covered only by chunks
+// without an analysis
+func unanalyzedLines(chunks []chunk, analyses []*models.AnalysisResponse) []models.LineRange {
+	covered := make([]bool, chunks[len(chunks)-1].end()+1)
+	for i, c := range chunks {
+		if analyses[i] != nil {
+			for line := c.start; line <= c.end(); line++ {
+				covered[line] = true
+			}
 		}
 	}
 
-	// Validate scores are within range
-	if analysis.OverallScore < 1 || analysis.OverallScore > 10 {
-		analysis.OverallScore = 5.0
+	var ranges []models.LineRange
+	for line := 1; line < len(covered); line++ {
+		switch {
+		case covered[line]:
+		case len(ranges) > 0 && ranges[len(ranges)-1].End == line-1:
+			ranges[len(ranges)-1].End = line
+		default:
+			ranges = append(ranges, models.LineRange{Start: line, End: line})
+		}
 	}
+	return ranges
+}
+
+// categories returns the categories of an analysis, security first
+func categories(a *models.AnalysisResponse) []*models.Category {
+	return []*models.Category{&a.Security, &a.Performance, &a.CodeQuality, &a.Maintainability, &a.BestPractices}
+}
 
-	return &analysis, nil
+// roundScore rounds a score to one decimal
+func roundScore(score float64) float64 {
+	return float64(int(score*10+0.5)) / 10
 }
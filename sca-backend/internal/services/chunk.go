@@ -0,0 +1,111 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+)
+
+// chunk is a run of lines of a file analyzed on its own
+type chunk struct {
+	// start is the line number of the first line in the file, from 1
+	start int
+	lines []string
+}
+
+// end returns the line number of the last line of the chunk
+func (c chunk) end() int {
+	return c.start + len(c.lines) - 1
+}
+
+// declarations match the lines that start a top-level declaration in each
+// language. Other languages fall back to looksLikeDeclaration.
+var declarations = map[string]*regexp.Regexp{
+	"go":         regexp.MustCompile(`^(func|type|var|const)\b`),
+	"python":     regexp.MustCompile(`^(@|(async\s+)?def\s|class\s)`),
+	"javascript": regexp.MustCompile(`^(export\s+)?(default\s+)?(async\s+)?(function|class|const|let)\b`),
+	"typescript": regexp.MustCompile(`^(export\s+)?(default\s+)?(declare\s+)?(async\s+)?(function|class|const|let|interface|type|enum)\b`),
+	"rust":       regexp.MustCompile(`^(#\[|(pub(\([^)]*\))?\s+)?(async\s+)?(unsafe\s+)?(fn|struct|enum|impl|trait|mod)\b)`),
+	"ruby":       regexp.MustCompile(`^\s{0,2}(def|class|module)\b`),
+	"php":        regexp.MustCompile(`^\s{0,4}(((public|private|protected|static|final|abstract)\s+)*function|(final\s+|abstract\s+)?class)\b`),
+	"terraform":  regexp.MustCompile(`^(resource|data|module|variable|output|locals|provider)\b`),
+}
+
+// commentLine matches comments and annotations that belong to the
+// declaration below them
+var commentLine = regexp.MustCompile(`^\s*(//|#|/\*|\*|@|"""|''')`)
+
+// splitCode splits code into chunks of at most size characters, ending each
+// chunk before a declaration where one is found in its second half. Each
+// chunk after the first repeats the last overlap lines of the one before.
+// Code that fits in size is returned as a single chunk.
+func splitCode(code, lang string, size, overlap int) []chunk {
+	lines := strings.Split(code, "\n")
+	if len(code) <= size {
+		return []chunk{{start: 1, lines: lines}}
+	}
+
+	var chunks []chunk
+	for start := 0; start < len(lines); {
+		end, n := start, 0
+		// A chunk holds at least one line, however long
+		for end < len(lines) && (end == start || n+len(lines[end])+1 <= size) {
+			n += len(lines[end]) + 1
+			end++
+		}
+		if end < len(lines) {
+			for i := end; i > start+(end-start)/2; i-- {
+				if isDeclaration(lines, i, lang) {
+					end = withLeadingComments(lines, i, start+1)
+					break
+				}
+			}
+		}
+		chunks = append(chunks, chunk{start: start + 1, lines: lines[start:end]})
+		if end == len(lines) {
+			break
+		}
+
+		next := end - overlap
+		if next <= start {
+			next = end
+		}
+		start = next
+	}
+	return chunks
+}
+
+// isDeclaration reports whether lines[i] starts a declaration
+func isDeclaration(lines []string, i int, lang string) bool {
+	if re, ok := declarations[lang]; ok {
+		return re.MatchString(lines[i])
+	}
+	return looksLikeDeclaration(lines, i)
+}
+
+// looksLikeDeclaration guesses that a line starts a declaration when it
+// follows a blank line and is indented by at most one level, as methods in
+// a class body are
+func looksLikeDeclaration(lines []string, i int) bool {
+	if i == 0 || strings.TrimSpace(lines[i-1]) != "" {
+		return false
+	}
+	line := strings.ReplaceAll(lines[i], "\t", "    ")
+	trimmed := strings.TrimLeft(line, " ")
+	if trimmed == "" || len(line)-len(trimmed) > 4 {
+		return false
+	}
+	switch trimmed[0] {
+	case '}', ')', ']':
+		return false
+	}
+	return true
+}
+
+// withLeadingComments moves the start of the declaration at lines[i] up
+// over the comments and annotations directly above it, but not above min
+func withLeadingComments(lines []string, i, min int) int {
+	for i > min && commentLine.MatchString(lines[i-1]) {
+		i--
+	}
+	return i
+}
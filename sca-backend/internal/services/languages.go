@@ -0,0 +1,285 @@
+package services
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"sca-backend/internal/models"
+)
+
+// extensionLanguages infers the language of a file sent without one
+var extensionLanguages = map[string]string{
+	".go":    "go",
+	".py":    "python",
+	".js":    "javascript",
+	".jsx":   "javascript",
+	".mjs":   "javascript",
+	".cjs":   "javascript",
+	".ts":    "typescript",
+	".tsx":   "typescript",
+	".java":  "java",
+	".kt":    "kotlin",
+	".c":     "c",
+	".h":     "c",
+	".cpp":   "cpp",
+	".cc":    "cpp",
+	".hpp":   "cpp",
+	".cs":    "csharp",
+	".rs":    "rust",
+	".rb":    "ruby",
+	".php":   "php",
+	".swift": "swift",
+	".tf":    "terraform",
+}
+
+// languageAliases maps other names clients use to the names of the guides
+var languageAliases = map[string]string{
+	"golang":        "go",
+	"py":            "python",
+	"js":            "javascript",
+	"ts":            "typescript",
+	"c++":           "cpp",
+	"c#":            "csharp",
+	"rs":            "rust",
+	"rb":            "ruby",
+	"hcl":           "terraform",
+	"k8s":           "kubernetes",
+	"docker":        "dockerfile",
+	"containerfile": "dockerfile",
+}
+
+// languageGuides are the problems to look for first in each language
+var languageGuides = map[string][]string{
+	"go": {
+		"errors that are ignored or returned without context",
+		"goroutines that leak or race on shared state without synchronization",
+		"SQL built with fmt.Sprintf or string concatenation instead of placeholders",
+		"exec.Command run with user input, and paths joined from user input without cleaning",
+		"HTTP servers and clients without timeouts, and response bodies left open",
+		"math/rand used for secrets instead of crypto/rand, and InsecureSkipVerify",
+	},
+	"python": {
+		"SQL built with f-strings, % or format instead of query parameters",
+		"eval, exec, pickle, yaml.load and subprocess with shell=True on untrusted input",
+		"bare except clauses that swallow errors",
+		"mutable default arguments",
+		"requests calls without a timeout or with verify=False",
+		"files and connections opened without a with block",
+	},
+	"javascript": {
+		"injection through eval, new Function, child_process.exec and template strings built into SQL or shell commands",
+		"XSS through innerHTML, dangerouslySetInnerHTML and unescaped template output",
+		"prototype pollution when merging untrusted objects",
+		"unhandled promise rejections and missing await",
+		"== instead of ===, and var instead of let or const",
+		"regular expressions vulnerable to catastrophic backtracking",
+	},
+	"typescript": {
+		"any and non-null assertions that hide type errors",
+		"injection through eval, child_process.exec and template strings built into SQL or shell commands",
+		"XSS through innerHTML and dangerouslySetInnerHTML",
+		"unhandled promise rejections and missing await",
+		"type assertions on untrusted input that is never validated",
+	},
+	"java": {
+		"SQL built by string concatenation instead of PreparedStatement parameters",
+		"deserialization of untrusted data with ObjectInputStream",
+		"XML parsers with external entities enabled",
+		"resources not closed with try-with-resources",
+		"catching Exception or Throwable and ignoring it",
+		"shared mutable state accessed from several threads without synchronization",
+	},
+	"kotlin": {
+		"!! assertions that can throw on null",
+		"SQL built with string templates instead of parameters",
+		"coroutines launched in GlobalScope or without structured cancellation",
+		"resources not closed with use",
+	},
+	"c": {
+		"buffer overflows from strcpy, strcat, sprintf, gets and unchecked lengths",
+		"format string vulnerabilities where user input is the format",
+		"use after free, double free and memory leaks",
+		"integer overflow in size calculations before allocation",
+		"unchecked return values of malloc and system calls",
+	},
+	"cpp": {
+		"buffer overflows from C string functions and unchecked indexing",
+		"use after free, dangling references and raw new/delete instead of RAII",
+		"undefined behaviour such as signed overflow and uninitialized variables",
+		"exceptions escaping destructors",
+		"data races on shared state",
+	},
+	"csharp": {
+		"SQL built by string concatenation instead of parameters",
+		"BinaryFormatter and other unsafe deserializers",
+		"IDisposable objects not disposed with using",
+		"async void methods and blocking on tasks with .Result or .Wait()",
+	},
+	"rust": {
+		"unsafe blocks and the invariants they rely on",
+		"unwrap and expect on values that can fail at runtime",
+		"panics in library code instead of returned errors",
+		"integer overflow in release builds, and unchecked indexing",
+		"Command built from user input, and SQL built with format!",
+		"blocking calls inside async code",
+	},
+	"ruby": {
+		"SQL built with string interpolation instead of bound parameters",
+		"eval, send, constantize and system called with user input",
+		"YAML.load and Marshal.load on untrusted data",
+		"mass assignment without strong parameters",
+	},
+	"php": {
+		"SQL built by concatenation instead of prepared statements",
+		"XSS from echoing request data without htmlspecialchars",
+		"include and require with user-controlled paths",
+		"unserialize, eval and shell functions on user input",
+		"loose comparisons with ==",
+	},
+	"swift": {
+		"force unwrapping and force try",
+		"retain cycles from closures capturing self strongly",
+		"secrets stored in UserDefaults instead of the Keychain",
+		"UI updates off the main thread",
+	},
+	"dockerfile": {
+		"containers running as root",
+		"base images without a pinned tag or digest",
+		"secrets in ENV and ARG instructions",
+		"scripts piped from curl or wget into a shell",
+		"missing HEALTHCHECK and unnecessary packages enlarging the image",
+	},
+	"compose": {
+		"privileged services and mounts of the Docker socket",
+		"secrets written in environment variables or command lines",
+		"database ports published on every host interface",
+		"images without a pinned tag and services without healthchecks",
+	},
+	"kubernetes": {
+		"privileged containers, privilege escalation and containers running as root",
+		"hostPath mounts and host network, PID or IPC namespaces",
+		"secrets committed in Secret manifests or env values",
+		"containers without resource limits or probes",
+	},
+	"terraform": {
+		"storage buckets and databases open to the public",
+		"security groups open to 0.0.0.0/0, especially on SSH and RDP",
+		"storage and databases without encryption at rest",
+		"secrets in attributes and variable defaults",
+	},
+}
+
+// frameworkGuides are the problems to look for first in projects built on
+// each framework
+var frameworkGuides = map[string]string{
+	"django":      "raw() and extra() queries, mark_safe on user input, DEBUG and ALLOWED_HOSTS settings, and views missing csrf protection",
+	"flask":       "render_template_string with user input, debug mode, and secret keys in the code",
+	"fastapi":     "endpoints without dependency-based authentication, and blocking calls in async endpoints",
+	"express":     "missing input validation, helmet and rate limiting, and req parameters passed to queries, paths or redirects",
+	"next.js":     "secrets exposed through NEXT_PUBLIC_ variables, API routes without authentication, and dangerouslySetInnerHTML",
+	"react":       "dangerouslySetInnerHTML, missing hook dependencies and unnecessary re-renders",
+	"vue":         "v-html with user input",
+	"angular":     "bypassSecurityTrust calls",
+	"nestjs":      "controllers without guards and DTOs without validation pipes",
+	"spring-boot": "actuator endpoints left open, disabled CSRF protection, and @Query strings built by concatenation",
+	"rails":       "params passed to where, order or find_by_sql as strings, html_safe and raw, and skipped before_action callbacks",
+	"laravel":     "DB::raw and whereRaw with user input, {!! !!} output, and mass assignment through $guarded = []",
+	"symfony":     "raw DQL built from request data, and routes without access control",
+	"gin":         "request binding without validation, and c.File or c.Redirect with user input",
+	"echo":        "request binding without validation, and c.File or c.Redirect with user input",
+	"fiber":       "request parsing without validation, and handlers that keep references to the request context",
+	"actix-web":   "extractors without size limits, and blocking work in handlers",
+	"axum":        "extractors without body size limits, and blocking work in handlers",
+}
+
+// normalizeLanguage returns the guide name of the language of req, inferred
+// from its filename when the client sent none
+func normalizeLanguage(req models.CodeRequest) string {
+	lang := strings.ToLower(strings.TrimSpace(req.Language))
+	if alias, ok := languageAliases[lang]; ok {
+		lang = alias
+	}
+	if lang != "" {
+		return lang
+	}
+	name := req.Filename
+	if name == "" {
+		name = path.Base(req.Path)
+	}
+	return extensionLanguages[strings.ToLower(path.Ext(name))]
+}
+
+// analysisRequest builds the user prompt for reviewing part of req, a file
+// of total lines: a header describing the file and its project, the problems
+// to look for first in its language and frameworks, and the code itself
+func analysisRequest(req models.CodeRequest, part chunk, total int) string {
+	var b strings.Builder
+	lang := normalizeLanguage(req)
+
+	file := req.Path
+	if file == "" {
+		file = req.Filename
+	}
+	if file = headerValue(file); file != "" {
+		b.WriteString("File: " + file + "\n")
+	}
+	if part.start != 1 || part.end() != total {
+		fmt.Fprintf(&b, "Part: lines %d-%d of %d; the rest of the file is reviewed separately\n", part.start, part.end(), total)
+	}
+	if lang != "" {
+		b.WriteString("Language: " + headerValue(lang) + "\n")
+	}
+	var frameworks []string
+	if req.Context != nil {
+		if module := headerValue(req.Context.GoModule); module != "" {
+			b.WriteString("Go module: " + module + "\n")
+		}
+		for _, f := range req.Context.Frameworks {
+			if f = headerValue(strings.ToLower(f)); f != "" {
+				frameworks = append(frameworks, f)
+			}
+		}
+		if len(frameworks) > 0 {
+			b.WriteString("Frameworks: " + strings.Join(frameworks, ", ") + "\n")
+		}
+	}
+
+	var focus []string
+	focus = append(focus, languageGuides[lang]...)
+	for _, f := range frameworks {
+		if guide, ok := frameworkGuides[f]; ok {
+			focus = append(focus, "in "+f+" code, "+guide)
+		}
+	}
+	if len(focus) > 0 {
+		b.WriteString("\nBesides general problems, look in particular for:\n")
+		for _, f := range focus {
+			b.WriteString("- " + f + "\n")
+		}
+	}
+
+	code := strings.Join(part.lines, "\n")
+	// Without any header the code is sent as is
+	if b.Len() == 0 {
+		return code
+	}
+	b.WriteString("\nCode, starting at line 1:\n")
+	b.WriteString(code)
+	return b.String()
+}
+
+// headerValue makes a client-supplied value safe to put on one line of the
+// prompt header, so it cannot add instructions of its own
+func headerValue(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, s)
+	if runes := []rune(s); len(runes) > 200 {
+		s = string(runes[:200])
+	}
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"sca-backend/internal/config"
+)
+
+// PromptVersion identifies the analysis prompt. Bump it whenever a change to
+// the prompts or to how replies are scored makes earlier results stale.
+const PromptVersion = "3"
+
+// AnalysisVersion identifies everything that shapes an analysis: the prompt,
+// the model and how files are split. Clients key cached results on it.
+// DigitalOcean agents are identified by URL since they choose their own
+// model.
+func AnalysisVersion(llm config.LLM, analysis config.Analysis) string {
+	model := llm.Model
+	if llm.Provider == config.ProviderDigitalOcean {
+		model = llm.AnalyzeAgentURL
+	}
+	return fmt.Sprintf("%s/%s/%s/%d-%d", PromptVersion, llm.Provider, model, analysis.ChunkSize, analysis.ChunkOverlap)
+}
+
+// analysisPrompt instructs models other than the DigitalOcean agents, which
+// carry their own instructions, to review code and answer in the shape of
+// models.AnalysisResponse
+const analysisPrompt = `You are a senior code reviewer. Review the code sent by the user for security, performance, code quality, maintainability and best practices.
+
+Reply with a single JSON object and nothing else, in this shape:
+{
+  "overall_score": <number from 1 to 10>,
+  "security": {"score": <number from 1 to 10>, "issues": [<issue>, ...]},
+  "performance": {"score": <number>, "issues": [...]},
+  "code_quality": {"score": <number>, "issues": [...]},
+  "maintainability": {"score": <number>, "issues": [...]},
+  "best_practices": {"score": <number>, "issues": [...]},
+  "suggestions": [<string>, ...]
+}
+where each issue is
+{"severity": "ERROR" | "WARNING" | "INFO", "type": <short title>, "description": <what is wrong>, "line": <line number>, "suggestion": <how to fix it>}
+
+The user may describe the file, its language and project before the code; use that to apply the idioms and pitfalls of the language and frameworks. Only report real problems in the code given, with the line they are on, counting from the first line of the code. Use an empty issues list for a category without problems.`
+
+// fixPrompt instructs models other than the DigitalOcean agents to fix one
+// problem and answer in the shape of models.FixIssuesResponse
+const fixPrompt = `You fix problems in code. The user sends a JSON object with "code", the "problem" found in it and a "suggestion" for fixing it.
+
+Make the smallest change that fixes the problem and reply with a single JSON object and nothing else, in this shape:
+{
+  "success": <true if the problem was fixed>,
+  "diff": <unified diff of the change, with --- and +++ headers and @@ hunks>,
+  "explanation": <why the change fixes the problem>,
+  "confidence": <integer from 1 to 10>,
+  "changelog": <one line describing the change>
+}`
+
+// repairRequest asks the model to correct reply, its answer to request,
+// which has the given problems
+func repairRequest(request, reply string, problems []string) string {
+	var b strings.Builder
+	b.WriteString(request)
+	if strings.Contains(reply, "{") {
+		const maxReply = 8000
+		if len(reply) > maxReply {
+			reply = reply[:maxReply] + "\n[truncated]"
+		}
+		b.WriteString("\n\nYour previous reply was:\n")
+		b.WriteString(reply)
+		b.WriteString("\n\nIt does not match the required JSON shape:\n")
+	} else {
+		b.WriteString("\n\nYour previous reply contained no JSON object:\n")
+	}
+	const maxProblems = 20
+	for i, p := range problems {
+		if i == maxProblems {
+			fmt.Fprintf(&b, "- and %d more\n", len(problems)-maxProblems)
+			break
+		}
+		b.WriteString("- " + p + "\n")
+	}
+	b.WriteString("\nReply again with the complete, corrected JSON object and nothing else.")
+	return b.String()
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"sca-backend/internal/config"
+	"sca-backend/internal/models"
+)
+
+// Tasks a provider is asked to complete. DigitalOcean runs each task on its
+// own agent.
+const (
+	TaskAnalyze = "analyze"
+	TaskFix     = "fix"
+)
+
+// Completion is a prompt for a language model
+type Completion struct {
+	Task string
+	// System holds the instructions. Providers whose agents carry their own
+	// instructions ignore it.
+	System      string
+	Prompt      string
+	MaxTokens   int
+	Temperature float64
+}
+
+// Provider sends prompts to a language model and returns its reply
+type Provider interface {
+	// Name identifies the provider in logs
+	Name() string
+	Complete(ctx context.Context, c Completion) (string, error)
+}
+
+// NewProvider returns the provider selected by the configuration
+func NewProvider(cfg config.LLM) (Provider, error) {
+	client := &http.Client{Timeout: cfg.Timeout}
+	switch cfg.Provider {
+	case config.ProviderDigitalOcean:
+		return &DigitalOceanProvider{
+			Agents: map[string]Endpoint{
+				TaskAnalyze: {URL: cfg.AnalyzeAgentURL, APIKey: cfg.AnalyzeAgentKey},
+				TaskFix:     {URL: cfg.FixAgentURL, APIKey: cfg.FixAgentKey},
+			},
+			Client: client,
+		}, nil
+	case config.ProviderOpenAI:
+		return &OpenAIProvider{
+			Endpoint: Endpoint{URL: cfg.BaseURL + "/chat/completions", APIKey: cfg.APIKey},
+			Model:    cfg.Model,
+			Client:   client,
+		}, nil
+	case config.ProviderLlamaCpp:
+		return &OpenAIProvider{
+			Endpoint:        Endpoint{URL: cfg.BaseURL + "/chat/completions", APIKey: cfg.APIKey},
+			Model:           cfg.Model,
+			Client:          client,
+			LegacyMaxTokens: true,
+		}, nil
+	case config.ProviderOllama:
+		return &OllamaProvider{BaseURL: cfg.BaseURL, Model: cfg.Model, Client: client}, nil
+	}
+	return nil, fmt.Errorf("unknown provider %q", cfg.Provider)
+}
+
+// Endpoint is a chat completions URL and the bearer token it requires
+type Endpoint struct {
+	URL    string
+	APIKey string
+}
+
+// DigitalOceanProvider sends prompts to DigitalOcean agents, which hold
+// their own instructions, choosing the agent by task
+type DigitalOceanProvider struct {
+	Agents map[string]Endpoint
+	Client *http.Client
+}
+
+func (p *DigitalOceanProvider) Name() string {
+	return config.ProviderDigitalOcean
+}
+
+func (p *DigitalOceanProvider) Complete(ctx context.Context, c Completion) (string, error) {
+	agent, ok := p.Agents[c.Task]
+	if !ok {
+		return "", fmt.Errorf("no agent configured for task %q", c.Task)
+	}
+	return chatCompletion(ctx, p.Client, agent, models.ChatRequest{
+		Messages:            []models.ChatMessage{{Role: "user", Content: c.Prompt}},
+		MaxCompletionTokens: c.MaxTokens,
+		Temperature:         c.Temperature,
+	})
+}
+
+// OpenAIProvider sends prompts to an OpenAI-compatible chat completions API,
+// such as OpenAI, vLLM or a llama.cpp server
+type OpenAIProvider struct {
+	Endpoint Endpoint
+	Model    string
+	Client   *http.Client
+	// LegacyMaxTokens sends max_tokens instead of max_completion_tokens,
+	// for servers that predate it
+	LegacyMaxTokens bool
+}
+
+func (p *OpenAIProvider) Name() string {
+	return "openai-compatible"
+}
+
+func (p *OpenAIProvider) Complete(ctx context.Context, c Completion) (string, error) {
+	req := models.ChatRequest{
+		Model:       p.Model,
+		Messages:    chatMessages(c),
+		Temperature: c.Temperature,
+	}
+	if p.LegacyMaxTokens {
+		req.MaxTokens = c.MaxTokens
+	} else {
+		req.MaxCompletionTokens = c.MaxTokens
+	}
+	return chatCompletion(ctx, p.Client, p.Endpoint, req)
+}
+
+// OllamaProvider sends prompts to the chat API of an Ollama server, asking
+// for JSON output
+type OllamaProvider struct {
+	BaseURL string
+	Model   string
+	Client  *http.Client
+}
+
+func (p *OllamaProvider) Name() string {
+	return config.ProviderOllama
+}
+
+func (p *OllamaProvider) Complete(ctx context.Context, c Completion) (string, error) {
+	var resp models.OllamaResponse
+	err := postJSON(ctx, p.Client, Endpoint{URL: p.BaseURL + "/api/chat"}, models.OllamaRequest{
+		Model:    p.Model,
+		Messages: chatMessages(c),
+		Format:   "json",
+		Options:  models.OllamaOptions{Temperature: c.Temperature, NumPredict: c.MaxTokens},
+	}, &resp)
+	if err != nil {
+		return "", err
+	}
+	if resp.Error != "" {
+		return "", fmt.Errorf("AI API error: %s", resp.Error)
+	}
+	if resp.Message.Content == "" {
+		return "", fmt.Errorf("no response from AI model")
+	}
+	return resp.Message.Content, nil
+}
+
+// chatMessages returns the system and user messages of a completion
+func chatMessages(c Completion) []models.ChatMessage {
+	var messages []models.ChatMessage
+	if c.System != "" {
+		messages = append(messages, models.ChatMessage{Role: "system", Content: c.System})
+	}
+	return append(messages, models.ChatMessage{Role: "user", Content: c.Prompt})
+}
+
+// chatCompletion sends an OpenAI-compatible chat request and returns the
+// content of the first choice
+func chatCompletion(ctx context.Context, client *http.Client, endpoint Endpoint, req models.ChatRequest) (string, error) {
+	var resp models.ChatResponse
+	if err := postJSON(ctx, client, endpoint, req, &resp); err != nil {
+		return "", err
+	}
+	if resp.Error != nil {
+		return "", fmt.Errorf("AI API error: %s", resp.Error.Message)
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("no response from AI model")
+	}
+	return resp.Choices[0].Message.Content, nil
+}
+
+// postJSON posts body as JSON to the endpoint and decodes the response into
+// out
+func postJSON(ctx context.Context, client *http.Client, endpoint Endpoint, body, out any) error {
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to serialize request: %v", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(jsonBody))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if endpoint.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+endpoint.APIKey)
+	}
+
+	httpResp, err := client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to contact AI API: %v", err)
+	}
+	defer httpResp.Body.Close()
+
+	respBody, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read AI response: %v", err)
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return fmt.Errorf("AI service temporarily unavailable (status %d)", httpResp.StatusCode)
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to parse AI response: %v", err)
+	}
+	return nil
+}
+
+// extractJSON returns the outermost JSON object in a model reply, which may
+// be wrapped in prose or a code fence
+func extractJSON(content string) string {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start >= 0 && end > start {
+		return content[start : end+1]
+	}
+	return content
+}
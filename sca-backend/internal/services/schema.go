@@ -0,0 +1,284 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sca-backend/internal/models"
+)
+
+// analysisSchema is the JSON Schema a model's analysis must satisfy before
+// it is decoded into models.AnalysisResponse
+const analysisSchema = `{
+  "type": "object",
+  "required": ["overall_score", "security", "performance", "code_quality", "maintainability", "best_practices", "suggestions"],
+  "properties": {
+    "overall_score": {"type": "number", "minimum": 1, "maximum": 10},
+    "security": {"$ref": "#/$defs/category"},
+    "performance": {"$ref": "#/$defs/category"},
+    "code_quality": {"$ref": "#/$defs/category"},
+    "maintainability": {"$ref": "#/$defs/category"},
+    "best_practices": {"$ref": "#/$defs/category"},
+    "suggestions": {"type": "array", "items": {"type": "string"}}
+  },
+  "$defs": {
+    "category": {
+      "type": "object",
+      "required": ["score", "issues"],
+      "properties": {
+        "score": {"type": "number", "minimum": 1, "maximum": 10},
+        "issues": {"type": "array", "items": {"$ref": "#/$defs/issue"}}
+      }
+    },
+    "issue": {
+      "type": "object",
+      "required": ["severity", "type", "description", "suggestion"],
+      "properties": {
+        "severity": {"enum": ["INFO", "WARNING", "ERROR"]},
+        "type": {"type": "string", "minLength": 1},
+        "description": {"type": "string", "minLength": 1},
+        "line": {"type": "integer", "minimum": 0},
+        "suggestion": {"type": "string"}
+      }
+    }
+  }
+}`
+
+// schema is the subset of JSON Schema used by analysisSchema
+type schema struct {
+	Ref        string             `json:"$ref"`
+	Type       string             `json:"type"`
+	Required   []string           `json:"required"`
+	Properties map[string]*schema `json:"properties"`
+	Items      *schema            `json:"items"`
+	Enum       []any              `json:"enum"`
+	Minimum    *float64           `json:"minimum"`
+	Maximum    *float64           `json:"maximum"`
+	MinLength  int                `json:"minLength"`
+	Defs       map[string]*schema `json:"$defs"`
+}
+
+var parsedAnalysisSchema = mustParseSchema(analysisSchema)
+
+// mustParseSchema parses a schema document, panicking when it is invalid
+func mustParseSchema(text string) *schema {
+	var s schema
+	if err := json.Unmarshal([]byte(text), &s); err != nil {
+		panic(fmt.Sprintf("invalid schema: %v", err))
+	}
+	return &s
+}
+
+// validate checks value, decoded from JSON, against s and returns a problem
+// for each violation, prefixed with its JSON path. root resolves $ref.
+func (s *schema) validate(root *schema, value any, path string) []string {
+	if s.Ref != "" {
+		def, ok := root.Defs[strings.TrimPrefix(s.Ref, "#/$defs/")]
+		if !ok {
+			return []string{fmt.Sprintf("%s: unknown schema reference %s", path, s.Ref)}
+		}
+		return def.validate(root, value, path)
+	}
+
+	if len(s.Enum) > 0 {
+		for _, allowed := range s.Enum {
+			if value == allowed {
+				return nil
+			}
+		}
+		var names []string
+		for _, allowed := range s.Enum {
+			names = append(names, fmt.Sprint(allowed))
+		}
+		return []string{fmt.Sprintf("%s: must be one of %s, not %s", path, strings.Join(names, ", "), describe(value))}
+	}
+
+	var problems []string
+	switch s.Type {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s: must be an object, not %s", path, describe(value))}
+		}
+		for _, key := range s.Required {
+			if _, ok := obj[key]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: missing required field %q", path, key))
+			}
+		}
+		keys := make([]string, 0, len(s.Properties))
+		for key := range s.Properties {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if v, ok := obj[key]; ok {
+				problems = append(problems, s.Properties[key].validate(root, v, path+"."+key)...)
+			}
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s: must be an array, not %s", path, describe(value))}
+		}
+		if s.Items != nil {
+			for i, item := range items {
+				problems = append(problems, s.Items.validate(root, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return []string{fmt.Sprintf("%s: must be a string, not %s", path, describe(value))}
+		}
+		if len(strings.TrimSpace(str)) < s.MinLength {
+			problems = append(problems, fmt.Sprintf("%s: must not be empty", path))
+		}
+	case "number", "integer":
+		n, ok := value.(float64)
+		if !ok || (s.Type == "integer" && n != math.Trunc(n)) {
+			return []string{fmt.Sprintf("%s: must be %s, not %s", path, article(s.Type), describe(value))}
+		}
+		if s.Minimum != nil && n < *s.Minimum || s.Maximum != nil && n > *s.Maximum {
+			problems = append(problems, fmt.Sprintf("%s: %v is out of range", path, n))
+		}
+	}
+	return problems
+}
+
+// describe names the JSON type of a decoded value for a problem message
+func describe(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "an object"
+	case []any:
+		return "an array"
+	case string:
+		return strconv.Quote(v)
+	case bool:
+		return "a boolean"
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// article returns a JSON type name with its indefinite article
+func article(typ string) string {
+	if typ == "integer" {
+		return "an integer"
+	}
+	return "a " + typ
+}
+
+// severityNames maps the severities models use to INFO, WARNING and ERROR
+var severityNames = map[string]string{
+	"error":    "ERROR",
+	"critical": "ERROR",
+	"high":     "ERROR",
+	"severe":   "ERROR",
+	"blocker":  "ERROR",
+	"warning":  "WARNING",
+	"warn":     "WARNING",
+	"medium":   "WARNING",
+	"moderate": "WARNING",
+	"major":    "WARNING",
+	"info":     "INFO",
+	"low":      "INFO",
+	"minor":    "INFO",
+	"note":     "INFO",
+	"hint":     "INFO",
+}
+
+// categoryKeys are the JSON keys of the analysis categories
+var categoryKeys = []string{"security", "performance", "code_quality", "maintainability", "best_practices"}
+
+// normalizeAnalysis fixes the slips models commonly make in an analysis
+// decoded from JSON, before it is validated: severities outside the enum,
+// scores out of range or written as strings, and missing issue or
+// suggestion lists
+func normalizeAnalysis(value any) {
+	analysis, ok := value.(map[string]any)
+	if !ok {
+		return
+	}
+	normalizeScore(analysis, "overall_score")
+	if analysis["suggestions"] == nil {
+		analysis["suggestions"] = []any{}
+	}
+
+	for _, key := range categoryKeys {
+		category, ok := analysis[key].(map[string]any)
+		if !ok {
+			continue
+		}
+		normalizeScore(category, "score")
+		if category["issues"] == nil {
+			category["issues"] = []any{}
+		}
+		issues, _ := category["issues"].([]any)
+		for _, item := range issues {
+			issue, ok := item.(map[string]any)
+			if !ok {
+				continue
+			}
+			if severity, ok := issue["severity"].(string); ok {
+				if name, ok := severityNames[strings.ToLower(strings.TrimSpace(severity))]; ok {
+					issue["severity"] = name
+				}
+			}
+			switch line := issue["line"].(type) {
+			case nil:
+				delete(issue, "line")
+			case string:
+				if n, err := strconv.Atoi(strings.TrimSpace(line)); err == nil {
+					issue["line"] = float64(n)
+				}
+			}
+		}
+	}
+}
+
+// normalizeScore parses a score written as a string and clamps it to the
+// range 1 to 10
+func normalizeScore(obj map[string]any, key string) {
+	score, ok := obj[key].(float64)
+	if s, isString := obj[key].(string); isString {
+		n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		score, ok = n, err == nil
+	}
+	if ok && !math.IsNaN(score) {
+		obj[key] = math.Min(math.Max(score, 1), 10)
+	}
+}
+
+// parseAnalysis decodes a model reply into an analysis, returning the
+// problems that make it invalid instead when it does not satisfy
+// analysisSchema after normalization
+func parseAnalysis(reply string) (*models.AnalysisResponse, []string) {
+	content := extractJSON(reply)
+	var value any
+	if err := json.Unmarshal([]byte(content), &value); err != nil {
+		return nil, []string{fmt.Sprintf("the reply is not valid JSON: %v", err)}
+	}
+
+	normalizeAnalysis(value)
+	if problems := parsedAnalysisSchema.validate(parsedAnalysisSchema, value, "$"); len(problems) > 0 {
+		return nil, problems
+	}
+
+	normalized, err := json.Marshal(value)
+	if err != nil {
+		return nil, []string{fmt.Sprintf("the reply cannot be encoded: %v", err)}
+	}
+	var analysis models.AnalysisResponse
+	if err := json.Unmarshal(normalized, &analysis); err != nil {
+		return nil, []string{fmt.Sprintf("the reply does not decode into an analysis: %v", err)}
+	}
+	return &analysis, nil
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
@@ -10,7 +11,9 @@ import (
 	"sca-backend/internal/config"
 	"sca-backend/internal/firebase"
 	"sca-backend/internal/handlers"
+	"sca-backend/internal/jobs"
 	"sca-backend/internal/middleware"
+	"sca-backend/internal/services"
 )
 
 func main() {
@@ -21,9 +24,23 @@ func main() {
 	}
 	handlers.SetAPIKey(apiKey)
 
-	digitalOceanKey := os.Getenv("DIGITALOCEAN_API_KEY")
-	if digitalOceanKey == "" {
-		log.Fatal("DIGITALOCEAN_API_KEY environment variable not set")
+	// Select the language model provider
+	llmConfig, err := config.LoadLLM()
+	if err != nil {
+		log.Fatal(err)
+	}
+	provider, err := services.NewProvider(llmConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Using %s LLM provider", provider.Name())
+	analysisConfig, err := config.LoadAnalysis()
+	if err != nil {
+		log.Fatal(err)
+	}
+	jobsConfig, err := config.LoadJobs()
+	if err != nil {
+		log.Fatal(err)
 	}
 
 	// Initialize Redis (with fallback if unavailable)
@@ -79,7 +96,7 @@ func main() {
 
 			if allowed {
 				w.Header().Set("Access-Control-Allow-Origin", origin)
-				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
 				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-API-Key, X-User-ID, Authorization")
 				w.Header().Set("Access-Control-Max-Age", "86400") // 24 hours
 			}
@@ -94,12 +111,25 @@ func main() {
 		})
 	}
 
-	analyzeHandler := handlers.NewHandler(fsclient)
+	analyzeHandler := handlers.NewHandler(fsclient, provider, analysisConfig)
+	analyzeHandler.Version = services.AnalysisVersion(llmConfig, analysisConfig)
+
+	// Run queued analyses in the background; jobs survive restarts only
+	// with Redis
+	analyzeHandler.Jobs = jobs.NewQueue(rdb, analyzeHandler.AnalyzeJob, jobsConfig)
+	analyzeHandler.Jobs.Start(context.Background())
+	if rdb == nil {
+		log.Println("Job queue running in memory")
+	}
 
 	// Set up routes with CORS middleware
 	mux.HandleFunc("/health", handlers.HealthHandler) // Health check endpoint (no auth required)
 	mux.HandleFunc("/api/analyze-code", middleware.AuthMiddleware(analyzeHandler.AnalyzeHandler, fsclient))
 	mux.HandleFunc("/api/issues/fix", middleware.AuthMiddleware(analyzeHandler.FixIssuesHandler, fsclient))
+	mux.HandleFunc("POST /api/jobs", middleware.AuthMiddleware(analyzeHandler.CreateJobHandler, fsclient))
+	mux.HandleFunc("GET /api/jobs/{id}", middleware.AuthMiddleware(analyzeHandler.GetJobHandler, fsclient))
+	mux.HandleFunc("DELETE /api/jobs/{id}", middleware.AuthMiddleware(analyzeHandler.CancelJobHandler, fsclient))
+	mux.HandleFunc("GET /api/version", middleware.AuthMiddleware(analyzeHandler.VersionHandler, fsclient))
 	mux.HandleFunc("/api/stats", middleware.AuthMiddleware(handlers.StatsHandler, fsclient))
 	mux.HandleFunc("/api/feedback", middleware.AuthMiddleware(handlers.FeedbackHandler, fsclient))
 